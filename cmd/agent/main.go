@@ -52,7 +52,10 @@ func main() {
 	}
 
 	var wg sync.WaitGroup
-	agent.Run(ctx, &wg, cfg, pubKey)
+	if err := agent.Run(ctx, &wg, cfg, pubKey); err != nil {
+		log.Fatalf("failed to start agent: %s", err)
+		return
+	}
 
 	<-quit
 	logger.Log.Info("Received Ctrl+C, stopping...")
@@ -15,11 +15,17 @@ import (
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/zap"
 
+	"metrics/internal/core/alert"
 	"metrics/internal/core/config"
 	"metrics/internal/core/service"
+	"metrics/internal/infra/api/grpcapi"
 	"metrics/internal/infra/api/rest"
+	"metrics/internal/infra/graphite"
+	"metrics/internal/infra/notifier"
+	"metrics/internal/infra/statsd"
 	"metrics/internal/infra/store"
 	"metrics/internal/logger"
+	"metrics/internal/selfmetrics"
 	"metrics/migrations"
 )
 
@@ -74,13 +80,38 @@ func run(ctx context.Context, wg *sync.WaitGroup, cfg *config.Config) error {
 
 	metricService := service.NewMetricService(store)
 	systemService := service.NewSystemService(store)
+	rangeService := service.NewRangeService(store)
 	logger.Log.Info("Service initialized")
 
 	privateKey, err := service.NewPrivateKey(cfg.CryptoKey)
 	if err != nil {
 		return fmt.Errorf("failed to initialize private key: %w", err)
 	}
-	api := rest.NewAPI(cfg, metricService, systemService, privateKey)
+	rules, err := alert.LoadRules(cfg.Alerting.RulesFile)
+	if err != nil {
+		return fmt.Errorf("failed to load alerting rules: %w", err)
+	}
+	// база данных хранит историю всегда, хранилище в памяти - только при заданном размере истории
+	history := cfg.Storage.DatabaseDSN != "" || cfg.Storage.HistorySize > 0
+	if err := alert.CheckHistory(rules.Rules, history); err != nil {
+		return fmt.Errorf("invalid alerting rules: %w", err)
+	}
+	notifiers, err := notifier.NewNotifiers(rules.Receivers)
+	if err != nil {
+		return fmt.Errorf("failed to initialize alert notifiers: %w", err)
+	}
+	alertEngine := alert.NewEngine(
+		store,
+		rules.Rules,
+		time.Duration(cfg.Alerting.EvaluationInterval)*time.Second,
+		alert.NewDispatcher(notifiers),
+	)
+	wg.Add(1)
+	go alertEngine.Run(ctx, wg)
+	defer alertEngine.Close()
+
+	registry := selfmetrics.NewRegistry()
+	api := rest.NewAPI(cfg, metricService, systemService, rangeService, alertEngine, registry, privateKey)
 
 	// https://github.com/gin-gonic/gin/blob/master/docs/doc.md#manually
 	// Initializing the server in a goroutine so that
@@ -91,6 +122,45 @@ func run(ctx context.Context, wg *sync.WaitGroup, cfg *config.Config) error {
 		}
 	}()
 
+	var grpcAPI *grpcapi.API
+	if cfg.Server.GRPCAddress != "" {
+		grpcAPI = grpcapi.NewAPI(cfg, metricService, systemService, privateKey)
+		go func() {
+			if err := grpcAPI.Run(cfg.Server.GRPCAddress); err != nil {
+				logger.Log.Info("Runing gRPC server error", zap.Error(err))
+			}
+		}()
+	}
+
+	if cfg.Server.StatsDAddress != "" {
+		listener, err := statsd.Listen(
+			cfg.Server.StatsDAddress,
+			time.Duration(cfg.Server.StatsDFlushInterval)*time.Second,
+			metricService,
+			registry,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to start StatsD listener: %w", err)
+		}
+		wg.Add(1)
+		go listener.Run(ctx, wg)
+		defer listener.Close()
+	}
+	if cfg.Server.GraphiteAddress != "" {
+		listener, err := graphite.Listen(
+			cfg.Server.GraphiteAddress,
+			time.Duration(cfg.Server.GraphiteFlushInterval)*time.Second,
+			metricService,
+			registry,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to start Graphite listener: %w", err)
+		}
+		wg.Add(1)
+		go listener.Run(ctx, wg)
+		defer listener.Close()
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server with
 	// a timeout of 5 seconds.
 	quit := make(chan os.Signal, 1)
@@ -109,6 +179,11 @@ func run(ctx context.Context, wg *sync.WaitGroup, cfg *config.Config) error {
 	if err := api.Shutdown(ctx); err != nil {
 		return err
 	}
+	if grpcAPI != nil {
+		if err := grpcAPI.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
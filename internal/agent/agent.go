@@ -3,44 +3,69 @@ package agent
 import (
 	"context"
 	"crypto/rsa"
+	"errors"
 	"fmt"
-	"math/rand"
-	"runtime"
+	"io"
 	"sync"
 	"syscall"
 	"time"
 
+	"metrics/internal/agent/collector"
 	"metrics/internal/agent/config"
 	"metrics/internal/agent/metrics"
+	"metrics/internal/agent/queue"
 	"metrics/internal/agent/transport"
 	"metrics/internal/core/model"
 	"metrics/internal/logger"
 	"metrics/internal/retrier"
 
-	"github.com/shirou/gopsutil/v4/cpu"
-	"github.com/shirou/gopsutil/v4/mem"
 	"go.uber.org/zap"
 )
 
-func Run(ctx context.Context, wg *sync.WaitGroup, config *config.AgentConfig, pubKey *rsa.PublicKey) {
-	lock := &sync.Mutex{}
+func Run(ctx context.Context, wg *sync.WaitGroup, config *config.AgentConfig, pubKey *rsa.PublicKey) error {
+	collectors, err := collector.Default.Build(config.Collectors, time.Duration(config.PollInterval)*time.Second)
+	if err != nil {
+		return fmt.Errorf("error building collectors: %w", err)
+	}
+	set := metrics.NewSet()
+
+	var q *queue.Queue
+	if config.QueueDir != "" {
+		q, err = queue.Open(queue.Config{
+			Dir:     config.QueueDir,
+			MaxSize: config.QueueMaxSize,
+			MaxAge:  time.Duration(config.QueueMaxAge) * time.Second,
+			Sync:    queue.SyncPolicy(config.QueueSync),
+		})
+		if err != nil {
+			return fmt.Errorf("error opening queue: %w", err)
+		}
+	}
 
-	go metricRuntimePoller(ctx, wg, config, lock)
-	go metricPollerPsutils(ctx, wg, config, lock)
+	for _, c := range collectors {
+		logger.Log.Info("Start collector", zap.String("collector", c.Name), zap.Duration("interval", c.Interval))
+		go collectorPoller(ctx, wg, c, set)
+	}
 
-	go metricReporter(ctx, wg, config, lock, pubKey)
+	if q != nil {
+		go queueReporter(ctx, wg, config, set, q)
+		go queueSender(ctx, wg, config, q, pubKey)
+		return nil
+	}
+	go metricReporter(ctx, wg, config, set, pubKey)
+	return nil
 }
 
-func metricReporter(ctx context.Context, wg *sync.WaitGroup, cfg *config.AgentConfig, lock *sync.Mutex, pubKey *rsa.PublicKey) {
+func metricReporter(ctx context.Context, wg *sync.WaitGroup, cfg *config.AgentConfig, set *metrics.Set, pubKey *rsa.PublicKey) {
 	wg.Add(1)
 	defer wg.Done()
 
 	reportTicker := time.NewTicker(time.Duration(cfg.ReportInterval) * time.Second)
 	defer reportTicker.Stop()
 
-	metricsCh := make(chan []model.MetricsV2, cfg.RateLimit)
+	metricsCh := make(chan *metrics.Batch, cfg.RateLimit)
 	for i := 0; i < cfg.RateLimit; i++ {
-		go metricReporterWorker(ctx, cfg, metricsCh, pubKey, i)
+		go metricReporterWorker(ctx, cfg, metricsCh, set, pubKey, i)
 	}
 
 	for {
@@ -49,15 +74,7 @@ func metricReporter(ctx context.Context, wg *sync.WaitGroup, cfg *config.AgentCo
 			logger.Log.Info("Stop posting metrics")
 			return
 		case <-reportTicker.C:
-			lock.Lock()
-			data := make([]model.MetricsV2, 0, len(metrics.AllMetrics))
-			for _, metric := range metrics.AllMetrics {
-				data = append(data, metric.Payload())
-				metric.WasSent()
-			}
-			lock.Unlock()
-
-			metricsCh <- data
+			metricsCh <- set.Batch()
 		}
 	}
 
@@ -66,25 +83,64 @@ func metricReporter(ctx context.Context, wg *sync.WaitGroup, cfg *config.AgentCo
 func metricReporterWorker(
 	ctx context.Context,
 	cfg *config.AgentConfig,
-	metricsCh chan []model.MetricsV2,
+	metricsCh chan *metrics.Batch,
+	set *metrics.Set,
 	pubKey *rsa.PublicKey,
 	workerID int,
 ) {
 	logger.Log.Info(fmt.Sprintf("Start worker N: %d", workerID))
-	client := transport.NewClient(cfg.ServerAddresPort, cfg.HashKey, pubKey)
+	client, err := newTransporter(cfg, pubKey)
+	if err != nil {
+		logger.Log.Error("creating transport error", zap.Int("worker", workerID), zap.Error(err))
+		return
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			logger.Log.Info("Exit from metricReporterWorker")
+			if closer, ok := client.(io.Closer); ok {
+				if err := closer.Close(); err != nil {
+					logger.Log.Error("closing transport error", zap.Int("worker", workerID), zap.Error(err))
+				}
+			}
 			return
-		case data := <-metricsCh:
-			postMetrics(ctx, client, data, workerID)
+		case batch := <-metricsCh:
+			deliverBatch(ctx, client, set, batch, workerID)
 		}
 	}
 }
 
-func postMetrics(ctx context.Context, client metrics.Transporter, data []model.MetricsV2, workerID int) {
+func newTransporter(cfg *config.AgentConfig, pubKey *rsa.PublicKey) (metrics.Transporter, error) {
+	if cfg.Transport == config.TransportGRPC {
+		return transport.NewGRPCClient(cfg.GRPCAddress, cfg.HashKey, pubKey)
+	}
+	return transport.NewClient(cfg.ServerAddresPort, cfg.HashKey, pubKey), nil
+}
+
+// deliverBatch sends the batch and acknowledges its counter deltas. The failed batch is kept by the set
+// and is sent again with the same key. The batch rejected by the server is dropped, it would fail again.
+func deliverBatch(
+	ctx context.Context, client metrics.Transporter, set *metrics.Set, batch *metrics.Batch, workerID int,
+) {
+	// ключ один на все попытки, чтобы повторная отправка не удвоила счетчики на сервере
+	if batch.Key == "" {
+		batch.Key = transport.NewIdempotencyKey()
+	}
+	err := postMetrics(ctx, client, batch.Key, batch.Data, workerID)
+
+	if errors.Is(err, transport.ErrRejected) {
+		logger.Log.Error("dropping rejected batch", zap.String("key", batch.Key), zap.Error(err))
+	} else if err != nil {
+		set.Release(batch)
+		return
+	}
+	set.Ack(batch)
+}
+
+func postMetrics(
+	ctx context.Context, client metrics.Transporter, key string, data []model.MetricsV2, workerID int,
+) error {
 	logger.Log.Debug(fmt.Sprintf("Reporting metrics. Worker ID: %d", workerID))
 
 	ret := &retrier.Retrier{
@@ -100,112 +156,133 @@ func postMetrics(ctx context.Context, client metrics.Transporter, data []model.M
 	}
 
 	fun := func() error {
-		return client.SendMetric(data)
+		return client.SendMetric(key, data)
 	}
 
-	if err := ret.Do(ctx, fun, syscall.ECONNREFUSED); err != nil {
+	if err := ret.Do(ctx, fun, syscall.ECONNREFUSED, transport.ErrUnavailable); err != nil {
 		logger.Log.Error("sending metric error", zap.String("error", err.Error()))
-		return
+		return err
 	}
+	return nil
 }
 
-func metricRuntimePoller(ctx context.Context, wg *sync.WaitGroup, cfg *config.AgentConfig, lock *sync.Mutex) {
+// queueReporter writes batches to the disk queue. Counter deltas are acknowledged when the batch is saved.
+func queueReporter(ctx context.Context, wg *sync.WaitGroup, cfg *config.AgentConfig, set *metrics.Set, q *queue.Queue) {
 	wg.Add(1)
 	defer wg.Done()
 
-	pollTicker := time.NewTicker(time.Duration(cfg.PollInterval) * time.Second)
-	defer pollTicker.Stop()
+	reportTicker := time.NewTicker(time.Duration(cfg.ReportInterval) * time.Second)
+	defer reportTicker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
-			logger.Log.Info("Stop to poll Runtime metrics...")
+			logger.Log.Info("Stop queueing metrics")
 			return
-		case <-pollTicker.C:
-			metricPollFromRuntime(lock)
+		case <-reportTicker.C:
+			batch := set.Batch()
+			if batch.Key == "" {
+				batch.Key = transport.NewIdempotencyKey()
+			}
+			if err := q.Push(&queue.Record{Key: batch.Key, Metrics: batch.Data}); err != nil {
+				logger.Log.Error("queueing metrics error", zap.Error(err))
+				set.Release(batch)
+				continue
+			}
+			set.Ack(batch)
 		}
 	}
 }
 
-func metricPollerPsutils(ctx context.Context, wg *sync.WaitGroup, cfg *config.AgentConfig, lock *sync.Mutex) {
+// queueSender replays the queue in order by a single connection, so the rate limit is not used.
+func queueSender(
+	ctx context.Context, wg *sync.WaitGroup, cfg *config.AgentConfig, q *queue.Queue, pubKey *rsa.PublicKey,
+) {
 	wg.Add(1)
 	defer wg.Done()
+	defer func() {
+		if err := q.Close(); err != nil {
+			logger.Log.Error("closing queue error", zap.Error(err))
+		}
+	}()
 
-	pollTicker := time.NewTicker(time.Duration(cfg.PollInterval) * time.Second)
+	client, err := newTransporter(cfg, pubKey)
+	if err != nil {
+		logger.Log.Error("creating transport error", zap.Error(err))
+		return
+	}
+	if closer, ok := client.(io.Closer); ok {
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.Log.Error("closing transport error", zap.Error(err))
+			}
+		}()
+	}
+
+	sendQueue(ctx, client, q, time.Duration(cfg.ReportInterval)*time.Second)
+}
+
+// sendQueue sends records of the queue until the context is done. The record which is not delivered
+// is sent again after the delay, records behind it wait. Records rejected by the server are dropped,
+// because they would block the queue.
+func sendQueue(ctx context.Context, client metrics.Transporter, q *queue.Queue, delay time.Duration) {
+	for {
+		rec, err := q.Peek(ctx)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) && !errors.Is(err, queue.ErrClosed) {
+				logger.Log.Error("reading queue error", zap.Error(err))
+			}
+			return
+		}
+
+		err = postMetrics(ctx, client, rec.Key, rec.Metrics, 0)
+		if errors.Is(err, transport.ErrRejected) {
+			logger.Log.Error("dropping rejected queue record", zap.String("key", rec.Key), zap.Error(err))
+		} else if err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			continue
+		}
+		if err := q.Ack(rec); err != nil {
+			logger.Log.Error("acknowledging queue record error", zap.Error(err))
+			return
+		}
+	}
+}
+
+// collectorPoller polls the collector with its interval and updates the set by the collected metrics.
+func collectorPoller(ctx context.Context, wg *sync.WaitGroup, c *collector.Scheduled, set *metrics.Set) {
+	wg.Add(1)
+	defer wg.Done()
+
+	pollTicker := time.NewTicker(c.Interval)
 	defer pollTicker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
-			logger.Log.Info("Stop to poll Psutils metrics...")
+			logger.Log.Info("Stop to poll collector", zap.String("collector", c.Name))
 			return
 		case <-pollTicker.C:
-			metricPollFromPsutils(lock)
+			pollCollector(ctx, c, set)
 		}
 	}
 }
 
-func metricPollFromRuntime(lock *sync.Mutex) {
-	logger.Log.Debug("Gathering Runtime metrics")
-	lock.Lock()
-	defer lock.Unlock()
-
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-
-	var rtm runtime.MemStats
-	runtime.ReadMemStats(&rtm)
-
-	// Counters metric
-	metrics.PollCountCounter.Increment(1)
-
-	// Gauge metrics
-	metrics.RandomValue.Set(r.Float64())
-	metrics.AllocGauge.Set(float64(rtm.Alloc))
-	metrics.BuckHashSysGauge.Set(float64(rtm.BuckHashSys))
-	metrics.FreesGauge.Set(float64(rtm.Frees))
-	metrics.GCCPUFractionGauge.Set(float64(rtm.GCCPUFraction))
-	metrics.GCSysGauge.Set(float64(rtm.GCSys))
-	metrics.HeapAllocGauge.Set(float64(rtm.HeapAlloc))
-	metrics.HeapIdleGauge.Set(float64(rtm.HeapIdle))
-	metrics.HeapInuseGauge.Set(float64(rtm.HeapInuse))
-	metrics.HeapObjectsGauge.Set(float64(rtm.HeapObjects))
-	metrics.HeapReleasedGauge.Set(float64(rtm.HeapReleased))
-	metrics.HeapSysGauge.Set(float64(rtm.HeapSys))
-	metrics.LastGCGauge.Set(float64(rtm.LastGC))
-	metrics.LookupsGauge.Set(float64(rtm.Lookups))
-	metrics.MCacheInuseGauge.Set(float64(rtm.MCacheInuse))
-	metrics.MCacheSysGauge.Set(float64(rtm.MCacheSys))
-	metrics.MSpanInuseGauge.Set(float64(rtm.MSpanInuse))
-	metrics.MSpanSysGauge.Set(float64(rtm.MSpanSys))
-	metrics.MallocsGauge.Set(float64(rtm.Mallocs))
-	metrics.NextGCGauge.Set(float64(rtm.NextGC))
-	metrics.NumForcedGCGauge.Set(float64(rtm.NumForcedGC))
-	metrics.NumGCGauge.Set(float64(rtm.NumGC))
-	metrics.OtherSysGauge.Set(float64(rtm.OtherSys))
-	metrics.PauseTotalNsGauge.Set(float64(rtm.PauseTotalNs))
-	metrics.StackInuseGauge.Set(float64(rtm.StackInuse))
-	metrics.StackSysGauge.Set(float64(rtm.StackSys))
-	metrics.SysGauge.Set(float64(rtm.Sys))
-	metrics.TotalAllocGauge.Set(float64(rtm.TotalAlloc))
-}
-
-func metricPollFromPsutils(lock *sync.Mutex) {
-	logger.Log.Debug("Gathering psutils metrics")
-	lock.Lock()
-	defer lock.Unlock()
-	v, err := mem.VirtualMemory()
+func pollCollector(ctx context.Context, c *collector.Scheduled, set *metrics.Set) {
+	logger.Log.Debug("Gathering metrics", zap.String("collector", c.Name))
+	collected, err := c.Collector.Collect(ctx)
 	if err != nil {
-		logger.Log.Error("Could not pull gopsutil metrics")
-	}
-
-	metrics.TotalMemory.Set(float64(v.Total))
-	metrics.FreeMemory.Set(float64(v.Free))
-
-	cpus, err := cpu.Percent(time.Second, true)
-	if err != nil {
-		logger.Log.Error("Could not pull gopsutil cpu utilization")
+		logger.Log.Error("Could not collect metrics", zap.String("collector", c.Name), zap.Error(err))
+		if len(collected) == 0 {
+			// неудачный сбор не означает, что метрики коллектора пропали
+			return
+		}
 	}
-	for i, cpu := range cpus {
-		metrics.CPUutilizations[i].Set(cpu)
+	if err := set.Update(c.Name, collected); err != nil {
+		logger.Log.Error("Invalid collected metrics", zap.String("collector", c.Name), zap.Error(err))
 	}
 }
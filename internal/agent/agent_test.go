@@ -2,13 +2,18 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
 
+	"metrics/internal/agent/collector"
 	"metrics/internal/agent/config"
 	"metrics/internal/agent/metrics"
+	"metrics/internal/agent/queue"
+	"metrics/internal/agent/transport"
 	"metrics/internal/core/model"
 	"metrics/internal/infra/api/rest/middlewares"
 
@@ -17,79 +22,6 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func TestPollFromRuntime(t *testing.T) {
-	lock := &sync.Mutex{}
-	cfg := config.AgentConfig{PollInterval: 1}
-
-	ctx := context.Background()
-	ctxT, cancel := context.WithTimeout(ctx, time.Duration(cfg.PollInterval)*time.Second+time.Second)
-	defer cancel()
-
-	var wg sync.WaitGroup
-	metricRuntimePoller(ctxT, &wg, &cfg, lock)
-
-	gauges := []metrics.Gauge{
-		metrics.RandomValue,
-		metrics.AllocGauge,
-		metrics.BuckHashSysGauge,
-		metrics.GCSysGauge,
-		metrics.HeapAllocGauge,
-		metrics.HeapIdleGauge,
-		metrics.HeapInuseGauge,
-		metrics.HeapObjectsGauge,
-		metrics.HeapReleasedGauge,
-		metrics.HeapSysGauge,
-		metrics.MCacheInuseGauge,
-		metrics.MCacheSysGauge,
-		metrics.MSpanInuseGauge,
-		metrics.MSpanSysGauge,
-		metrics.MallocsGauge,
-		metrics.OtherSysGauge,
-		metrics.StackInuseGauge,
-		metrics.StackSysGauge,
-		metrics.SysGauge,
-		metrics.TotalAllocGauge,
-	}
-
-	for _, gauge := range gauges {
-		t.Run(gauge.Name, func(t *testing.T) {
-			assert.Greater(t, gauge.Value, 0.0)
-		})
-	}
-
-	t.Run(metrics.PollCountCounter.Name, func(t *testing.T) {
-		assert.Positive(t, metrics.PollCountCounter.Value)
-	})
-}
-
-func TestPollFromPsutils(t *testing.T) {
-	lock := &sync.Mutex{}
-	cfg := config.AgentConfig{PollInterval: 1}
-
-	ctx := context.Background()
-	ctxT, cancel := context.WithTimeout(ctx, time.Duration(cfg.PollInterval)*time.Second+time.Second)
-	defer cancel()
-
-	var wg sync.WaitGroup
-	metricPollerPsutils(ctxT, &wg, &cfg, lock)
-
-	gauges := []metrics.Gauge{
-		metrics.TotalMemory,
-		metrics.FreeMemory,
-	}
-
-	for _, gauge := range gauges {
-		t.Run(gauge.Name, func(t *testing.T) {
-			assert.Greater(t, gauge.Value, 0.0)
-		})
-	}
-	for _, gauge := range metrics.CPUutilizations {
-		t.Run(gauge.Name, func(t *testing.T) {
-			assert.GreaterOrEqual(t, gauge.Value, 0.0)
-		})
-	}
-}
-
 func TestRun(t *testing.T) {
 	var called bool
 
@@ -112,7 +44,7 @@ func TestRun(t *testing.T) {
 
 	var wg sync.WaitGroup
 
-	Run(
+	err := Run(
 		ctx,
 		&wg,
 		&config.AgentConfig{
@@ -123,9 +55,190 @@ func TestRun(t *testing.T) {
 		},
 		nil,
 	)
+	require.NoError(t, err)
 
 	<-ctx.Done()
 
 	// Verify the result
 	assert.True(t, called)
 }
+
+func TestDeliverBatchKeepsUnsentDeltas(t *testing.T) {
+	var requests int
+	var received int64
+	keys := make(map[string]int)
+
+	// сервер недоступен для первых двух батчей
+	srv := gin.New()
+	srv.Use(middlewares.GzipDecompressMiddleware())
+	srv.POST("/updates", func(c *gin.Context) {
+		requests++
+		keys[c.GetHeader("Idempotency-Key")]++
+		if requests <= 2 {
+			c.Status(http.StatusServiceUnavailable)
+			return
+		}
+		var actualMetrics []model.MetricsV2
+		require.NoError(t, c.BindJSON(&actualMetrics))
+		for _, m := range actualMetrics {
+			received += *m.Delta
+		}
+	})
+	testSrv := httptest.NewServer(srv)
+	defer testSrv.Close()
+
+	client := transport.NewClient(testSrv.URL, "", nil)
+	set := metrics.NewSet()
+
+	var total int64
+	for i := int64(1); i <= 4; i++ {
+		delta := i
+		require.NoError(t, set.Update("runtime", []model.MetricsV2{{ID: "PollCount", MType: model.CounterType, Delta: &delta}}))
+		total += i
+
+		deliverBatch(context.Background(), client, set, set.Batch(), 0)
+	}
+
+	assert.Equal(t, 4, requests)
+	assert.Equal(t, total, received)
+	// неотправленный батч повторяется с тем же ключом
+	assert.Len(t, keys, 2)
+	assert.Equal(t, int64(0), *set.Batch().Data[0].Delta)
+}
+
+func TestDeliverBatchDropsRejected(t *testing.T) {
+	var requests int
+	srv := gin.New()
+	srv.POST("/updates", func(c *gin.Context) {
+		requests++
+		if requests == 1 {
+			c.String(http.StatusBadRequest, "invalid metrics")
+		}
+	})
+	testSrv := httptest.NewServer(srv)
+	defer testSrv.Close()
+
+	client := transport.NewClient(testSrv.URL, "", nil)
+	set := metrics.NewSet()
+	delta := int64(2)
+	require.NoError(t, set.Update("runtime", []model.MetricsV2{{ID: "PollCount", MType: model.CounterType, Delta: &delta}}))
+
+	// отклоненный батч не отправляется повторно и не блокирует новые
+	rejected := set.Batch()
+	deliverBatch(context.Background(), client, set, rejected, 0)
+	batch := set.Batch()
+	assert.NotSame(t, rejected, batch)
+	assert.Equal(t, int64(0), *batch.Data[0].Delta)
+	deliverBatch(context.Background(), client, set, batch, 0)
+	assert.Equal(t, 2, requests)
+}
+
+func TestSendQueue(t *testing.T) {
+	dir := t.TempDir()
+	q, err := queue.Open(queue.Config{Dir: dir})
+	require.NoError(t, err)
+	delta := int64(1)
+	for _, key := range []string{"a", "b", "c"} {
+		rec := &queue.Record{Key: key, Metrics: []model.MetricsV2{{ID: "PollCount", MType: model.CounterType, Delta: &delta}}}
+		require.NoError(t, q.Push(rec))
+	}
+	// очередь переживает перезапуск агента
+	require.NoError(t, q.Close())
+	q, err = queue.Open(queue.Config{Dir: dir})
+	require.NoError(t, err)
+	defer q.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// первые две попытки сервер недоступен
+	var requests int
+	var keys []string
+	srv := gin.New()
+	srv.Use(middlewares.GzipDecompressMiddleware())
+	srv.POST("/updates", func(c *gin.Context) {
+		requests++
+		if requests <= 2 {
+			c.Status(http.StatusServiceUnavailable)
+			return
+		}
+		keys = append(keys, c.GetHeader("Idempotency-Key"))
+		if len(keys) == 3 {
+			cancel()
+		}
+	})
+	testSrv := httptest.NewServer(srv)
+	defer testSrv.Close()
+
+	sendQueue(ctx, transport.NewClient(testSrv.URL, "", nil), q, 10*time.Millisecond)
+
+	assert.Equal(t, []string{"a", "b", "c"}, keys)
+	assert.Equal(t, 5, requests)
+}
+
+func TestSendQueueDropsRejected(t *testing.T) {
+	q, err := queue.Open(queue.Config{Dir: t.TempDir()})
+	require.NoError(t, err)
+	defer q.Close()
+	delta := int64(1)
+	for _, key := range []string{"a", "b"} {
+		rec := &queue.Record{Key: key, Metrics: []model.MetricsV2{{ID: "PollCount", MType: model.CounterType, Delta: &delta}}}
+		require.NoError(t, q.Push(rec))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// сервер отклоняет первую запись, она не блокирует очередь
+	var keys []string
+	srv := gin.New()
+	srv.POST("/updates", func(c *gin.Context) {
+		keys = append(keys, c.GetHeader("Idempotency-Key"))
+		if len(keys) == 1 {
+			c.String(http.StatusBadRequest, "invalid metrics")
+			return
+		}
+		cancel()
+	})
+	testSrv := httptest.NewServer(srv)
+	defer testSrv.Close()
+
+	sendQueue(ctx, transport.NewClient(testSrv.URL, "", nil), q, time.Hour)
+
+	assert.Equal(t, []string{"a", "b"}, keys)
+}
+
+type fakeCollector struct {
+	res []model.MetricsV2
+	err error
+}
+
+func (c *fakeCollector) Collect(context.Context) ([]model.MetricsV2, error) {
+	return c.res, c.err
+}
+
+func TestPollCollectorKeepsMetricsOnFailure(t *testing.T) {
+	value := 1.5
+	fake := &fakeCollector{res: []model.MetricsV2{{ID: "Load1", MType: model.GaugeType, Value: &value}}}
+	c := &collector.Scheduled{Collector: fake, Name: "load"}
+	set := metrics.NewSet()
+
+	pollCollector(context.Background(), c, set)
+	fake.res, fake.err = nil, errors.New("load is not available")
+	pollCollector(context.Background(), c, set)
+	assert.Len(t, set.Batch().Data, 1)
+
+	// коллектор без ошибок больше не сообщает метрику
+	fake.err = nil
+	pollCollector(context.Background(), c, set)
+	assert.Empty(t, set.Batch().Data)
+}
+
+func TestRunUnknownCollector(t *testing.T) {
+	var wg sync.WaitGroup
+	err := Run(context.Background(), &wg, &config.AgentConfig{
+		PollInterval: 1,
+		Collectors:   map[string]config.CollectorConfig{"unknown": {}},
+	}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,217 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"metrics/internal/core/model"
+)
+
+func init() {
+	Register("cgroup", newCgroupCollector, false)
+}
+
+const (
+	defaultCgroupRoot = "/sys/fs/cgroup"
+	selfCgroupFile    = "/proc/self/cgroup"
+)
+
+// cpuStatCounters maps cpu.stat keys to counters, times are in microseconds.
+var cpuStatCounters = map[string]string{
+	"usage_usec":     "CgroupCPUUsage",
+	"user_usec":      "CgroupCPUUser",
+	"system_usec":    "CgroupCPUSystem",
+	"nr_periods":     "CgroupCPUPeriods",
+	"nr_throttled":   "CgroupCPUThrottledPeriods",
+	"throttled_usec": "CgroupCPUThrottledTime",
+}
+
+// ioStatCounters maps io.stat keys to counters labeled by the device major:minor.
+var ioStatCounters = map[string]string{
+	"rbytes": "CgroupIOReadBytes",
+	"wbytes": "CgroupIOWriteBytes",
+	"rios":   "CgroupIOReads",
+	"wios":   "CgroupIOWrites",
+}
+
+type cgroupOptions struct {
+	Root string `json:"root"` // cgroup v2 mount, /sys/fs/cgroup by default
+	Path string `json:"path"` // cgroup path relative to the root, the agent cgroup by default
+}
+
+// cgroupCollector reports usage of the cgroup v2 read from the cgroup files:
+//   - memory.current and memory.max are CgroupMemoryUsage and CgroupMemoryLimit;
+//   - cpu.stat are CgroupCPU* counters and CgroupCPUPercent, 100 is a single core;
+//   - io.stat are CgroupIO* counters labeled by the device;
+//   - pids.current and pids.max are CgroupPids and CgroupPidsLimit.
+//
+// Files of disabled controllers are skipped, unlimited limits are not reported.
+type cgroupCollector struct {
+	dir      string
+	totals   *totals
+	now      func() time.Time
+	lastCPU  uint64
+	lastTime time.Time
+}
+
+func newCgroupCollector(options json.RawMessage) (Collector, error) {
+	opts := cgroupOptions{Root: defaultCgroupRoot}
+	if err := decodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	if opts.Path == "" {
+		var err error
+		if opts.Path, err = selfCgroup(selfCgroupFile); err != nil {
+			return nil, err
+		}
+	}
+	return &cgroupCollector{dir: filepath.Join(opts.Root, opts.Path), totals: newTotals(), now: time.Now}, nil
+}
+
+// selfCgroup returns the cgroup v2 path of the process: the line with the 0 hierarchy ID, e.g. 0::/system.slice/agent.
+func selfCgroup(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading process cgroup: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if cgroup, ok := strings.CutPrefix(line, "0::"); ok {
+			return cgroup, nil
+		}
+	}
+	return "", errors.New("cgroup v2 is not used by the process")
+}
+
+func (c *cgroupCollector) Collect(_ context.Context) ([]model.MetricsV2, error) {
+	res := make([]model.MetricsV2, 0)
+	var errs []error
+
+	gauges := []struct {
+		file, name string
+	}{
+		{"memory.current", "CgroupMemoryUsage"},
+		{"memory.max", "CgroupMemoryLimit"},
+		{"pids.current", "CgroupPids"},
+		{"pids.max", "CgroupPidsLimit"},
+	}
+	for _, g := range gauges {
+		value, ok, err := c.readValue(g.file)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if ok {
+			res = append(res, Gauge(g.name, nil, float64(value)))
+		}
+	}
+
+	res, err := c.cpuStat(res)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	res, err = c.ioStat(res)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	c.totals.prune()
+	return res, errors.Join(errs...)
+}
+
+// readFile returns nil if the file does not exist, i.e. the controller is disabled.
+func (c *cgroupCollector) readFile(name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(c.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// readValue reads the single value file, max means no limit and is skipped.
+func (c *cgroupCollector) readValue(name string) (uint64, bool, error) {
+	data, err := c.readFile(name)
+	if data == nil || err != nil {
+		return 0, false, err
+	}
+	s := strings.TrimSpace(string(data))
+	if s == "max" {
+		return 0, false, nil
+	}
+	value, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return value, true, nil
+}
+
+func (c *cgroupCollector) cpuStat(res []model.MetricsV2) ([]model.MetricsV2, error) {
+	data, err := c.readFile("cpu.stat")
+	if data == nil || err != nil {
+		return res, err
+	}
+
+	now := c.now()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, s, ok := strings.Cut(scanner.Text(), " ")
+		name, known := cpuStatCounters[key]
+		if !ok || !known {
+			continue
+		}
+		value, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return res, fmt.Errorf("invalid cpu.stat %s: %w", key, err)
+		}
+		res = c.totals.counter(res, name, nil, value)
+
+		if key != "usage_usec" {
+			continue
+		}
+		elapsed := now.Sub(c.lastTime).Microseconds()
+		if !c.lastTime.IsZero() && elapsed > 0 && value >= c.lastCPU {
+			res = append(res, Gauge("CgroupCPUPercent", nil, float64(value-c.lastCPU)/float64(elapsed)*100))
+		}
+		c.lastCPU, c.lastTime = value, now
+	}
+	return res, nil
+}
+
+// ioStat parses lines like "8:0 rbytes=1024 wbytes=0 rios=1 wios=0 dbytes=0 dios=0".
+func (c *cgroupCollector) ioStat(res []model.MetricsV2) ([]model.MetricsV2, error) {
+	data, err := c.readFile("io.stat")
+	if data == nil || err != nil {
+		return res, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		labels := model.Labels{"device": fields[0]}
+		for _, field := range fields[1:] {
+			key, s, _ := strings.Cut(field, "=")
+			name, ok := ioStatCounters[key]
+			if !ok {
+				continue
+			}
+			value, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				return res, fmt.Errorf("invalid io.stat %s: %w", key, err)
+			}
+			res = c.totals.counter(res, name, labels, value)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCgroup creates cgroup files in the root directory.
+func fakeCgroup(t *testing.T, root, path string, files map[string]string) {
+	t.Helper()
+	dir := filepath.Join(root, path)
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	for name, data := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644))
+	}
+}
+
+func TestSelfCgroup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cgroup")
+	require.NoError(t, os.WriteFile(path, []byte("0::/system.slice/agent.service\n"), 0o644))
+	cgroup, err := selfCgroup(path)
+	require.NoError(t, err)
+	assert.Equal(t, "/system.slice/agent.service", cgroup)
+
+	// cgroup v1
+	require.NoError(t, os.WriteFile(path, []byte("12:memory:/docker/abc\n11:cpu,cpuacct:/docker/abc\n"), 0o644))
+	_, err = selfCgroup(path)
+	assert.Error(t, err)
+}
+
+func TestCgroupCollector(t *testing.T) {
+	root := t.TempDir()
+	fakeCgroup(t, root, "docker/abc", map[string]string{
+		"memory.current": "104857600\n",
+		"memory.max":     "536870912\n",
+		"pids.current":   "12\n",
+		"pids.max":       "max\n",
+		"cpu.stat": "usage_usec 1000000\nuser_usec 600000\nsystem_usec 400000\n" +
+			"nr_periods 100\nnr_throttled 5\nthrottled_usec 20000\n",
+		"io.stat": "8:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0\n",
+	})
+
+	c, err := newCgroupCollector(json.RawMessage(`{"root":"` + root + `","path":"docker/abc"}`))
+	require.NoError(t, err)
+	cgroup := c.(*cgroupCollector)
+	now := time.Now()
+	cgroup.now = func() time.Time { return now }
+
+	res := collect(t, c)
+	assert.Len(t, res, 3)
+	assert.Equal(t, 104857600.0, *res["CgroupMemoryUsage"].Value)
+	assert.Equal(t, 536870912.0, *res["CgroupMemoryLimit"].Value)
+	assert.Equal(t, 12.0, *res["CgroupPids"].Value)
+	assert.NotContains(t, res, "CgroupPidsLimit")
+
+	// за 2 секунды контейнер использовал 1 секунду CPU и был ограничен 3 раза
+	now = now.Add(2 * time.Second)
+	fakeCgroup(t, root, "docker/abc", map[string]string{
+		"cpu.stat": "usage_usec 2000000\nuser_usec 1200000\nsystem_usec 800000\n" +
+			"nr_periods 120\nnr_throttled 8\nthrottled_usec 50000\n",
+		"io.stat": "8:0 rbytes=8192 wbytes=8192 rios=2 wios=2 dbytes=0 dios=0\n",
+	})
+	res = collect(t, c)
+	assert.InDelta(t, 50.0, *res["CgroupCPUPercent"].Value, 1e-9)
+	assert.Equal(t, int64(1000000), *res["CgroupCPUUsage"].Delta)
+	assert.Equal(t, int64(3), *res["CgroupCPUThrottledPeriods"].Delta)
+	assert.Equal(t, int64(30000), *res["CgroupCPUThrottledTime"].Delta)
+	assert.Equal(t, int64(4096), *res[`CgroupIOReadBytes{device="8:0"}`].Delta)
+	assert.Equal(t, int64(1), *res[`CgroupIOReads{device="8:0"}`].Delta)
+	assert.Equal(t, int64(0), *res[`CgroupIOWriteBytes{device="8:0"}`].Delta)
+}
+
+func TestCgroupCollectorErrors(t *testing.T) {
+	root := t.TempDir()
+	// контроллеры memory и io выключены
+	fakeCgroup(t, root, "agent", map[string]string{
+		"pids.current": "3\n",
+		"cpu.stat":     "usage_usec abc\n",
+	})
+
+	c, err := newCgroupCollector(json.RawMessage(`{"root":"` + root + `","path":"agent"}`))
+	require.NoError(t, err)
+	collected, err := c.Collect(context.Background())
+	assert.Error(t, err)
+	require.Len(t, collected, 1)
+	assert.Equal(t, "CgroupPids", collected[0].ID)
+
+	_, err = newCgroupCollector(json.RawMessage(`{"mount":"/sys/fs/cgroup"}`))
+	assert.Error(t, err)
+}
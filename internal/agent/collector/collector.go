@@ -0,0 +1,130 @@
+// Package collector defines collectors of agent metrics and the registry to configure them.
+//
+// Built-in collectors are registered by the package. Custom collectors are registered by Register
+// before the agent is started and are configured by the name like the built-in ones.
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"metrics/internal/agent/config"
+	"metrics/internal/core/model"
+)
+
+// Collector gathers metrics of a single source.
+type Collector interface {
+	// Collect returns gauges with current values and counters with increments since the previous call.
+	// Metrics gathered before the error are returned with it.
+	Collect(ctx context.Context) ([]model.MetricsV2, error)
+}
+
+// Factory creates the collector. Options are the raw JSON options from the collector config, nil if not set.
+type Factory func(options json.RawMessage) (Collector, error)
+
+// Scheduled is the collector polled with its own interval.
+type Scheduled struct {
+	Collector Collector
+	Name      string
+	Interval  time.Duration
+}
+
+type registration struct {
+	factory Factory
+	enabled bool
+}
+
+// Registry keeps collector factories by names.
+type Registry struct {
+	mux        sync.RWMutex
+	collectors map[string]*registration
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]*registration)}
+}
+
+// Register adds the collector factory. Collectors enabled by default are built unless disabled by the config.
+func (r *Registry) Register(name string, factory Factory, enabled bool) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if _, ok := r.collectors[name]; ok {
+		return fmt.Errorf("collector %s is already registered", name)
+	}
+	r.collectors[name] = &registration{factory: factory, enabled: enabled}
+	return nil
+}
+
+// Names returns sorted names of registered collectors.
+func (r *Registry) Names() []string {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	return r.names()
+}
+
+func (r *Registry) names() []string {
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Build creates enabled collectors ordered by names. Collectors without interval are polled every defaultInterval.
+func (r *Registry) Build(cfg map[string]config.CollectorConfig, defaultInterval time.Duration) ([]*Scheduled, error) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	for name := range cfg {
+		if _, ok := r.collectors[name]; !ok {
+			return nil, fmt.Errorf("unknown collector: %s", name)
+		}
+	}
+
+	res := make([]*Scheduled, 0, len(r.collectors))
+	for _, name := range r.names() {
+		reg := r.collectors[name]
+		c := cfg[name]
+		if c.Enabled != nil && !*c.Enabled || c.Enabled == nil && !reg.enabled {
+			continue
+		}
+		if c.Interval < 0 {
+			return nil, fmt.Errorf("collector %s: interval could not be negative", name)
+		}
+		collector, err := reg.factory(c.Options)
+		if err != nil {
+			return nil, fmt.Errorf("error creating collector %s: %w", name, err)
+		}
+
+		interval := defaultInterval
+		if c.Interval > 0 {
+			interval = time.Duration(c.Interval) * time.Second
+		}
+		res = append(res, &Scheduled{Collector: collector, Name: name, Interval: interval})
+	}
+	return res, nil
+}
+
+// Default is the registry used by the agent.
+var Default = NewRegistry()
+
+// Register adds the collector to the default registry, it panics if the name is already registered.
+func Register(name string, factory Factory, enabled bool) {
+	if err := Default.Register(name, factory, enabled); err != nil {
+		panic(err)
+	}
+}
+
+// Gauge returns the gauge payload.
+func Gauge(name string, labels model.Labels, value float64) model.MetricsV2 {
+	return model.MetricsV2{ID: name, MType: model.GaugeType, Labels: labels, Value: &value}
+}
+
+// Counter returns the counter increment payload.
+func Counter(name string, labels model.Labels, delta int64) model.MetricsV2 {
+	return model.MetricsV2{ID: name, MType: model.CounterType, Labels: labels, Delta: &delta}
+}
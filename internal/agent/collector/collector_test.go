@@ -0,0 +1,161 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"metrics/internal/agent/config"
+	"metrics/internal/core/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticCollector struct {
+	options json.RawMessage
+}
+
+func (c *staticCollector) Collect(context.Context) ([]model.MetricsV2, error) {
+	return []model.MetricsV2{Gauge("Static", nil, 1)}, nil
+}
+
+func newStatic(options json.RawMessage) (Collector, error) {
+	return &staticCollector{options: options}, nil
+}
+
+func TestRegistryBuild(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.Register("b", newStatic, true))
+	require.NoError(t, r.Register("a", newStatic, true))
+	require.NoError(t, r.Register("optional", newStatic, false))
+	require.NoError(t, r.Register("broken", func(json.RawMessage) (Collector, error) {
+		return nil, errors.New("invalid options")
+	}, false))
+	assert.Error(t, r.Register("a", newStatic, true))
+	assert.Equal(t, []string{"a", "b", "broken", "optional"}, r.Names())
+
+	enabled, disabled := true, false
+	tests := []struct {
+		name      string
+		cfg       map[string]config.CollectorConfig
+		want      []string
+		intervals []time.Duration
+		wantErr   bool
+	}{
+		{
+			name:      "defaults",
+			want:      []string{"a", "b"},
+			intervals: []time.Duration{2 * time.Second, 2 * time.Second},
+		},
+		{
+			name: "enable and disable",
+			cfg: map[string]config.CollectorConfig{
+				"a":        {Enabled: &disabled},
+				"optional": {Enabled: &enabled, Interval: 10, Options: json.RawMessage(`{"path":"/tmp"}`)},
+			},
+			want:      []string{"b", "optional"},
+			intervals: []time.Duration{2 * time.Second, 10 * time.Second},
+		},
+		{
+			name:    "unknown collector",
+			cfg:     map[string]config.CollectorConfig{"c": {}},
+			wantErr: true,
+		},
+		{
+			name:    "negative interval",
+			cfg:     map[string]config.CollectorConfig{"a": {Interval: -1}},
+			wantErr: true,
+		},
+		{
+			name:    "factory error",
+			cfg:     map[string]config.CollectorConfig{"broken": {Enabled: &enabled}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := r.Build(tt.cfg, 2*time.Second)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			names := make([]string, 0, len(res))
+			intervals := make([]time.Duration, 0, len(res))
+			for _, c := range res {
+				names = append(names, c.Name)
+				intervals = append(intervals, c.Interval)
+				assert.Equal(t, tt.cfg[c.Name].Options, c.Collector.(*staticCollector).options)
+			}
+			assert.Equal(t, tt.want, names)
+			assert.Equal(t, tt.intervals, intervals)
+		})
+	}
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	assert.Equal(t,
+		[]string{
+			"cgroup", "cpu", "disk", "exec", "filesystem", "load", "memory", "network", "process", "runtime", "scrape",
+			"swap", "textfile", "uptime",
+		},
+		Default.Names(),
+	)
+
+	// host collectors are enabled by the config only
+	res, err := Default.Build(nil, time.Second)
+	require.NoError(t, err)
+	names := make([]string, 0, len(res))
+	for _, c := range res {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"cpu", "memory", "runtime"}, names)
+	assert.Panics(t, func() { Register("runtime", newStatic, true) })
+}
+
+func collect(t *testing.T, c Collector) map[string]model.MetricsV2 {
+	t.Helper()
+	collected, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	res := make(map[string]model.MetricsV2, len(collected))
+	for _, m := range collected {
+		res[model.MetricKey(m.ID, m.Labels)] = m
+	}
+	return res
+}
+
+func TestRuntimeCollector(t *testing.T) {
+	c, err := newRuntimeCollector(nil)
+	require.NoError(t, err)
+	res := collect(t, c)
+
+	assert.Equal(t, int64(1), *res["PollCount"].Delta)
+	for _, name := range []string{"Alloc", "HeapAlloc", "HeapSys", "StackSys", "Sys", "TotalAlloc"} {
+		require.Contains(t, res, name)
+		assert.Positive(t, *res[name].Value, name)
+	}
+	assert.Len(t, res, 29)
+}
+
+func TestMemoryCollector(t *testing.T) {
+	c, err := newMemoryCollector(nil)
+	require.NoError(t, err)
+	res := collect(t, c)
+
+	assert.Positive(t, *res["TotalMemory"].Value)
+	assert.Positive(t, *res["FreeMemory"].Value)
+}
+
+func TestCPUCollector(t *testing.T) {
+	c, err := newCPUCollector(nil)
+	require.NoError(t, err)
+	res := collect(t, c)
+
+	require.Contains(t, res, "CPUutilization1")
+	for _, m := range res {
+		assert.GreaterOrEqual(t, *m.Value, 0.0)
+	}
+}
@@ -0,0 +1,108 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"metrics/internal/core/model"
+)
+
+func init() {
+	Register("exec", newExecCollector, false)
+}
+
+const (
+	defaultExecTimeout = 10 * time.Second
+	maxExecStderr      = 256
+)
+
+type execCommand struct {
+	Name    string   `json:"name"` // name in errors, the command by default
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Timeout int64    `json:"timeout"` // seconds, 10 by default
+
+	exposition *exposition
+}
+
+type execOptions struct {
+	Commands []*execCommand `json:"commands"`
+}
+
+// execCollector runs commands and parses their stdout like the textfile collector:
+// JSON array of metrics or the Prometheus text format.
+// Commands are run one by one, a command is killed after the timeout.
+// Output of a failed command or a command exited with non-zero code is skipped.
+type execCollector struct {
+	commands []*execCommand
+}
+
+func newExecCollector(options json.RawMessage) (Collector, error) {
+	var opts execOptions
+	if err := decodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	if len(opts.Commands) == 0 {
+		return nil, errors.New("no commands to run")
+	}
+	for _, cmd := range opts.Commands {
+		if cmd.Command == "" {
+			return nil, errors.New("command is required")
+		}
+		if cmd.Timeout < 0 {
+			return nil, fmt.Errorf("command %s: timeout could not be negative", cmd.Command)
+		}
+		if cmd.Name == "" {
+			cmd.Name = cmd.Command
+		}
+		cmd.exposition = newExposition()
+	}
+	return &execCollector{commands: opts.Commands}, nil
+}
+
+func (c *execCollector) Collect(ctx context.Context) ([]model.MetricsV2, error) {
+	res := make([]model.MetricsV2, 0)
+	var errs []error
+	for _, cmd := range c.commands {
+		var err error
+		if res, err = cmd.collect(ctx, res); err != nil {
+			errs = append(errs, fmt.Errorf("command %s: %w", cmd.Name, err))
+		}
+	}
+	return res, errors.Join(errs...)
+}
+
+func (c *execCommand) collect(ctx context.Context, res []model.MetricsV2) ([]model.MetricsV2, error) {
+	timeout := defaultExecTimeout
+	if c.Timeout > 0 {
+		timeout = time.Duration(c.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Command, c.Args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// дочерние процессы могут держать stdout после завершения команды
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg[:min(len(msg), maxExecStderr)])
+		}
+		return res, err
+	}
+
+	res, err := c.exposition.parse(res, stdout.Bytes())
+	c.exposition.totals.prune()
+	return res, err
+}
@@ -0,0 +1,80 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// script writes the shell script to the temporary directory.
+func script(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755))
+	return path
+}
+
+func TestExecCollector(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "counter")
+	require.NoError(t, os.WriteFile(counter, []byte("5"), 0o644))
+	text := script(t, `echo "# TYPE requests_total counter"
+echo "requests_total $(cat `+counter+`)"
+echo 'up{job="app"} 1'`)
+	options, err := json.Marshal(execOptions{Commands: []*execCommand{
+		{Command: text},
+		{Command: "echo", Args: []string{
+			`[{"id":"Users","type":"gauge","value":42},{"id":"Logins","type":"counter","delta":3}]`,
+		}},
+	}})
+	require.NoError(t, err)
+
+	c, err := newExecCollector(options)
+	require.NoError(t, err)
+	// дельты в JSON - приращения, они отправляются при каждом запуске
+	res := collect(t, c)
+	assert.Len(t, res, 3)
+	assert.Equal(t, 1.0, *res[`up{job="app"}`].Value)
+	assert.Equal(t, 42.0, *res["Users"].Value)
+	assert.Equal(t, int64(3), *res["Logins"].Delta)
+
+	require.NoError(t, os.WriteFile(counter, []byte("7"), 0o644))
+	res = collect(t, c)
+	assert.Len(t, res, 4)
+	assert.Equal(t, int64(2), *res["requests_total"].Delta)
+	assert.Equal(t, int64(3), *res["Logins"].Delta)
+}
+
+func TestExecCollectorFailure(t *testing.T) {
+	options, err := json.Marshal(execOptions{Commands: []*execCommand{
+		{Name: "failed", Command: script(t, "echo 'up 1'; echo 'no database' >&2; exit 1")},
+		{Name: "slow", Command: "sleep", Args: []string{"10"}, Timeout: 1},
+		{Command: "echo", Args: []string{"up 1"}},
+	}})
+	require.NoError(t, err)
+
+	c, err := newExecCollector(options)
+	require.NoError(t, err)
+	res, err := c.Collect(context.Background())
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "command failed: exit status 1: no database")
+	assert.ErrorContains(t, err, "command slow: context deadline exceeded")
+	assert.Len(t, res, 1)
+}
+
+func TestExecCollectorOptions(t *testing.T) {
+	for _, options := range []string{
+		``,
+		`{"commands":[]}`,
+		`{"commands":[{"args":["1"]}]}`,
+		`{"commands":[{"command":"echo","timeout":-1}]}`,
+		`{"commands":[{"command":"echo","env":["A=1"]}]}`,
+	} {
+		_, err := newExecCollector(json.RawMessage(options))
+		assert.Error(t, err, options)
+	}
+}
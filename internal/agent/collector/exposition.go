@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"metrics/internal/core/model"
+	"metrics/internal/infra/prometheus"
+)
+
+// exposition converts metrics exposed by scripts and other programs in the Prometheus text format
+// or as a JSON array of metrics, e.g. [{"id":"Jobs","type":"counter","value":10}].
+// Counters of the text format and JSON counters with the value are totals, they are reported as increments
+// since the previous collection. JSON counters with the delta are increments like in the server API,
+// they are reported as is on every collection, so files read repeatedly should expose totals.
+type exposition struct {
+	totals *floatTotals
+}
+
+func newExposition() *exposition {
+	return &exposition{totals: newFloatTotals()}
+}
+
+// parse detects the format: JSON array starts with a bracket, anything else is the text format.
+func (e *exposition) parse(res []model.MetricsV2, data []byte) ([]model.MetricsV2, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return e.parseJSON(res, data)
+	}
+	return e.parseText(res, data)
+}
+
+func (e *exposition) parseText(res []model.MetricsV2, data []byte) ([]model.MetricsV2, error) {
+	samples, err := prometheus.ParseText(bytes.NewReader(data))
+	errs := []error{err}
+	for _, s := range samples {
+		if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+			errs = append(errs, fmt.Errorf("metric %s has invalid value: %v", s.Name, s.Value))
+			continue
+		}
+		if s.Type == model.GaugeType {
+			res = append(res, Gauge(s.Name, s.Labels, s.Value))
+			continue
+		}
+		if s.Value < 0 {
+			errs = append(errs, fmt.Errorf("counter %s is negative", s.Name))
+			continue
+		}
+		res = e.totals.counter(res, s.Name, s.Labels, s.Value)
+	}
+	return res, errors.Join(errs...)
+}
+
+func (e *exposition) parseJSON(res []model.MetricsV2, data []byte) ([]model.MetricsV2, error) {
+	var metrics []model.MetricsV2
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return res, fmt.Errorf("invalid metrics JSON: %w", err)
+	}
+
+	var errs []error
+	for _, m := range metrics {
+		switch {
+		case m.ID == "":
+			errs = append(errs, errors.New("metric has no id"))
+		case m.MType == model.GaugeType && m.Value != nil:
+			res = append(res, Gauge(m.ID, m.Labels, *m.Value))
+		case m.MType == model.CounterType && m.Value != nil && *m.Value >= 0 && !math.IsInf(*m.Value, 0):
+			res = e.totals.counter(res, m.ID, m.Labels, *m.Value)
+		case m.MType == model.CounterType && m.Value == nil && m.Delta != nil && *m.Delta >= 0:
+			res = append(res, Counter(m.ID, m.Labels, *m.Delta))
+		case m.MType == model.GaugeType || m.MType == model.CounterType:
+			errs = append(errs, fmt.Errorf("%s %s has no valid value", m.MType, m.ID))
+		default:
+			errs = append(errs, fmt.Errorf("metric %s has unsupported type: %s", m.ID, m.MType))
+		}
+	}
+	return res, errors.Join(errs...)
+}
+
+// floatTotals converts totals to counter increments like totals. Totals could be fractional,
+// so the fraction of the increment is carried to the next collection instead of being truncated.
+type floatTotals struct {
+	last  map[string]float64
+	carry map[string]float64
+	seen  map[string]bool
+}
+
+func newFloatTotals() *floatTotals {
+	return &floatTotals{last: make(map[string]float64), carry: make(map[string]float64), seen: make(map[string]bool)}
+}
+
+// counter appends the increment of the total to res.
+func (t *floatTotals) counter(
+	res []model.MetricsV2, name string, labels model.Labels, total float64,
+) []model.MetricsV2 {
+	key := model.MetricKey(name, labels)
+	last, ok := t.last[key]
+	t.last[key] = total
+	t.seen[key] = true
+	if !ok {
+		return res
+	}
+	delta := total - last
+	if total < last {
+		delta = total
+	}
+	delta += t.carry[key]
+	increment := math.Floor(delta)
+	t.carry[key] = delta - increment
+	return append(res, Counter(name, labels, int64(increment)))
+}
+
+// prune forgets series which were not reported since the previous prune.
+func (t *floatTotals) prune() {
+	for key := range t.last {
+		if !t.seen[key] {
+			delete(t.last, key)
+			delete(t.carry, key)
+		}
+	}
+	clear(t.seen)
+}
@@ -0,0 +1,37 @@
+package collector
+
+import (
+	"testing"
+
+	"metrics/internal/core/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpositionFractionalTotals(t *testing.T) {
+	e := newExposition()
+	var increments []int64
+	for _, data := range []string{
+		"# TYPE job_seconds_total counter\njob_seconds_total 1.5\n",
+		"# TYPE job_seconds_total counter\njob_seconds_total 2.25\n",
+		"# TYPE job_seconds_total counter\njob_seconds_total 3.5\n",
+		`[{"id":"job_seconds_total","type":"counter","value":4.75}]`,
+	} {
+		res, err := e.parse(nil, []byte(data))
+		require.NoError(t, err)
+		for _, m := range res {
+			increments = append(increments, *m.Delta)
+		}
+	}
+	// дробные части не теряются, а переносятся на следующий сбор
+	assert.Equal(t, []int64{0, 2, 1}, increments)
+}
+
+func TestExpositionJSONCounters(t *testing.T) {
+	e := newExposition()
+	data := `[{"id":"Jobs","type":"counter","delta":2},{"id":"Runs","type":"counter","value":-1}]`
+	res, err := e.parseJSON(nil, []byte(data))
+	assert.ErrorContains(t, err, "counter Runs has no valid value")
+	assert.Equal(t, []model.MetricsV2{Counter("Jobs", nil, 2)}, res)
+}
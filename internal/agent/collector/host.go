@@ -0,0 +1,242 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"metrics/internal/core/model"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/load"
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// Host collectors are disabled by default.
+func init() {
+	Register("disk", newDiskCollector, false)
+	Register("network", newNetworkCollector, false)
+	Register("load", newLoadCollector, false)
+	Register("swap", newSwapCollector, false)
+	Register("uptime", newUptimeCollector, false)
+	Register("filesystem", newFilesystemCollector, false)
+}
+
+// diskCollector reports I/O counters of block devices labeled by the device.
+type diskCollector struct {
+	devices    []string
+	totals     *totals
+	ioCounters func(ctx context.Context, names ...string) (map[string]disk.IOCountersStat, error)
+}
+
+type diskOptions struct {
+	Devices []string `json:"devices"` // all devices if empty
+}
+
+func newDiskCollector(options json.RawMessage) (Collector, error) {
+	var opts diskOptions
+	if err := decodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	return &diskCollector{devices: opts.Devices, totals: newTotals(), ioCounters: disk.IOCountersWithContext}, nil
+}
+
+func (c *diskCollector) Collect(ctx context.Context) ([]model.MetricsV2, error) {
+	counters, err := c.ioCounters(ctx, c.devices...)
+	if err != nil {
+		return nil, fmt.Errorf("error reading disk io counters: %w", err)
+	}
+	res := make([]model.MetricsV2, 0, len(counters)*7)
+	for _, name := range sortedKeys(counters) {
+		s := counters[name]
+		labels := model.Labels{"device": name}
+		res = c.totals.counter(res, "DiskReadBytes", labels, s.ReadBytes)
+		res = c.totals.counter(res, "DiskWriteBytes", labels, s.WriteBytes)
+		res = c.totals.counter(res, "DiskReads", labels, s.ReadCount)
+		res = c.totals.counter(res, "DiskWrites", labels, s.WriteCount)
+		res = c.totals.counter(res, "DiskReadTime", labels, s.ReadTime) // milliseconds
+		res = c.totals.counter(res, "DiskWriteTime", labels, s.WriteTime)
+		res = c.totals.counter(res, "DiskIOTime", labels, s.IoTime)
+	}
+	c.totals.prune()
+	return res, nil
+}
+
+// networkCollector reports traffic counters of network interfaces labeled by the interface.
+type networkCollector struct {
+	interfaces []string
+	totals     *totals
+	ioCounters func(ctx context.Context, pernic bool) ([]net.IOCountersStat, error)
+}
+
+type networkOptions struct {
+	Interfaces []string `json:"interfaces"` // all interfaces if empty
+}
+
+func newNetworkCollector(options json.RawMessage) (Collector, error) {
+	var opts networkOptions
+	if err := decodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	return &networkCollector{interfaces: opts.Interfaces, totals: newTotals(), ioCounters: net.IOCountersWithContext}, nil
+}
+
+func (c *networkCollector) Collect(ctx context.Context) ([]model.MetricsV2, error) {
+	counters, err := c.ioCounters(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("error reading network io counters: %w", err)
+	}
+	res := make([]model.MetricsV2, 0, len(counters)*8)
+	for _, s := range counters {
+		if len(c.interfaces) > 0 && !slices.Contains(c.interfaces, s.Name) {
+			continue
+		}
+		labels := model.Labels{"interface": s.Name}
+		res = c.totals.counter(res, "NetBytesSent", labels, s.BytesSent)
+		res = c.totals.counter(res, "NetBytesRecv", labels, s.BytesRecv)
+		res = c.totals.counter(res, "NetPacketsSent", labels, s.PacketsSent)
+		res = c.totals.counter(res, "NetPacketsRecv", labels, s.PacketsRecv)
+		res = c.totals.counter(res, "NetErrorsIn", labels, s.Errin)
+		res = c.totals.counter(res, "NetErrorsOut", labels, s.Errout)
+		res = c.totals.counter(res, "NetDropsIn", labels, s.Dropin)
+		res = c.totals.counter(res, "NetDropsOut", labels, s.Dropout)
+	}
+	c.totals.prune()
+	return res, nil
+}
+
+// loadCollector reports load averages.
+type loadCollector struct {
+	avg func(ctx context.Context) (*load.AvgStat, error)
+}
+
+func newLoadCollector(options json.RawMessage) (Collector, error) {
+	if err := decodeOptions(options, &struct{}{}); err != nil {
+		return nil, err
+	}
+	return &loadCollector{avg: load.AvgWithContext}, nil
+}
+
+func (c *loadCollector) Collect(ctx context.Context) ([]model.MetricsV2, error) {
+	avg, err := c.avg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading load average: %w", err)
+	}
+	return []model.MetricsV2{
+		Gauge("Load1", nil, avg.Load1),
+		Gauge("Load5", nil, avg.Load5),
+		Gauge("Load15", nil, avg.Load15),
+	}, nil
+}
+
+// swapCollector reports swap usage and swapped in and out bytes.
+type swapCollector struct {
+	totals *totals
+	swap   func(ctx context.Context) (*mem.SwapMemoryStat, error)
+}
+
+func newSwapCollector(options json.RawMessage) (Collector, error) {
+	if err := decodeOptions(options, &struct{}{}); err != nil {
+		return nil, err
+	}
+	return &swapCollector{totals: newTotals(), swap: mem.SwapMemoryWithContext}, nil
+}
+
+func (c *swapCollector) Collect(ctx context.Context) ([]model.MetricsV2, error) {
+	s, err := c.swap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading swap memory: %w", err)
+	}
+	res := []model.MetricsV2{
+		Gauge("SwapTotal", nil, float64(s.Total)),
+		Gauge("SwapUsed", nil, float64(s.Used)),
+		Gauge("SwapFree", nil, float64(s.Free)),
+		Gauge("SwapUsedPercent", nil, s.UsedPercent),
+	}
+	res = c.totals.counter(res, "SwapIn", nil, s.Sin)
+	res = c.totals.counter(res, "SwapOut", nil, s.Sout)
+	return res, nil
+}
+
+// uptimeCollector reports the host uptime in seconds.
+type uptimeCollector struct {
+	uptime func(ctx context.Context) (uint64, error)
+}
+
+func newUptimeCollector(options json.RawMessage) (Collector, error) {
+	if err := decodeOptions(options, &struct{}{}); err != nil {
+		return nil, err
+	}
+	return &uptimeCollector{uptime: host.UptimeWithContext}, nil
+}
+
+func (c *uptimeCollector) Collect(ctx context.Context) ([]model.MetricsV2, error) {
+	uptime, err := c.uptime(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading uptime: %w", err)
+	}
+	return []model.MetricsV2{Gauge("Uptime", nil, float64(uptime))}, nil
+}
+
+// filesystemCollector reports usage of mounted filesystems labeled by the mountpoint, device and type.
+type filesystemCollector struct {
+	mountpoints []string
+	partitions  func(ctx context.Context, all bool) ([]disk.PartitionStat, error)
+	usage       func(ctx context.Context, path string) (*disk.UsageStat, error)
+}
+
+type filesystemOptions struct {
+	Mountpoints []string `json:"mountpoints"` // all physical filesystems if empty
+}
+
+func newFilesystemCollector(options json.RawMessage) (Collector, error) {
+	var opts filesystemOptions
+	if err := decodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	return &filesystemCollector{
+		mountpoints: opts.Mountpoints,
+		partitions:  disk.PartitionsWithContext,
+		usage:       disk.UsageWithContext,
+	}, nil
+}
+
+// Collect skips filesystems which could not be read and returns the last error.
+func (c *filesystemCollector) Collect(ctx context.Context) ([]model.MetricsV2, error) {
+	partitions, err := c.partitions(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("error reading partitions: %w", err)
+	}
+	res := make([]model.MetricsV2, 0, len(partitions)*4)
+	var lastErr error
+	for _, p := range partitions {
+		if len(c.mountpoints) > 0 && !slices.Contains(c.mountpoints, p.Mountpoint) {
+			continue
+		}
+		usage, err := c.usage(ctx, p.Mountpoint)
+		if err != nil {
+			lastErr = fmt.Errorf("error reading %s usage: %w", p.Mountpoint, err)
+			continue
+		}
+		labels := model.Labels{"mountpoint": p.Mountpoint, "device": p.Device, "fstype": p.Fstype}
+		res = append(res,
+			Gauge("FilesystemTotal", labels, float64(usage.Total)),
+			Gauge("FilesystemUsed", labels, float64(usage.Used)),
+			Gauge("FilesystemFree", labels, float64(usage.Free)),
+			Gauge("FilesystemUsedPercent", labels, usage.UsedPercent),
+		)
+	}
+	return res, lastErr
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,151 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"metrics/internal/core/model"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/load"
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTotals(t *testing.T) {
+	tt := newTotals()
+	labels := model.Labels{"device": "sda"}
+
+	// первое значение - точка отсчета
+	assert.Empty(t, tt.counter(nil, "DiskReads", labels, 100))
+	assert.Equal(t, []model.MetricsV2{Counter("DiskReads", labels, 20)}, tt.counter(nil, "DiskReads", labels, 120))
+	// сброс счетчика
+	assert.Equal(t, []model.MetricsV2{Counter("DiskReads", labels, 5)}, tt.counter(nil, "DiskReads", labels, 5))
+
+	tt.prune()
+	tt.prune()
+	assert.Empty(t, tt.counter(nil, "DiskReads", labels, 10))
+}
+
+func TestDecodeOptions(t *testing.T) {
+	_, err := newDiskCollector(json.RawMessage(`{"devices":["sda"]}`))
+	require.NoError(t, err)
+	_, err = newDiskCollector(json.RawMessage(`{"device":["sda"]}`))
+	assert.Error(t, err)
+	_, err = newLoadCollector(json.RawMessage(`{"interval":1}`))
+	assert.Error(t, err)
+}
+
+func TestDiskCollector(t *testing.T) {
+	c, err := newDiskCollector(json.RawMessage(`{"devices":["sda"]}`))
+	require.NoError(t, err)
+	disks := c.(*diskCollector)
+
+	stat := disk.IOCountersStat{Name: "sda", ReadBytes: 1000, WriteBytes: 500, ReadCount: 10, WriteCount: 5}
+	disks.ioCounters = func(_ context.Context, names ...string) (map[string]disk.IOCountersStat, error) {
+		assert.Equal(t, []string{"sda"}, names)
+		return map[string]disk.IOCountersStat{"sda": stat}, nil
+	}
+
+	res := collect(t, c)
+	assert.Empty(t, res)
+
+	stat.ReadBytes, stat.ReadCount = 1500, 12
+	res = collect(t, c)
+	assert.Len(t, res, 7)
+	assert.Equal(t, int64(500), *res[`DiskReadBytes{device="sda"}`].Delta)
+	assert.Equal(t, int64(2), *res[`DiskReads{device="sda"}`].Delta)
+	assert.Equal(t, int64(0), *res[`DiskWriteBytes{device="sda"}`].Delta)
+}
+
+func TestNetworkCollector(t *testing.T) {
+	c, err := newNetworkCollector(json.RawMessage(`{"interfaces":["eth0"]}`))
+	require.NoError(t, err)
+	network := c.(*networkCollector)
+
+	eth := net.IOCountersStat{Name: "eth0", BytesSent: 100, BytesRecv: 200, Errin: 1}
+	lo := net.IOCountersStat{Name: "lo", BytesSent: 100}
+	network.ioCounters = func(context.Context, bool) ([]net.IOCountersStat, error) {
+		return []net.IOCountersStat{eth, lo}, nil
+	}
+
+	assert.Empty(t, collect(t, c))
+	eth.BytesSent, eth.Errin = 150, 3
+	lo.BytesSent = 1000
+	res := collect(t, c)
+	assert.Len(t, res, 8)
+	assert.Equal(t, int64(50), *res[`NetBytesSent{interface="eth0"}`].Delta)
+	assert.Equal(t, int64(2), *res[`NetErrorsIn{interface="eth0"}`].Delta)
+	assert.Equal(t, model.CounterType, res[`NetBytesRecv{interface="eth0"}`].MType)
+}
+
+func TestLoadCollector(t *testing.T) {
+	c := &loadCollector{avg: func(context.Context) (*load.AvgStat, error) {
+		return &load.AvgStat{Load1: 1.5, Load5: 1, Load15: 0.5}, nil
+	}}
+	res := collect(t, c)
+	assert.Equal(t, 1.5, *res["Load1"].Value)
+	assert.Equal(t, 1.0, *res["Load5"].Value)
+	assert.Equal(t, 0.5, *res["Load15"].Value)
+
+	c.avg = func(context.Context) (*load.AvgStat, error) {
+		return nil, errors.New("not supported")
+	}
+	_, err := c.Collect(context.Background())
+	assert.Error(t, err)
+}
+
+func TestSwapCollector(t *testing.T) {
+	stat := &mem.SwapMemoryStat{Total: 1000, Used: 250, Free: 750, UsedPercent: 25, Sin: 10, Sout: 20}
+	c := &swapCollector{totals: newTotals(), swap: func(context.Context) (*mem.SwapMemoryStat, error) {
+		return stat, nil
+	}}
+	res := collect(t, c)
+	assert.Len(t, res, 4)
+	assert.Equal(t, 25.0, *res["SwapUsedPercent"].Value)
+
+	stat.Sin, stat.Sout = 15, 20
+	res = collect(t, c)
+	assert.Equal(t, int64(5), *res["SwapIn"].Delta)
+	assert.Equal(t, int64(0), *res["SwapOut"].Delta)
+}
+
+func TestUptimeCollector(t *testing.T) {
+	c, err := newUptimeCollector(nil)
+	require.NoError(t, err)
+	res := collect(t, c)
+	assert.Positive(t, *res["Uptime"].Value)
+}
+
+func TestFilesystemCollector(t *testing.T) {
+	c := &filesystemCollector{
+		mountpoints: []string{"/", "/data"},
+		partitions: func(context.Context, bool) ([]disk.PartitionStat, error) {
+			return []disk.PartitionStat{
+				{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4"},
+				{Device: "/dev/sdb1", Mountpoint: "/data", Fstype: "xfs"},
+				{Device: "/dev/sdc1", Mountpoint: "/backup", Fstype: "xfs"},
+			}, nil
+		},
+		usage: func(_ context.Context, path string) (*disk.UsageStat, error) {
+			if path == "/data" {
+				return nil, errors.New("permission denied")
+			}
+			return &disk.UsageStat{Path: path, Total: 100, Used: 40, Free: 60, UsedPercent: 40}, nil
+		},
+	}
+
+	collected, err := c.Collect(context.Background())
+	assert.Error(t, err)
+	labels := model.Labels{"mountpoint": "/", "device": "/dev/sda1", "fstype": "ext4"}
+	assert.Equal(t, []model.MetricsV2{
+		Gauge("FilesystemTotal", labels, 100),
+		Gauge("FilesystemUsed", labels, 40),
+		Gauge("FilesystemFree", labels, 60),
+		Gauge("FilesystemUsedPercent", labels, 40),
+	}, collected)
+}
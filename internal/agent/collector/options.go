@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"metrics/internal/core/model"
+)
+
+// decodeOptions decodes collector options. Unknown options are errors to catch typos in the config.
+func decodeOptions(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	return nil
+}
+
+// totals converts monotonic totals to counter increments since the previous collection.
+// The first total of a series is the baseline, a decreased total means the source was reset,
+// so the total is the increment.
+type totals struct {
+	last map[string]uint64
+	seen map[string]bool
+}
+
+func newTotals() *totals {
+	return &totals{last: make(map[string]uint64), seen: make(map[string]bool)}
+}
+
+// counter appends the increment of the total to res.
+func (t *totals) counter(res []model.MetricsV2, name string, labels model.Labels, total uint64) []model.MetricsV2 {
+	key := model.MetricKey(name, labels)
+	last, ok := t.last[key]
+	t.last[key] = total
+	t.seen[key] = true
+	if !ok {
+		return res
+	}
+	delta := total - last
+	if total < last {
+		delta = total
+	}
+	return append(res, Counter(name, labels, int64(delta)))
+}
+
+// prune forgets series which were not reported since the previous prune, e.g. removed devices.
+func (t *totals) prune() {
+	for key := range t.last {
+		if !t.seen[key] {
+			delete(t.last, key)
+		}
+	}
+	clear(t.seen)
+}
@@ -0,0 +1,310 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"metrics/internal/core/model"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+func init() {
+	Register("process", newProcessCollector, false)
+}
+
+// processIdentity identifies the process instance, the pid could be reused by another process.
+type processIdentity struct {
+	Name    string
+	Cmdline string
+	Created int64 // unix milliseconds
+}
+
+type processUsage struct {
+	CPU     float64 // user and system CPU time in seconds
+	RSS     uint64
+	FDs     int32 // -1 if descriptors could not be read, e.g. of a process of another user
+	Threads int32
+}
+
+// processSource reads processes of the host.
+type processSource interface {
+	Pids(ctx context.Context) ([]int32, error)
+	Identity(ctx context.Context, pid int32) (*processIdentity, error)
+	Usage(ctx context.Context, pid int32) (*processUsage, error)
+}
+
+type processRule struct {
+	Name    string `json:"name"`    // value of the process label
+	Process string `json:"process"` // process name
+	Cmdline string `json:"cmdline"` // regexp of the command line
+	Pidfile string `json:"pidfile"`
+
+	cmdline *regexp.Regexp
+}
+
+type processOptions struct {
+	Processes []*processRule `json:"processes"`
+}
+
+// processInstance is the key of the matched process.
+type processInstance struct {
+	rule    string
+	pid     int32
+	created int64
+}
+
+type cpuSample struct {
+	cpu  float64
+	time time.Time
+}
+
+// processCollector reports usage of processes matched by rules, every process has process and pid labels:
+//   - ProcessCPUPercent, CPU utilization since the previous collection, 100 is a single core;
+//   - ProcessRSS, ProcessOpenFDs and ProcessThreads. ProcessOpenFDs is skipped if descriptors are not readable.
+//
+// Every rule reports ProcessCount gauge and ProcessRestarts counter of processes started after
+// the first collection, both have the process label only.
+type processCollector struct {
+	rules     []*processRule
+	source    processSource
+	now       func() time.Time
+	last      map[processInstance]cpuSample
+	collected bool
+}
+
+func newProcessCollector(options json.RawMessage) (Collector, error) {
+	var opts processOptions
+	if err := decodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	if len(opts.Processes) == 0 {
+		return nil, errors.New("no processes to watch")
+	}
+	for _, rule := range opts.Processes {
+		if err := rule.compile(); err != nil {
+			return nil, err
+		}
+	}
+	return &processCollector{
+		rules:  opts.Processes,
+		source: psutilProcesses{},
+		now:    time.Now,
+		last:   make(map[processInstance]cpuSample),
+	}, nil
+}
+
+func (r *processRule) compile() error {
+	if r.Name == "" {
+		return errors.New("process name is required")
+	}
+	matchers := 0
+	for _, m := range []string{r.Process, r.Cmdline, r.Pidfile} {
+		if m != "" {
+			matchers++
+		}
+	}
+	if matchers != 1 {
+		return fmt.Errorf("process %s: exactly one of process, cmdline or pidfile is required", r.Name)
+	}
+	if r.Cmdline != "" {
+		var err error
+		if r.cmdline, err = regexp.Compile(r.Cmdline); err != nil {
+			return fmt.Errorf("process %s: invalid cmdline: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+func (c *processCollector) Collect(ctx context.Context) ([]model.MetricsV2, error) {
+	var pids []int32
+	var err error
+	for _, rule := range c.rules {
+		if rule.Pidfile == "" {
+			if pids, err = c.source.Pids(ctx); err != nil {
+				return nil, fmt.Errorf("error listing processes: %w", err)
+			}
+			break
+		}
+	}
+
+	now := c.now()
+	current := make(map[processInstance]cpuSample, len(c.last))
+	identities := make(map[int32]*processIdentity) // процессы читаются один раз для всех правил
+	res := make([]model.MetricsV2, 0)
+	var errs []error
+	for _, rule := range c.rules {
+		matched, err := c.match(ctx, rule, pids, identities)
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		var restarts int64
+		for _, pid := range sortedPids(matched) {
+			instance := processInstance{rule: rule.Name, pid: pid, created: matched[pid].Created}
+			usage, err := c.source.Usage(ctx, pid)
+			if err != nil {
+				// процесс мог завершиться после поиска
+				delete(matched, pid)
+				continue
+			}
+			prev, ok := c.last[instance]
+			if !ok && c.collected {
+				restarts++
+			}
+			current[instance] = cpuSample{cpu: usage.CPU, time: now}
+
+			labels := model.Labels{"process": rule.Name, "pid": strconv.Itoa(int(pid))}
+			if elapsed := now.Sub(prev.time).Seconds(); ok && elapsed > 0 {
+				res = append(res, Gauge("ProcessCPUPercent", labels, (usage.CPU-prev.cpu)/elapsed*100))
+			}
+			res = append(res,
+				Gauge("ProcessRSS", labels, float64(usage.RSS)),
+				Gauge("ProcessThreads", labels, float64(usage.Threads)),
+			)
+			if usage.FDs >= 0 {
+				res = append(res, Gauge("ProcessOpenFDs", labels, float64(usage.FDs)))
+			}
+		}
+
+		labels := model.Labels{"process": rule.Name}
+		res = append(res,
+			Gauge("ProcessCount", labels, float64(len(matched))),
+			Counter("ProcessRestarts", labels, restarts),
+		)
+	}
+	c.last = current
+	c.collected = true
+	return res, errors.Join(errs...)
+}
+
+// match returns identities of processes matched by the rule. Identities are cached for the collection.
+func (c *processCollector) match(
+	ctx context.Context, rule *processRule, pids []int32, identities map[int32]*processIdentity,
+) (map[int32]*processIdentity, error) {
+	matched := make(map[int32]*processIdentity)
+	if rule.Pidfile != "" {
+		pid, err := readPidfile(rule.Pidfile)
+		if err != nil {
+			return matched, fmt.Errorf("process %s: %w", rule.Name, err)
+		}
+		if pid == 0 {
+			return matched, nil
+		}
+		if id := c.identity(ctx, pid, identities); id != nil {
+			matched[pid] = id
+		}
+		return matched, nil
+	}
+
+	for _, pid := range pids {
+		id := c.identity(ctx, pid, identities)
+		if id == nil {
+			continue
+		}
+		switch {
+		case rule.Process != "" && id.Name == rule.Process:
+			matched[pid] = id
+		case rule.cmdline != nil && rule.cmdline.MatchString(id.Cmdline):
+			matched[pid] = id
+		}
+	}
+	return matched, nil
+}
+
+// identity returns the cached identity of the process, nil if the process could not be read.
+func (c *processCollector) identity(
+	ctx context.Context, pid int32, identities map[int32]*processIdentity,
+) *processIdentity {
+	id, ok := identities[pid]
+	if !ok {
+		id, _ = c.source.Identity(ctx, pid)
+		identities[pid] = id
+	}
+	return id
+}
+
+// readPidfile returns the pid from the file. A missing file means the process is not running.
+func readPidfile(path string) (int32, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error reading pidfile: %w", err)
+	}
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pidfile %s: %w", path, err)
+	}
+	return int32(pid), nil
+}
+
+func sortedPids(m map[int32]*processIdentity) []int32 {
+	pids := make([]int32, 0, len(m))
+	for pid := range m {
+		pids = append(pids, pid)
+	}
+	slices.Sort(pids)
+	return pids
+}
+
+// psutilProcesses reads processes by gopsutil.
+type psutilProcesses struct{}
+
+func (psutilProcesses) Pids(ctx context.Context) ([]int32, error) {
+	return process.PidsWithContext(ctx)
+}
+
+func (psutilProcesses) Identity(ctx context.Context, pid int32) (*processIdentity, error) {
+	if pid <= 0 {
+		return nil, fmt.Errorf("invalid pid: %d", pid)
+	}
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+	id := &processIdentity{}
+	if id.Name, err = p.NameWithContext(ctx); err != nil {
+		return nil, err
+	}
+	if id.Cmdline, err = p.CmdlineWithContext(ctx); err != nil {
+		return nil, err
+	}
+	if id.Created, err = p.CreateTimeWithContext(ctx); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+func (psutilProcesses) Usage(ctx context.Context, pid int32) (*processUsage, error) {
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+	times, err := p.TimesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mem, err := p.MemoryInfoWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usage := &processUsage{CPU: times.User + times.System, RSS: mem.RSS}
+	if usage.FDs, err = p.NumFDsWithContext(ctx); err != nil {
+		// дескрипторы процесса другого пользователя недоступны без прав root
+		usage.FDs = -1
+	}
+	if usage.Threads, err = p.NumThreadsWithContext(ctx); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
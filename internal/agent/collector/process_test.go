@@ -0,0 +1,160 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProcess struct {
+	id    processIdentity
+	usage processUsage
+}
+
+type fakeProcesses map[int32]*fakeProcess
+
+// countingProcesses counts reads of process identities.
+type countingProcesses struct {
+	fakeProcesses
+	identities map[int32]int
+}
+
+func (c *countingProcesses) Identity(ctx context.Context, pid int32) (*processIdentity, error) {
+	c.identities[pid]++
+	return c.fakeProcesses.Identity(ctx, pid)
+}
+
+func (f fakeProcesses) Pids(context.Context) ([]int32, error) {
+	pids := make([]int32, 0, len(f))
+	for pid := range f {
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+func (f fakeProcesses) Identity(_ context.Context, pid int32) (*processIdentity, error) {
+	p, ok := f[pid]
+	if !ok {
+		return nil, errors.New("process not found")
+	}
+	return &p.id, nil
+}
+
+func (f fakeProcesses) Usage(_ context.Context, pid int32) (*processUsage, error) {
+	p, ok := f[pid]
+	if !ok {
+		return nil, errors.New("process not found")
+	}
+	return &p.usage, nil
+}
+
+func TestProcessOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		wantErr bool
+	}{
+		{name: "by name", options: `{"processes":[{"name":"nginx","process":"nginx"}]}`},
+		{name: "by cmdline", options: `{"processes":[{"name":"worker","cmdline":"python .*worker\\.py"}]}`},
+		{name: "by pidfile", options: `{"processes":[{"name":"pg","pidfile":"/run/postgresql.pid"}]}`},
+		{name: "no processes", options: `{}`, wantErr: true},
+		{name: "no name", options: `{"processes":[{"process":"nginx"}]}`, wantErr: true},
+		{name: "no matcher", options: `{"processes":[{"name":"nginx"}]}`, wantErr: true},
+		{
+			name:    "two matchers",
+			options: `{"processes":[{"name":"nginx","process":"nginx","pidfile":"/run/nginx.pid"}]}`,
+			wantErr: true,
+		},
+		{name: "invalid regexp", options: `{"processes":[{"name":"worker","cmdline":"("}]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newProcessCollector(json.RawMessage(tt.options))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestProcessCollector(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "db.pid")
+	require.NoError(t, os.WriteFile(pidfile, []byte("30\n"), 0o644))
+
+	options := `{"processes":[
+		{"name":"nginx","process":"nginx"},
+		{"name":"worker","cmdline":"worker\\.py"},
+		{"name":"db","pidfile":"` + pidfile + `"}
+	]}`
+	c, err := newProcessCollector(json.RawMessage(options))
+	require.NoError(t, err)
+	processes := c.(*processCollector)
+
+	source := fakeProcesses{
+		10: {id: processIdentity{Name: "nginx", Created: 1}, usage: processUsage{CPU: 1, RSS: 1024, FDs: 10, Threads: 2}},
+		20: {
+			id:    processIdentity{Name: "python", Cmdline: "python worker.py", Created: 1},
+			usage: processUsage{CPU: 5, FDs: -1}, // процесс другого пользователя
+		},
+		30: {id: processIdentity{Name: "postgres", Created: 1}, usage: processUsage{CPU: 2}},
+		40: {id: processIdentity{Name: "bash", Cmdline: "bash"}},
+	}
+	now := time.Now()
+	counting := &countingProcesses{fakeProcesses: source, identities: make(map[int32]int)}
+	processes.source = counting
+	processes.now = func() time.Time { return now }
+
+	res := collect(t, c)
+	// процессы читаются один раз для всех правил
+	assert.Equal(t, map[int32]int{10: 1, 20: 1, 30: 1, 40: 1}, counting.identities)
+	assert.Contains(t, res, `ProcessRSS{pid="20",process="worker"}`)
+	assert.NotContains(t, res, `ProcessOpenFDs{pid="20",process="worker"}`)
+	assert.NotContains(t, res, `ProcessCPUPercent{pid="10",process="nginx"}`)
+	assert.Equal(t, 1024.0, *res[`ProcessRSS{pid="10",process="nginx"}`].Value)
+	assert.Equal(t, 10.0, *res[`ProcessOpenFDs{pid="10",process="nginx"}`].Value)
+	assert.Equal(t, 2.0, *res[`ProcessThreads{pid="10",process="nginx"}`].Value)
+	assert.Equal(t, 1.0, *res[`ProcessCount{process="worker"}`].Value)
+	assert.Equal(t, 1.0, *res[`ProcessCount{process="db"}`].Value)
+	assert.Equal(t, int64(0), *res[`ProcessRestarts{process="nginx"}`].Delta)
+
+	// nginx потребляет половину ядра, worker перезапущен с новым pid, db остановлен
+	now = now.Add(10 * time.Second)
+	source[10].usage.CPU = 6
+	delete(source, 20)
+	source[21] = &fakeProcess{id: processIdentity{Name: "python", Cmdline: "python worker.py", Created: 2}}
+	require.NoError(t, os.Remove(pidfile))
+
+	res = collect(t, c)
+	assert.InDelta(t, 50.0, *res[`ProcessCPUPercent{pid="10",process="nginx"}`].Value, 1e-9)
+	assert.Equal(t, int64(0), *res[`ProcessRestarts{process="nginx"}`].Delta)
+	assert.Contains(t, res, `ProcessRSS{pid="21",process="worker"}`)
+	assert.NotContains(t, res, `ProcessRSS{pid="20",process="worker"}`)
+	assert.Equal(t, int64(1), *res[`ProcessRestarts{process="worker"}`].Delta)
+	assert.Equal(t, 0.0, *res[`ProcessCount{process="db"}`].Value)
+}
+
+func TestProcessCollectorSelf(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "self.pid")
+	require.NoError(t, os.WriteFile(pidfile, []byte(fmt.Sprint(os.Getpid())), 0o644))
+
+	c, err := newProcessCollector(json.RawMessage(`{"processes":[{"name":"self","pidfile":"` + pidfile + `"}]}`))
+	require.NoError(t, err)
+	res := collect(t, c)
+
+	assert.Equal(t, 1.0, *res[`ProcessCount{process="self"}`].Value)
+	for key, m := range res {
+		if m.ID == "ProcessRSS" {
+			assert.Positive(t, *m.Value, key)
+		}
+	}
+}
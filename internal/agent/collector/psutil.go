@@ -0,0 +1,55 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"metrics/internal/core/model"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+func init() {
+	Register("memory", newMemoryCollector, true)
+	Register("cpu", newCPUCollector, true)
+}
+
+// memoryCollector reports total and free virtual memory.
+type memoryCollector struct{}
+
+func newMemoryCollector(json.RawMessage) (Collector, error) {
+	return &memoryCollector{}, nil
+}
+
+func (c *memoryCollector) Collect(ctx context.Context) ([]model.MetricsV2, error) {
+	v, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading virtual memory: %w", err)
+	}
+	return []model.MetricsV2{
+		Gauge("TotalMemory", nil, float64(v.Total)),
+		Gauge("FreeMemory", nil, float64(v.Free)),
+	}, nil
+}
+
+// cpuCollector reports utilization of every CPU measured for a second.
+type cpuCollector struct{}
+
+func newCPUCollector(json.RawMessage) (Collector, error) {
+	return &cpuCollector{}, nil
+}
+
+func (c *cpuCollector) Collect(ctx context.Context) ([]model.MetricsV2, error) {
+	cpus, err := cpu.PercentWithContext(ctx, time.Second, true)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cpu utilization: %w", err)
+	}
+	res := make([]model.MetricsV2, 0, len(cpus))
+	for i, utilization := range cpus {
+		res = append(res, Gauge(fmt.Sprintf("CPUutilization%d", i+1), nil, utilization))
+	}
+	return res, nil
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"runtime"
+	"time"
+
+	"metrics/internal/core/model"
+)
+
+func init() {
+	Register("runtime", newRuntimeCollector, true)
+}
+
+// runtimeCollector reports Go runtime memory statistics, a random value and the amount of polls.
+type runtimeCollector struct {
+	rnd *rand.Rand
+}
+
+func newRuntimeCollector(json.RawMessage) (Collector, error) {
+	return &runtimeCollector{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}, nil
+}
+
+func (c *runtimeCollector) Collect(_ context.Context) ([]model.MetricsV2, error) {
+	var rtm runtime.MemStats
+	runtime.ReadMemStats(&rtm)
+
+	return []model.MetricsV2{
+		Counter("PollCount", nil, 1),
+		Gauge("RandomValue", nil, c.rnd.Float64()),
+		Gauge("Alloc", nil, float64(rtm.Alloc)),
+		Gauge("BuckHashSys", nil, float64(rtm.BuckHashSys)),
+		Gauge("Frees", nil, float64(rtm.Frees)),
+		Gauge("GCCPUFraction", nil, rtm.GCCPUFraction),
+		Gauge("GCSys", nil, float64(rtm.GCSys)),
+		Gauge("HeapAlloc", nil, float64(rtm.HeapAlloc)),
+		Gauge("HeapIdle", nil, float64(rtm.HeapIdle)),
+		Gauge("HeapInuse", nil, float64(rtm.HeapInuse)),
+		Gauge("HeapObjects", nil, float64(rtm.HeapObjects)),
+		Gauge("HeapReleased", nil, float64(rtm.HeapReleased)),
+		Gauge("HeapSys", nil, float64(rtm.HeapSys)),
+		Gauge("LastGC", nil, float64(rtm.LastGC)),
+		Gauge("Lookups", nil, float64(rtm.Lookups)),
+		Gauge("MCacheInuse", nil, float64(rtm.MCacheInuse)),
+		Gauge("MCacheSys", nil, float64(rtm.MCacheSys)),
+		Gauge("MSpanInuse", nil, float64(rtm.MSpanInuse)),
+		Gauge("MSpanSys", nil, float64(rtm.MSpanSys)),
+		Gauge("Mallocs", nil, float64(rtm.Mallocs)),
+		Gauge("NextGC", nil, float64(rtm.NextGC)),
+		Gauge("NumForcedGC", nil, float64(rtm.NumForcedGC)),
+		Gauge("NumGC", nil, float64(rtm.NumGC)),
+		Gauge("OtherSys", nil, float64(rtm.OtherSys)),
+		Gauge("PauseTotalNs", nil, float64(rtm.PauseTotalNs)),
+		Gauge("StackInuse", nil, float64(rtm.StackInuse)),
+		Gauge("StackSys", nil, float64(rtm.StackSys)),
+		Gauge("Sys", nil, float64(rtm.Sys)),
+		Gauge("TotalAlloc", nil, float64(rtm.TotalAlloc)),
+	}, nil
+}
@@ -0,0 +1,233 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"metrics/internal/core/model"
+)
+
+func init() {
+	Register("scrape", newScrapeCollector, false)
+}
+
+const (
+	scrapePrometheus = "prometheus"
+	scrapeExpvar     = "expvar"
+
+	defaultScrapeTimeout = 5 * time.Second
+	maxScrapeSize        = 16 << 20
+)
+
+// relabelRule renames metrics with names matched by the regexp, $1 in the replacement is the first group.
+// Matched metrics are dropped if drop is set.
+type relabelRule struct {
+	Match   string `json:"match"`
+	Replace string `json:"replace"`
+	Drop    bool   `json:"drop"`
+
+	match *regexp.Regexp
+}
+
+type scrapeTarget struct {
+	URL     string         `json:"url"`
+	Format  string         `json:"format"`  // prometheus or expvar, expvar for /debug/vars by default
+	Prefix  string         `json:"prefix"`  // added to names after relabeling
+	Labels  model.Labels   `json:"labels"`  // added to every metric
+	Relabel []*relabelRule `json:"relabel"` // applied one by one to the scraped names
+	Timeout int64          `json:"timeout"` // seconds, 5 by default
+
+	exposition *exposition
+}
+
+type scrapeOptions struct {
+	Targets []*scrapeTarget `json:"targets"`
+}
+
+// scrapeCollector reads metrics of services exposed in the Prometheus text format, e.g. /metrics,
+// or by expvar, e.g. /debug/vars. Prometheus counters are reported as increments, histograms and summaries
+// are skipped. Numbers of expvar are gauges named by the path in the JSON: memstats.HeapAlloc.
+// Targets are scraped one by one, metrics of the failed target are skipped.
+type scrapeCollector struct {
+	targets []*scrapeTarget
+	client  *http.Client
+}
+
+func newScrapeCollector(options json.RawMessage) (Collector, error) {
+	var opts scrapeOptions
+	if err := decodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	if len(opts.Targets) == 0 {
+		return nil, errors.New("no targets to scrape")
+	}
+	for _, target := range opts.Targets {
+		if err := target.compile(); err != nil {
+			return nil, err
+		}
+	}
+	return &scrapeCollector{targets: opts.Targets, client: &http.Client{}}, nil
+}
+
+func (t *scrapeTarget) compile() error {
+	if t.URL == "" {
+		return errors.New("target url is required")
+	}
+	switch t.Format {
+	case "":
+		t.Format = scrapePrometheus
+		if strings.HasSuffix(t.URL, "/debug/vars") {
+			t.Format = scrapeExpvar
+		}
+	case scrapePrometheus, scrapeExpvar:
+	default:
+		return fmt.Errorf("target %s: unknown format %s", t.URL, t.Format)
+	}
+	if t.Timeout < 0 {
+		return fmt.Errorf("target %s: timeout could not be negative", t.URL)
+	}
+	for _, rule := range t.Relabel {
+		if rule.Match == "" || rule.Replace == "" && !rule.Drop {
+			return fmt.Errorf("target %s: relabel requires match and either replace or drop", t.URL)
+		}
+		var err error
+		if rule.match, err = regexp.Compile(rule.Match); err != nil {
+			return fmt.Errorf("target %s: invalid relabel match: %w", t.URL, err)
+		}
+	}
+	t.exposition = newExposition()
+	return nil
+}
+
+func (c *scrapeCollector) Collect(ctx context.Context) ([]model.MetricsV2, error) {
+	res := make([]model.MetricsV2, 0)
+	var errs []error
+	for _, target := range c.targets {
+		var err error
+		if res, err = c.scrape(ctx, target, res); err != nil {
+			errs = append(errs, fmt.Errorf("target %s: %w", target.URL, err))
+		}
+	}
+	return res, errors.Join(errs...)
+}
+
+func (c *scrapeCollector) scrape(
+	ctx context.Context, target *scrapeTarget, res []model.MetricsV2,
+) ([]model.MetricsV2, error) {
+	data, err := c.fetch(ctx, target)
+	if err != nil {
+		return res, err
+	}
+
+	var scraped []model.MetricsV2
+	if target.Format == scrapeExpvar {
+		scraped, err = parseExpvar(data)
+	} else {
+		scraped, err = target.exposition.parseText(nil, data)
+		target.exposition.totals.prune()
+	}
+	for _, m := range scraped {
+		if m, ok := target.relabel(m); ok {
+			res = append(res, m)
+		}
+	}
+	return res, err
+}
+
+func (c *scrapeCollector) fetch(ctx context.Context, target *scrapeTarget) ([]byte, error) {
+	timeout := defaultScrapeTimeout
+	if target.Timeout > 0 {
+		timeout = time.Duration(target.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.URL, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	if target.Format == scrapePrometheus {
+		req.Header.Set("Accept", "text/plain;version=0.0.4")
+	} else {
+		req.Header.Set("Accept", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxScrapeSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	if len(data) > maxScrapeSize {
+		return nil, fmt.Errorf("response is larger than %d bytes", maxScrapeSize)
+	}
+	return data, nil
+}
+
+// relabel renames the metric and adds the target labels, returns false if the metric is dropped.
+func (t *scrapeTarget) relabel(m model.MetricsV2) (model.MetricsV2, bool) {
+	for _, rule := range t.Relabel {
+		if !rule.match.MatchString(m.ID) {
+			continue
+		}
+		if rule.Drop {
+			return m, false
+		}
+		m.ID = rule.match.ReplaceAllString(m.ID, rule.Replace)
+	}
+	m.ID = t.Prefix + m.ID
+
+	if len(t.Labels) > 0 {
+		labels := m.Labels.Copy()
+		if labels == nil {
+			labels = model.Labels{}
+		}
+		for name, value := range t.Labels {
+			labels[name] = value
+		}
+		m.Labels = labels
+	}
+	return m, true
+}
+
+// parseExpvar returns numbers of the expvar JSON as gauges, values of nested objects are named by the path.
+// Strings, booleans and arrays are skipped.
+func parseExpvar(data []byte) ([]model.MetricsV2, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var vars map[string]any
+	if err := dec.Decode(&vars); err != nil {
+		return nil, fmt.Errorf("invalid expvar JSON: %w", err)
+	}
+
+	res := make([]model.MetricsV2, 0, len(vars))
+	var walk func(prefix string, vars map[string]any)
+	walk = func(prefix string, vars map[string]any) {
+		for _, key := range sortedKeys(vars) {
+			switch v := vars[key].(type) {
+			case json.Number:
+				if value, err := v.Float64(); err == nil {
+					res = append(res, Gauge(prefix+key, nil, value))
+				}
+			case map[string]any:
+				walk(prefix+key+".", v)
+			}
+		}
+	}
+	walk("", vars)
+	return res, nil
+}
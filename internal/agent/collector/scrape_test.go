@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScrapeCollector(t *testing.T) {
+	requests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Contains(t, r.Header.Get("Accept"), "text/plain")
+		w.Write([]byte("# TYPE http_requests_total counter\n"))
+		if requests == 1 {
+			w.Write([]byte("http_requests_total{code=\"200\"} 10\n"))
+		} else {
+			w.Write([]byte("http_requests_total{code=\"200\"} 14\n"))
+		}
+		w.Write([]byte("go_goroutines 8\ngo_threads 5\n"))
+	})
+	mux.HandleFunc("/debug/vars", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"cmdline":["app"],"memstats":{"HeapAlloc":1024,"PauseNs":[1,2]},"requests":7,"ready":true}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	options, err := json.Marshal(scrapeOptions{Targets: []*scrapeTarget{
+		{
+			URL:    srv.URL + "/metrics",
+			Prefix: "app_",
+			Labels: map[string]string{"service": "app"},
+			Relabel: []*relabelRule{
+				{Match: "^go_threads$", Drop: true},
+				{Match: "^go_(.*)$", Replace: "runtime_$1"},
+			},
+		},
+		{URL: srv.URL + "/debug/vars"},
+	}})
+	require.NoError(t, err)
+	c, err := newScrapeCollector(options)
+	require.NoError(t, err)
+
+	res := collect(t, c)
+	assert.Len(t, res, 3)
+	assert.Equal(t, 8.0, *res[`app_runtime_goroutines{service="app"}`].Value)
+	assert.Equal(t, 1024.0, *res["memstats.HeapAlloc"].Value)
+	assert.Equal(t, 7.0, *res["requests"].Value)
+
+	res = collect(t, c)
+	assert.Len(t, res, 4)
+	assert.Equal(t, int64(4), *res[`app_http_requests_total{code="200",service="app"}`].Delta)
+}
+
+func TestScrapeCollectorFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("up 1\n"))
+	}))
+	defer srv.Close()
+
+	options, err := json.Marshal(scrapeOptions{Targets: []*scrapeTarget{
+		{URL: srv.URL + "/missing"},
+		{URL: srv.URL + "/vars", Format: "expvar"},
+		{URL: srv.URL + "/metrics"},
+	}})
+	require.NoError(t, err)
+	c, err := newScrapeCollector(options)
+	require.NoError(t, err)
+
+	res, err := c.Collect(context.Background())
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "/missing: unexpected status: 404 Not Found")
+	assert.ErrorContains(t, err, "/vars: invalid expvar JSON")
+	require.Len(t, res, 1)
+	assert.Equal(t, "up", res[0].ID)
+}
+
+func TestScrapeCollectorOptions(t *testing.T) {
+	for _, options := range []string{
+		``,
+		`{"targets":[]}`,
+		`{"targets":[{"format":"expvar"}]}`,
+		`{"targets":[{"url":"http://localhost/metrics","format":"json"}]}`,
+		`{"targets":[{"url":"http://localhost/metrics","timeout":-1}]}`,
+		`{"targets":[{"url":"http://localhost/metrics","relabel":[{"match":"("}]}]}`,
+		`{"targets":[{"url":"http://localhost/metrics","relabel":[{"match":"^go_"}]}]}`,
+	} {
+		_, err := newScrapeCollector(json.RawMessage(options))
+		assert.Error(t, err, options)
+	}
+}
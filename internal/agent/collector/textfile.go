@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"metrics/internal/core/model"
+)
+
+func init() {
+	Register("textfile", newTextfileCollector, false)
+}
+
+type textfileOptions struct {
+	Directory string `json:"directory"`
+}
+
+// textfileCollector reads metrics written by cron jobs and scripts to the directory:
+// *.prom files in the Prometheus text format and *.json files with the JSON array of metrics.
+// Files are read on every collection, so they should be written to a temporary name and renamed.
+type textfileCollector struct {
+	dir        string
+	exposition *exposition
+}
+
+func newTextfileCollector(options json.RawMessage) (Collector, error) {
+	var opts textfileOptions
+	if err := decodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	if opts.Directory == "" {
+		return nil, errors.New("directory is required")
+	}
+	return &textfileCollector{dir: opts.Directory, exposition: newExposition()}, nil
+}
+
+func (c *textfileCollector) Collect(_ context.Context) ([]model.MetricsV2, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading metrics directory: %w", err)
+	}
+
+	res := make([]model.MetricsV2, 0)
+	var errs []error
+	for _, entry := range entries {
+		parse := c.exposition.parseText
+		switch filepath.Ext(entry.Name()) {
+		case ".prom":
+		case ".json":
+			parse = c.exposition.parseJSON
+		default:
+			continue
+		}
+		if entry.IsDir() {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(c.dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error reading metrics file: %w", err))
+			continue
+		}
+		if res, err = parse(res, data); err != nil {
+			errs = append(errs, fmt.Errorf("file %s: %w", entry.Name(), err))
+		}
+	}
+	c.exposition.totals.prune()
+	return res, errors.Join(errs...)
+}
@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextfileCollector(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644))
+	}
+	write("backup.prom", "# TYPE backup_runs_total counter\nbackup_runs_total{target=\"db\"} 10\nbackup_size_bytes 2048\n")
+	write("jobs.json", `[{"id":"Jobs","type":"counter","value":5},{"id":"QueueLength","type":"gauge","value":3}]`)
+	write("backup.prom.tmp", "half_written 1")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "old.prom"), 0o755))
+
+	c, err := newTextfileCollector(json.RawMessage(`{"directory":"` + dir + `"}`))
+	require.NoError(t, err)
+
+	// первые значения счетчиков - точка отсчета
+	res := collect(t, c)
+	assert.Len(t, res, 2)
+	assert.Equal(t, 2048.0, *res["backup_size_bytes"].Value)
+	assert.Equal(t, 3.0, *res["QueueLength"].Value)
+
+	write("backup.prom", "# TYPE backup_runs_total counter\nbackup_runs_total{target=\"db\"} 12\nbackup_size_bytes 4096\n")
+	write("jobs.json", `[{"id":"Jobs","type":"counter","value":8},{"id":"QueueLength","type":"gauge","value":1}]`)
+	res = collect(t, c)
+	assert.Len(t, res, 4)
+	assert.Equal(t, int64(2), *res[`backup_runs_total{target="db"}`].Delta)
+	assert.Equal(t, 4096.0, *res["backup_size_bytes"].Value)
+	assert.Equal(t, int64(3), *res["Jobs"].Delta)
+	assert.Equal(t, 1.0, *res["QueueLength"].Value)
+
+	// ошибки в файле не мешают остальным метрикам
+	write("jobs.json", `[{"id":"Jobs","type":"counter"},{"id":"QueueLength","type":"gauge","value":0}]`)
+	collected, err := c.Collect(context.Background())
+	assert.ErrorContains(t, err, "file jobs.json: counter Jobs has no valid value")
+	assert.Len(t, collected, 3)
+}
+
+func TestTextfileCollectorOptions(t *testing.T) {
+	_, err := newTextfileCollector(nil)
+	assert.Error(t, err)
+
+	c, err := newTextfileCollector(json.RawMessage(`{"directory":"` + filepath.Join(t.TempDir(), "missing") + `"}`))
+	require.NoError(t, err)
+	_, err = c.Collect(context.Background())
+	assert.Error(t, err)
+}
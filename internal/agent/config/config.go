@@ -11,24 +11,56 @@ import (
 	"github.com/pkg/errors"
 )
 
+// Transports to send metrics to the server.
+const (
+	TransportHTTP = "http"
+	TransportGRPC = "grpc"
+)
+
 type AgentConfig struct {
 	ServerAddresPort string `env:"ADDRESS" envDefault:"localhost:8080"`
+	GRPCAddress      string `env:"GRPC_ADDRESS" envDefault:"localhost:3200"`
+	Transport        string `env:"TRANSPORT" envDefault:"http"`
 	LogLevel         string `env:"LOG_LEVEL" envDefault:"info"`
 	HashKey          string `env:"KEY"`
 	CryptoKey        string `env:"CRYPTO_KEY"`
 	ReportInterval   int64  `env:"REPORT_INTERVAL" envDefault:"10"`
 	PollInterval     int64  `env:"POLL_INTERVAL" envDefault:"2"`
 	RateLimit        int    `env:"RATE_LIMIT" envDefault:"3"`
+
+	// Disk queue of batches, disabled if the directory is empty
+	QueueDir     string `env:"QUEUE_DIR"`
+	QueueMaxSize int64  `env:"QUEUE_MAX_SIZE" envDefault:"67108864"` // bytes
+	QueueMaxAge  int64  `env:"QUEUE_MAX_AGE" envDefault:"86400"`     // seconds
+	QueueSync    string `env:"QUEUE_SYNC" envDefault:"segment"`      // always, segment or never
+
+	// Collectors by names, collectors which are not listed use defaults. Set by JSON config only
+	Collectors map[string]CollectorConfig
+}
+
+// CollectorConfig configures the collector of metrics.
+type CollectorConfig struct {
+	Enabled  *bool           `json:"enabled,omitempty"`  // default depends on the collector
+	Interval int64           `json:"interval,omitempty"` // seconds, PollInterval by default
+	Options  json.RawMessage `json:"options,omitempty"`  // collector specific options
 }
 
 type JSONConfig struct {
 	ServerAddresPort *string `json:"address,omitempty"`
+	GRPCAddress      *string `json:"grpc_address,omitempty"`
+	Transport        *string `json:"transport,omitempty"`
 	LogLevel         *string `json:"log_level,omitempty"`
 	HashKey          *string `json:"key,omitempty"`
 	CryptoKey        *string `json:"crypto_key"`
 	ReportInterval   *int64  `json:"report_interval"`
 	PollInterval     *int64  `json:"poll_interval"`
 	RateLimit        *int    `json:"rate_limit"`
+	QueueDir         *string `json:"queue_dir,omitempty"`
+	QueueMaxSize     *int64  `json:"queue_max_size,omitempty"`
+	QueueMaxAge      *int64  `json:"queue_max_age,omitempty"`
+	QueueSync        *string `json:"queue_sync,omitempty"`
+
+	Collectors map[string]CollectorConfig `json:"collectors,omitempty"`
 }
 
 func loadJSONConfig(path string) (cfg *JSONConfig, err error) {
@@ -49,20 +81,32 @@ func NewAgentConfig() (*AgentConfig, error) {
 	// Read commant args to serparate variables
 	var jsonCfgPath, jsonCfgPathFull string
 	var flagRunAddr string
+	var flagGRPCAddr string
+	var flagTransport string
 	var flagLogLevel string
 	var flagHashKey string
 	var flagCryptoKey string
 	var flagReportInterval int64
 	var flagPollInterval int64
 	var flagRateLimit int
+	var flagQueueDir string
+	var flagQueueMaxSize int64
+	var flagQueueMaxAge int64
+	var flagQueueSync string
 
 	flag.StringVar(&flagRunAddr, "a", "localhost:8080", "server addres and port to send metrics")
+	flag.StringVar(&flagGRPCAddr, "grpc-address", "", "server gRPC addres and port to send metrics")
+	flag.StringVar(&flagTransport, "transport", "", "transport to send metrics: http or grpc")
 	flag.Int64Var(&flagReportInterval, "r", 10, "sent metric to server every given interval")
 	flag.Int64Var(&flagPollInterval, "p", 2, "gather metric every given interval")
 	flag.StringVar(&flagLogLevel, "v", "info", "Log levle: debug, info, warn, error, panic, fatal")
 	flag.StringVar(&flagHashKey, "k", "", "Hash key to sign requests")
 	flag.StringVar(&flagCryptoKey, "crypto-key", "", "Path to private key")
 	flag.IntVar(&flagRateLimit, "l", 3, "Amount of parallel requests to server")
+	flag.StringVar(&flagQueueDir, "queue-dir", "", "Disk queue directory, the queue is disabled if empty")
+	flag.Int64Var(&flagQueueMaxSize, "queue-max-size", 0, "Max size of the disk queue in bytes")
+	flag.Int64Var(&flagQueueMaxAge, "queue-max-age", 0, "Max age of batches in the disk queue in seconds")
+	flag.StringVar(&flagQueueSync, "queue-sync", "", "Disk queue fsync policy: always, segment or never")
 	flag.StringVar(&jsonCfgPath, "с", "", "json configuration file")
 	flag.StringVar(&jsonCfgPathFull, "config", "", "json configuration file")
 	flag.Parse()
@@ -95,6 +139,23 @@ func NewAgentConfig() (*AgentConfig, error) {
 		cfg.ServerAddresPort = "http://" + cfg.ServerAddresPort
 	}
 
+	// GRPC_ADDRESS
+	if _, ok := os.LookupEnv("GRPC_ADDRESS"); !ok && flagGRPCAddr != "" {
+		cfg.GRPCAddress = flagGRPCAddr
+	} else if jsonCfg != nil && jsonCfg.GRPCAddress != nil {
+		cfg.GRPCAddress = *jsonCfg.GRPCAddress
+	}
+
+	// TRANSPORT
+	if _, ok := os.LookupEnv("TRANSPORT"); !ok && flagTransport != "" {
+		cfg.Transport = flagTransport
+	} else if jsonCfg != nil && jsonCfg.Transport != nil {
+		cfg.Transport = *jsonCfg.Transport
+	}
+	if cfg.Transport != TransportHTTP && cfg.Transport != TransportGRPC {
+		return nil, fmt.Errorf("unknown transport: %s", cfg.Transport)
+	}
+
 	// REPORT_INTERVAL
 	if _, ok := os.LookupEnv("REPORT_INTERVAL"); !ok && flagReportInterval > 0 {
 		cfg.ReportInterval = flagReportInterval
@@ -137,5 +198,38 @@ func NewAgentConfig() (*AgentConfig, error) {
 		cfg.RateLimit = *jsonCfg.RateLimit
 	}
 
+	// QUEUE_DIR
+	if _, ok := os.LookupEnv("QUEUE_DIR"); !ok && flagQueueDir != "" {
+		cfg.QueueDir = flagQueueDir
+	} else if jsonCfg != nil && jsonCfg.QueueDir != nil {
+		cfg.QueueDir = *jsonCfg.QueueDir
+	}
+
+	// QUEUE_MAX_SIZE
+	if _, ok := os.LookupEnv("QUEUE_MAX_SIZE"); !ok && flagQueueMaxSize > 0 {
+		cfg.QueueMaxSize = flagQueueMaxSize
+	} else if jsonCfg != nil && jsonCfg.QueueMaxSize != nil {
+		cfg.QueueMaxSize = *jsonCfg.QueueMaxSize
+	}
+
+	// QUEUE_MAX_AGE
+	if _, ok := os.LookupEnv("QUEUE_MAX_AGE"); !ok && flagQueueMaxAge > 0 {
+		cfg.QueueMaxAge = flagQueueMaxAge
+	} else if jsonCfg != nil && jsonCfg.QueueMaxAge != nil {
+		cfg.QueueMaxAge = *jsonCfg.QueueMaxAge
+	}
+
+	// QUEUE_SYNC
+	if _, ok := os.LookupEnv("QUEUE_SYNC"); !ok && flagQueueSync != "" {
+		cfg.QueueSync = flagQueueSync
+	} else if jsonCfg != nil && jsonCfg.QueueSync != nil {
+		cfg.QueueSync = *jsonCfg.QueueSync
+	}
+
+	// Collectors are configured by JSON only
+	if jsonCfg != nil {
+		cfg.Collectors = jsonCfg.Collectors
+	}
+
 	return &cfg, nil
 }
@@ -5,34 +5,104 @@ import (
 )
 
 type Transporter interface {
-	SendMetric(req []model.MetricsV2) error
+	// SendMetric sends the batch. Retries of the same batch must use the same idempotency key,
+	// so the server applies the batch once.
+	SendMetric(key string, req []model.MetricsV2) error
 }
 
+// Metric is a metric collected by the agent. Counter deltas are removed only after the server
+// acknowledges them, so a failed send does not lose increments.
 type Metric interface {
 	Payload() model.MetricsV2
 	Type() model.MetricType
-	WasSent()
+	// Reserve returns the payload to be sent. The reserved delta is excluded from next payloads
+	// until it is acknowledged or released.
+	Reserve() model.MetricsV2
+	// Ack removes the delivered delta of the reserved payload.
+	Ack(p model.MetricsV2)
+	// Release returns the delta of the undelivered payload, it is sent with the next one.
+	Release(p model.MetricsV2)
 }
 
 type Gauge struct {
 	model.Gauge
 }
 
-func (g *Gauge) WasSent() {}
-
 func (g *Gauge) Payload() model.MetricsV2 {
-	return model.MetricsV2{ID: g.Name, MType: g.Type(), Value: &g.Value}
+	return model.MetricsV2{ID: g.Name, MType: g.Type(), Labels: g.Labels, Value: &g.Value}
+}
+
+func (g *Gauge) Reserve() model.MetricsV2 {
+	value := g.Value // значение фиксируется на момент формирования батча
+	return model.MetricsV2{ID: g.Name, MType: g.Type(), Labels: g.Labels, Value: &value}
 }
 
+func (g *Gauge) Ack(model.MetricsV2) {}
+
+func (g *Gauge) Release(model.MetricsV2) {}
+
 type Counter struct {
 	model.Counter
+	reserved int64 // delta of batches being sent
 }
 
+// Payload returns the delta which is not sent yet.
 func (c *Counter) Payload() model.MetricsV2 {
-	value := c.Value // Create new variable due to prevent of erasing by WasSent
-	return model.MetricsV2{ID: c.Name, MType: c.Type(), Delta: &value}
+	value := c.Value - c.reserved
+	return model.MetricsV2{ID: c.Name, MType: c.Type(), Labels: c.Labels, Delta: &value}
+}
+
+func (c *Counter) Reserve() model.MetricsV2 {
+	p := c.Payload()
+	c.reserved += *p.Delta
+	return p
+}
+
+func (c *Counter) Ack(p model.MetricsV2) {
+	c.Value -= *p.Delta
+	c.reserved -= *p.Delta
+}
+
+func (c *Counter) Release(p model.MetricsV2) {
+	c.reserved -= *p.Delta
+}
+
+// Batch is the payload of metrics sent by a single request.
+// Methods of the batch and metrics should be called under the same lock, see Set.
+type Batch struct {
+	Key     string // idempotency key, it is kept while the batch is resent
+	Data    []model.MetricsV2
+	metrics []Metric
+}
+
+// NewBatch reserves payloads of the metrics.
+func NewBatch(metrics []Metric) *Batch {
+	b := &Batch{Data: make([]model.MetricsV2, 0, len(metrics)), metrics: metrics}
+	for _, m := range metrics {
+		b.Data = append(b.Data, m.Reserve())
+	}
+	return b
+}
+
+// refresh updates gauge values of the batch which is sent again.
+func (b *Batch) refresh() {
+	for i, m := range b.metrics {
+		if m.Type() == model.GaugeType {
+			b.Data[i] = m.Reserve()
+		}
+	}
+}
+
+// Ack is called after the batch is delivered.
+func (b *Batch) Ack() {
+	for i, m := range b.metrics {
+		m.Ack(b.Data[i])
+	}
 }
 
-func (c *Counter) WasSent() {
-	c.Value = 0
+// Release is called if the batch is not delivered, its counter deltas are merged into the next batch.
+func (b *Batch) Release() {
+	for i, m := range b.metrics {
+		m.Release(b.Data[i])
+	}
 }
@@ -51,7 +51,7 @@ func TestPayload(t *testing.T) {
 		// counters
 		{
 			metric: &Counter{
-				model.Counter{
+				Counter: model.Counter{
 					Name:  "counter_test_01",
 					Value: delta10,
 				},
@@ -64,7 +64,7 @@ func TestPayload(t *testing.T) {
 		},
 		{
 			metric: &Counter{
-				model.Counter{
+				Counter: model.Counter{
 					Name:  "counter_test_02",
 					Value: delta101,
 				},
@@ -84,81 +84,38 @@ func TestPayload(t *testing.T) {
 	}
 }
 
-func TestWasSent(t *testing.T) {
-	var delta0 int64 = 0
-	var delta10 int64 = 10
-	var delta101 int64 = 101
+func TestCounterDelivery(t *testing.T) {
+	counter := &Counter{Counter: model.Counter{Name: "PollCount", Value: 5}}
 
-	value10 := 10.0
-	value101 := 101.0
+	first := NewBatch([]Metric{counter})
+	assert.Equal(t, int64(5), *first.Data[0].Delta)
 
-	tests := []struct {
-		want   model.MetricsV2
-		metric Metric
-		name   string
-	}{
-		// gauges
-		{
-			metric: &Gauge{
-				model.Gauge{
-					Name:  "gauge_test_01",
-					Value: value10,
-				},
-			},
-			want: model.MetricsV2{
-				ID:    "gauge_test_01",
-				MType: model.GaugeType,
-				Value: &value10,
-			},
-		},
-		{
-			metric: &Gauge{
-				model.Gauge{
-					Name:  "gauge_test_02",
-					Value: value101,
-				},
-			},
-			want: model.MetricsV2{
-				ID:    "gauge_test_02",
-				MType: model.GaugeType,
-				Value: &value101,
-			},
-		},
+	// пока первый батч отправляется, новые инкременты уходят отдельно
+	counter.Value += 3
+	second := NewBatch([]Metric{counter})
+	assert.Equal(t, int64(3), *second.Data[0].Delta)
 
-		// counters
-		{
-			metric: &Counter{
-				model.Counter{
-					Name:  "counter_test_01",
-					Value: delta10,
-				},
-			},
-			want: model.MetricsV2{
-				ID:    "counter_test_01",
-				MType: model.CounterType,
-				Delta: &delta0,
-			},
-		},
-		{
-			metric: &Counter{
-				model.Counter{
-					Name:  "counter_test_02",
-					Value: delta101,
-				},
-			},
-			want: model.MetricsV2{
-				ID:    "counter_test_02",
-				MType: model.CounterType,
-				Delta: &delta0,
-			},
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
+	second.Ack()
+	assert.Equal(t, int64(5), counter.Value)
 
-			tt.metric.WasSent()
-			actual := tt.metric.Payload()
-			assert.Equal(t, tt.want, actual)
-		})
-	}
+	// неотправленная дельта объединяется с новыми инкрементами
+	first.Release()
+	counter.Value += 2
+	third := NewBatch([]Metric{counter})
+	assert.Equal(t, int64(7), *third.Data[0].Delta)
+
+	third.Ack()
+	assert.Equal(t, int64(0), counter.Value)
+	assert.Equal(t, int64(0), *counter.Payload().Delta)
+}
+
+func TestGaugeDelivery(t *testing.T) {
+	gauge := &Gauge{model.Gauge{Name: "Alloc", Value: 1.5}}
+
+	batch := NewBatch([]Metric{gauge})
+	gauge.Set(2.5)
+	assert.Equal(t, 1.5, *batch.Data[0].Value)
+
+	batch.Release()
+	assert.Equal(t, 2.5, *gauge.Payload().Value)
 }
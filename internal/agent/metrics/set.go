@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"metrics/internal/core/model"
+)
+
+// Set keeps metrics gathered by collectors until they are delivered. It is safe for concurrent use.
+// Metrics which a collector stopped reporting, e.g. of a finished process, are removed from the set.
+type Set struct {
+	mux     *sync.Mutex
+	index   map[string]*entry
+	metrics []Metric // in order of the first update
+	failed  []*Batch // undelivered batches, counters keep their deltas reserved
+	updates uint64
+}
+
+// entry is a metric of the set with the collector which reported it.
+type entry struct {
+	metric  Metric
+	source  string
+	updated uint64 // number of the last update which reported the metric
+}
+
+func NewSet() *Set {
+	return &Set{mux: &sync.Mutex{}, index: make(map[string]*entry)}
+}
+
+// Update sets gauge values and increments counters by deltas collected by the source.
+// Metrics of the source which are not collected anymore are removed, counters are kept until their deltas
+// are delivered. Invalid metrics are skipped and returned as the error.
+func (s *Set) Update(source string, collected []model.MetricsV2) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.updates++
+	var errs []error
+	for _, m := range collected {
+		key := string(m.MType) + ":" + model.MetricKey(m.ID, m.Labels)
+		e, ok := s.index[key]
+		switch m.MType {
+		case model.GaugeType:
+			if m.Value == nil {
+				errs = append(errs, fmt.Errorf("gauge %s has no value", m.ID))
+				continue
+			}
+			if !ok {
+				e = s.add(key, &Gauge{*model.NewGauge(m.ID, m.Labels)})
+			}
+			e.metric.(*Gauge).Set(*m.Value)
+		case model.CounterType:
+			if m.Delta == nil {
+				errs = append(errs, fmt.Errorf("counter %s has no delta", m.ID))
+				continue
+			}
+			if !ok {
+				e = s.add(key, &Counter{Counter: *model.NewCounter(m.ID, m.Labels)})
+			}
+			if err := e.metric.(*Counter).Increment(*m.Delta); err != nil {
+				errs = append(errs, fmt.Errorf("counter %s: %w", m.ID, err))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("metric %s has unsupported type: %s", m.ID, m.MType))
+			continue
+		}
+		e.source, e.updated = source, s.updates
+	}
+	s.evict(source)
+	return errors.Join(errs...)
+}
+
+func (s *Set) add(key string, m Metric) *entry {
+	e := &entry{metric: m}
+	s.index[key] = e
+	s.metrics = append(s.metrics, m)
+	return e
+}
+
+// evict removes metrics of the source which were not reported by the last update.
+func (s *Set) evict(source string) {
+	evicted := make(map[Metric]bool)
+	for key, e := range s.index {
+		if e.source != source || e.updated == s.updates {
+			continue
+		}
+		if c, ok := e.metric.(*Counter); ok && (c.Value != 0 || c.reserved != 0) {
+			continue // недоставленные приращения отправляются до удаления счетчика
+		}
+		delete(s.index, key)
+		evicted[e.metric] = true
+	}
+	if len(evicted) == 0 {
+		return
+	}
+
+	// батчи в полете ссылаются на текущий слайс, поэтому создается новый
+	metrics := make([]Metric, 0, len(s.index))
+	for _, m := range s.metrics {
+		if !evicted[m] {
+			metrics = append(metrics, m)
+		}
+	}
+	s.metrics = metrics
+}
+
+// Batch reserves payloads of all metrics. If there is an undelivered batch, it is returned instead
+// with fresh gauge values, so its counter deltas are resent with the same idempotency key.
+func (s *Set) Batch() *Batch {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if len(s.failed) > 0 {
+		b := s.failed[0]
+		s.failed = s.failed[1:]
+		b.refresh()
+		return b
+	}
+	return NewBatch(s.metrics)
+}
+
+// Ack removes delivered counter deltas of the batch.
+func (s *Set) Ack(b *Batch) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	b.Ack()
+}
+
+// Release keeps the undelivered batch to be sent again. Its deltas are not merged into a new batch,
+// because the server could have applied the batch if only the response was lost.
+func (s *Set) Release(b *Batch) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.failed = append(s.failed, b)
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"testing"
+
+	"metrics/internal/core/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetUpdate(t *testing.T) {
+	set := NewSet()
+	value, delta, negative := 1.5, int64(2), int64(-1)
+	labels := model.Labels{"device": "sda"}
+
+	err := set.Update("test", []model.MetricsV2{
+		{ID: "Alloc", MType: model.GaugeType, Value: &value},
+		{ID: "PollCount", MType: model.CounterType, Delta: &delta},
+		{ID: "Reads", MType: model.CounterType, Labels: labels, Delta: &delta},
+		{ID: "Reads", MType: model.CounterType, Labels: labels, Delta: &delta},
+	})
+	assert.NoError(t, err)
+
+	value = 2.5
+	err = set.Update("test", []model.MetricsV2{
+		{ID: "Alloc", MType: model.GaugeType, Value: &value},
+		{ID: "PollCount", MType: model.CounterType, Delta: &negative},
+		{ID: "Broken", MType: model.GaugeType},
+		{ID: "Latency", MType: model.HistogramType},
+	})
+	assert.Error(t, err)
+
+	var gauge = 2.5
+	var pollCount, reads int64 = 2, 4
+	batch := set.Batch()
+	assert.Equal(t, []model.MetricsV2{
+		{ID: "Alloc", MType: model.GaugeType, Value: &gauge},
+		{ID: "PollCount", MType: model.CounterType, Delta: &pollCount},
+		{ID: "Reads", MType: model.CounterType, Labels: labels, Delta: &reads},
+	}, batch.Data)
+
+	set.Ack(batch)
+	assert.Equal(t, int64(0), *set.Batch().Data[2].Delta)
+}
+
+func TestSetEvict(t *testing.T) {
+	set := NewSet()
+	value, delta := 1.0, int64(3)
+	pid := model.Labels{"pid": "42"}
+
+	assert.NoError(t, set.Update("process", []model.MetricsV2{
+		{ID: "ProcessRSS", MType: model.GaugeType, Labels: pid, Value: &value},
+		{ID: "ProcessReads", MType: model.CounterType, Labels: pid, Delta: &delta},
+	}))
+	assert.NoError(t, set.Update("memory", []model.MetricsV2{{ID: "Alloc", MType: model.GaugeType, Value: &value}}))
+
+	// процесс завершился: гейдж удаляется сразу, счетчик - после доставки приращения
+	inflight := set.Batch()
+	assert.NoError(t, set.Update("process", nil))
+	batch := set.Batch()
+	assert.Len(t, batch.Data, 2)
+	assert.Equal(t, "ProcessReads", batch.Data[0].ID)
+	assert.Equal(t, int64(0), *batch.Data[0].Delta)
+	set.Ack(batch)
+
+	set.Ack(inflight)
+	assert.Len(t, inflight.Data, 3)
+	assert.NoError(t, set.Update("process", nil))
+	batch = set.Batch()
+	assert.Len(t, batch.Data, 1)
+	assert.Equal(t, "Alloc", batch.Data[0].ID)
+}
+
+func TestSetRelease(t *testing.T) {
+	set := NewSet()
+	value, delta := 1.0, int64(3)
+	update := func() {
+		assert.NoError(t, set.Update("runtime", []model.MetricsV2{
+			{ID: "PollCount", MType: model.CounterType, Delta: &delta},
+			{ID: "Alloc", MType: model.GaugeType, Value: &value},
+		}))
+	}
+
+	update()
+	failed := set.Batch()
+	failed.Key = "a"
+	set.Release(failed)
+
+	// неотправленный батч повторяется с тем же ключом и дельтами, новые дельты ждут следующего батча
+	value = 2
+	update()
+	batch := set.Batch()
+	assert.Same(t, failed, batch)
+	assert.Equal(t, "a", batch.Key)
+	assert.Equal(t, int64(3), *batch.Data[0].Delta)
+	assert.Equal(t, 2.0, *batch.Data[1].Value)
+	set.Ack(batch)
+
+	batch = set.Batch()
+	assert.Empty(t, batch.Key)
+	assert.Equal(t, int64(3), *batch.Data[0].Delta)
+}
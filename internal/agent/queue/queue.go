@@ -0,0 +1,458 @@
+// Package queue implements the disk-backed FIFO queue of metric batches.
+//
+// Records are appended to segment files in the queue directory. The position of the first
+// unacknowledged record is saved to the cursor file, so the queue survives restarts.
+// Segments are deleted when all their records are acknowledged.
+package queue
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"metrics/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// SyncPolicy defines when the queue files are flushed to the disk.
+type SyncPolicy string
+
+const (
+	SyncAlways  SyncPolicy = "always"  // after every push and ack
+	SyncSegment SyncPolicy = "segment" // when the segment is full and on close
+	SyncNever   SyncPolicy = "never"   // flushing is left to the OS
+)
+
+// DefaultSegmentSize is used when the segment size is not set.
+const DefaultSegmentSize = 4 << 20
+
+const (
+	segmentExt = ".seg"
+	cursorFile = "cursor"
+)
+
+var ErrClosed = errors.New("queue is closed")
+
+type Config struct {
+	Dir         string
+	SegmentSize int64         // segment is rotated when the next record does not fit
+	MaxSize     int64         // the oldest segments are dropped when the queue is bigger, 0 is unlimited
+	MaxAge      time.Duration // older records are dropped on read, 0 is unlimited
+	Sync        SyncPolicy
+}
+
+type segment struct {
+	seq  uint64
+	size int64
+}
+
+// position of the first unacknowledged record
+type position struct {
+	Segment uint64 `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+// Queue is safe for concurrent pushes, records should be read by a single consumer.
+type Queue struct {
+	cfg      Config
+	mux      sync.Mutex
+	segments []*segment // ordered by seq, the last one is opened for writing
+	file     *os.File
+	head     position
+	ready    chan struct{}
+	done     chan struct{}
+	closed   bool
+	now      func() time.Time
+}
+
+// Open opens the queue in the directory or creates a new one.
+// A partially written record at the end of the last segment is truncated.
+func Open(cfg Config) (*Queue, error) {
+	switch cfg.Sync {
+	case "":
+		cfg.Sync = SyncSegment
+	case SyncAlways, SyncSegment, SyncNever:
+	default:
+		return nil, fmt.Errorf("unknown queue sync policy: %s", cfg.Sync)
+	}
+	if cfg.SegmentSize <= 0 {
+		cfg.SegmentSize = DefaultSegmentSize
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating queue directory: %w", err)
+	}
+
+	q := &Queue{
+		cfg:   cfg,
+		ready: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+		now:   time.Now,
+	}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *Queue) load() error {
+	segments, err := q.listSegments()
+	if err != nil {
+		return err
+	}
+	head, err := q.loadCursor()
+	if err != nil {
+		return err
+	}
+
+	// сегменты до курсора уже подтверждены
+	for len(segments) > 0 && segments[0].seq < head.Segment {
+		if err := os.Remove(q.segmentPath(segments[0].seq)); err != nil {
+			return fmt.Errorf("error removing acknowledged segment: %w", err)
+		}
+		segments = segments[1:]
+	}
+	if len(segments) == 0 {
+		segments = append(segments, &segment{seq: max(head.Segment, 1)})
+	}
+	if head.Segment != segments[0].seq {
+		head = position{Segment: segments[0].seq}
+	}
+	q.segments, q.head = segments, head
+
+	last := segments[len(segments)-1]
+	file, err := os.OpenFile(q.segmentPath(last.seq), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening segment: %w", err)
+	}
+	if err := repair(file, last); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Seek(last.size, io.SeekStart); err != nil {
+		file.Close()
+		return fmt.Errorf("error seeking segment end: %w", err)
+	}
+	q.file = file
+	// курсор не может указывать за конец сегмента, например, после обрезки поврежденной записи
+	q.head.Offset = min(q.head.Offset, q.segments[0].size)
+	return nil
+}
+
+func (q *Queue) listSegments() ([]*segment, error) {
+	entries, err := os.ReadDir(q.cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading queue directory: %w", err)
+	}
+	segments := make([]*segment, 0, len(entries))
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), segmentExt)
+		if !ok || e.IsDir() {
+			continue
+		}
+		seq, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, fmt.Errorf("error reading segment info: %w", err)
+		}
+		segments = append(segments, &segment{seq: seq, size: info.Size()})
+	}
+	slices.SortFunc(segments, func(a, b *segment) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+	return segments, nil
+}
+
+func (q *Queue) loadCursor() (position, error) {
+	var head position
+	data, err := os.ReadFile(filepath.Join(q.cfg.Dir, cursorFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return head, nil
+	}
+	if err != nil {
+		return head, fmt.Errorf("error reading queue cursor: %w", err)
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		logger.Log.Warn("Queue cursor is damaged, the queue is replayed from the start", zap.Error(err))
+		return position{}, nil
+	}
+	return head, nil
+}
+
+// repair truncates the segment after the last valid record.
+func repair(file *os.File, seg *segment) error {
+	var offset int64
+	for {
+		rec, err := readRecord(file, offset, seg.size)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if errors.Is(err, errCorrupted) {
+			logger.Log.Warn("Truncating damaged queue segment",
+				zap.Uint64("segment", seg.seq), zap.Int64("offset", offset), zap.Int64("size", seg.size),
+			)
+			if err := file.Truncate(offset); err != nil {
+				return fmt.Errorf("error truncating segment: %w", err)
+			}
+			seg.size = offset
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		offset += rec.size
+	}
+}
+
+// Push appends the record to the queue.
+func (q *Queue) Push(rec *Record) error {
+	data, err := encodeRecord(rec, q.now())
+	if err != nil {
+		return err
+	}
+
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+
+	last := q.segments[len(q.segments)-1]
+	if last.size > 0 && last.size+int64(len(data)) > q.cfg.SegmentSize {
+		if last, err = q.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := q.file.Write(data)
+	last.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("error writing record: %w", err)
+	}
+	if q.cfg.Sync == SyncAlways {
+		if err := q.file.Sync(); err != nil {
+			return fmt.Errorf("error syncing segment: %w", err)
+		}
+	}
+	if err := q.trim(); err != nil {
+		return err
+	}
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// rotate closes the current segment and starts the next one.
+func (q *Queue) rotate() (*segment, error) {
+	if q.cfg.Sync != SyncNever {
+		if err := q.file.Sync(); err != nil {
+			return nil, fmt.Errorf("error syncing segment: %w", err)
+		}
+	}
+	if err := q.file.Close(); err != nil {
+		return nil, fmt.Errorf("error closing segment: %w", err)
+	}
+
+	seg := &segment{seq: q.segments[len(q.segments)-1].seq + 1}
+	file, err := os.OpenFile(q.segmentPath(seg.seq), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error creating segment: %w", err)
+	}
+	q.file = file
+	q.segments = append(q.segments, seg)
+	return seg, nil
+}
+
+// trim drops the oldest segments while the queue is bigger than MaxSize. The segment being written is kept.
+func (q *Queue) trim() error {
+	if q.cfg.MaxSize <= 0 {
+		return nil
+	}
+	var total int64
+	for _, seg := range q.segments {
+		total += seg.size
+	}
+	for total > q.cfg.MaxSize && len(q.segments) > 1 {
+		seg := q.segments[0]
+		logger.Log.Warn("Queue is full, dropping the oldest segment",
+			zap.Uint64("segment", seg.seq), zap.Int64("size", seg.size),
+		)
+		if err := q.dropHead(); err != nil {
+			return err
+		}
+		total -= seg.size
+	}
+	return nil
+}
+
+// dropHead removes the first segment and moves the cursor to the next one.
+func (q *Queue) dropHead() error {
+	if err := os.Remove(q.segmentPath(q.segments[0].seq)); err != nil {
+		return fmt.Errorf("error removing segment: %w", err)
+	}
+	q.segments = q.segments[1:]
+	q.head = position{Segment: q.segments[0].seq}
+	return q.saveCursor()
+}
+
+// Peek returns the first unacknowledged record, waiting for it if the queue is empty.
+// The same record is returned until it is acknowledged. Records older than MaxAge are dropped.
+func (q *Queue) Peek(ctx context.Context) (*Record, error) {
+	for {
+		rec, err := q.peek()
+		if err != nil || rec != nil {
+			return rec, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-q.done:
+			return nil, ErrClosed
+		case <-q.ready:
+		}
+	}
+}
+
+func (q *Queue) peek() (*Record, error) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if q.closed {
+		return nil, ErrClosed
+	}
+
+	for {
+		rec, err := q.read()
+		if rec == nil || err != nil {
+			return nil, err
+		}
+		if q.cfg.MaxAge <= 0 || q.now().Sub(rec.created) <= q.cfg.MaxAge {
+			return rec, nil
+		}
+		logger.Log.Warn("Dropping expired batch", zap.String("key", rec.Key), zap.Time("created", rec.created))
+		if err := q.advance(rec.size); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// read reads the record at the cursor, nil is returned if the queue is empty.
+func (q *Queue) read() (*Record, error) {
+	for {
+		seg := q.segments[0]
+		if q.head.Offset >= seg.size {
+			if len(q.segments) == 1 {
+				return nil, nil
+			}
+			if err := q.dropHead(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		file, err := os.Open(q.segmentPath(seg.seq))
+		if err != nil {
+			return nil, fmt.Errorf("error opening segment: %w", err)
+		}
+		rec, err := readRecord(file, q.head.Offset, seg.size)
+		file.Close()
+		if rec != nil {
+			rec.pos = q.head
+		}
+		if errors.Is(err, errCorrupted) {
+			logger.Log.Error("Skipping damaged queue segment",
+				zap.Uint64("segment", seg.seq), zap.Int64("offset", q.head.Offset),
+			)
+			q.head.Offset = seg.size
+			continue
+		}
+		return rec, err
+	}
+}
+
+// Ack removes the record returned by Peek from the queue. If the record was already dropped,
+// e.g. the queue was trimmed by Push, the cursor is not moved.
+func (q *Queue) Ack(rec *Record) error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	if rec.pos != q.head {
+		return nil
+	}
+	return q.advance(rec.size)
+}
+
+func (q *Queue) advance(size int64) error {
+	q.head.Offset += size
+	if q.head.Offset >= q.segments[0].size && len(q.segments) > 1 {
+		return q.dropHead()
+	}
+	return q.saveCursor()
+}
+
+func (q *Queue) saveCursor() error {
+	data, err := json.Marshal(q.head)
+	if err != nil {
+		return fmt.Errorf("error encoding queue cursor: %w", err)
+	}
+	path := filepath.Join(q.cfg.Dir, cursorFile)
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return fmt.Errorf("error creating queue cursor: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("error writing queue cursor: %w", err)
+	}
+	if q.cfg.Sync == SyncAlways {
+		if err := file.Sync(); err != nil {
+			return fmt.Errorf("error syncing queue cursor: %w", err)
+		}
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("error saving queue cursor: %w", err)
+	}
+	return nil
+}
+
+// Close flushes and closes the queue, blocked Peek returns ErrClosed.
+func (q *Queue) Close() error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	close(q.done)
+
+	var errs []error
+	if q.cfg.Sync != SyncNever {
+		if err := q.file.Sync(); err != nil {
+			errs = append(errs, fmt.Errorf("error syncing segment: %w", err))
+		}
+	}
+	if err := q.file.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("error closing segment: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (q *Queue) segmentPath(seq uint64) string {
+	return filepath.Join(q.cfg.Dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
@@ -0,0 +1,196 @@
+package queue
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"metrics/internal/core/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func record(key string) *Record {
+	delta := int64(1)
+	return &Record{Key: key, Metrics: []model.MetricsV2{{ID: "PollCount", MType: model.CounterType, Delta: &delta}}}
+}
+
+func pop(t *testing.T, q *Queue) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	rec, err := q.Peek(ctx)
+	require.NoError(t, err)
+	require.NoError(t, q.Ack(rec))
+	return rec.Key
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+	return files
+}
+
+func TestQueueOrder(t *testing.T) {
+	q, err := Open(Config{Dir: t.TempDir()})
+	require.NoError(t, err)
+	defer q.Close()
+
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, q.Push(record(key)))
+	}
+
+	// запись возвращается повторно, пока не подтверждена
+	rec, err := q.Peek(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "a", rec.Key)
+	assert.Equal(t, record("a").Metrics, rec.Metrics)
+	rec, err = q.Peek(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "a", rec.Key)
+	require.NoError(t, q.Ack(rec))
+
+	assert.Equal(t, "b", pop(t, q))
+	assert.Equal(t, "c", pop(t, q))
+}
+
+func TestQueuePeekWaits(t *testing.T) {
+	q, err := Open(Config{Dir: t.TempDir()})
+	require.NoError(t, err)
+	defer q.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = q.Peek(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		assert.NoError(t, q.Push(record("a")))
+	}()
+	assert.Equal(t, "a", pop(t, q))
+}
+
+func TestQueueReopen(t *testing.T) {
+	dir := t.TempDir()
+	q, err := Open(Config{Dir: dir, SegmentSize: 200, Sync: SyncAlways})
+	require.NoError(t, err)
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, q.Push(record(key)))
+	}
+	assert.Equal(t, "a", pop(t, q))
+	assert.Equal(t, "b", pop(t, q))
+	require.NoError(t, q.Close())
+
+	// неподтвержденные записи сохраняются после перезапуска
+	q, err = Open(Config{Dir: dir, SegmentSize: 200, Sync: SyncAlways})
+	require.NoError(t, err)
+	defer q.Close()
+	require.NoError(t, q.Push(record("f")))
+	for _, key := range []string{"c", "d", "e", "f"} {
+		assert.Equal(t, key, pop(t, q))
+	}
+
+	// подтвержденные сегменты удаляются
+	assert.Len(t, segmentFiles(t, dir), 1)
+}
+
+func TestQueueMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	size, err := encodeRecord(record("a"), time.Now())
+	require.NoError(t, err)
+
+	// по одной записи в сегменте, в очереди помещается три записи
+	q, err := Open(Config{Dir: dir, SegmentSize: 1, MaxSize: int64(3 * len(size))})
+	require.NoError(t, err)
+	defer q.Close()
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, q.Push(record(key)))
+	}
+	assert.Len(t, segmentFiles(t, dir), 3)
+	for _, key := range []string{"c", "d", "e"} {
+		assert.Equal(t, key, pop(t, q))
+	}
+}
+
+func TestQueueAckTrimmedRecord(t *testing.T) {
+	size, err := encodeRecord(record("a"), time.Now())
+	require.NoError(t, err)
+	q, err := Open(Config{Dir: t.TempDir(), SegmentSize: 1, MaxSize: int64(2 * len(size))})
+	require.NoError(t, err)
+	defer q.Close()
+
+	require.NoError(t, q.Push(record("a")))
+	rec, err := q.Peek(context.Background())
+	require.NoError(t, err)
+
+	// пока запись отправлялась, ее сегмент был удален при переполнении
+	for _, key := range []string{"b", "c"} {
+		require.NoError(t, q.Push(record(key)))
+	}
+	require.NoError(t, q.Ack(rec))
+	assert.Equal(t, "b", pop(t, q))
+	assert.Equal(t, "c", pop(t, q))
+}
+
+func TestQueueMaxAge(t *testing.T) {
+	q, err := Open(Config{Dir: t.TempDir(), MaxAge: time.Minute})
+	require.NoError(t, err)
+	defer q.Close()
+
+	now := time.Now()
+	q.now = func() time.Time { return now }
+	require.NoError(t, q.Push(record("a")))
+	now = now.Add(30 * time.Second)
+	require.NoError(t, q.Push(record("b")))
+
+	now = now.Add(45 * time.Second)
+	assert.Equal(t, "b", pop(t, q))
+}
+
+func TestQueueRepair(t *testing.T) {
+	dir := t.TempDir()
+	q, err := Open(Config{Dir: dir})
+	require.NoError(t, err)
+	require.NoError(t, q.Push(record("a")))
+	require.NoError(t, q.Close())
+
+	// запись, прерванная падением агента
+	files := segmentFiles(t, dir)
+	require.Len(t, files, 1)
+	file, err := os.OpenFile(files[0], os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = file.Write([]byte{42, 0, 0, 0, 1, 2})
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	q, err = Open(Config{Dir: dir})
+	require.NoError(t, err)
+	defer q.Close()
+	require.NoError(t, q.Push(record("b")))
+	assert.Equal(t, "a", pop(t, q))
+	assert.Equal(t, "b", pop(t, q))
+}
+
+func TestQueueClose(t *testing.T) {
+	q, err := Open(Config{Dir: t.TempDir()})
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		assert.NoError(t, q.Close())
+	}()
+	_, err = q.Peek(context.Background())
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.ErrorIs(t, q.Push(record("a")), ErrClosed)
+}
+
+func TestOpenUnknownSyncPolicy(t *testing.T) {
+	_, err := Open(Config{Dir: t.TempDir(), Sync: "sometimes"})
+	assert.Error(t, err)
+}
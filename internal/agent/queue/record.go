@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"metrics/internal/core/model"
+)
+
+// headerSize is the size of the record header: payload length, payload CRC32 and creation time in unix nanoseconds.
+const headerSize = 16
+
+var errCorrupted = errors.New("corrupted record")
+
+// Record is a batch of metrics stored in the queue.
+// The key is kept with the batch, so the replayed batch is not applied twice by the server.
+type Record struct {
+	Key     string            `json:"key"`
+	Metrics []model.MetricsV2 `json:"metrics"`
+
+	created time.Time
+	size    int64    // size of the record in the segment
+	pos     position // position of the record, set by Peek
+}
+
+// Created returns the time the record was pushed to the queue.
+func (r *Record) Created() time.Time {
+	return r.created
+}
+
+func encodeRecord(r *Record, created time.Time) ([]byte, error) {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding record: %w", err)
+	}
+	data := make([]byte, headerSize+len(payload))
+	binary.LittleEndian.PutUint32(data[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(data[4:8], crc32.ChecksumIEEE(payload))
+	binary.LittleEndian.PutUint64(data[8:16], uint64(created.UnixNano()))
+	copy(data[headerSize:], payload)
+	return data, nil
+}
+
+// readRecord reads the record at the offset. Returns io.EOF if there are no records after the offset
+// and errCorrupted if the record is partially written or damaged.
+func readRecord(r io.ReaderAt, offset, size int64) (*Record, error) {
+	if offset >= size {
+		return nil, io.EOF
+	}
+	if size-offset < headerSize {
+		return nil, errCorrupted
+	}
+	header := make([]byte, headerSize)
+	if _, err := r.ReadAt(header, offset); err != nil {
+		return nil, fmt.Errorf("error reading record header: %w", err)
+	}
+	length := int64(binary.LittleEndian.Uint32(header[0:4]))
+	if size-offset-headerSize < length {
+		return nil, errCorrupted
+	}
+	payload := make([]byte, length)
+	if _, err := r.ReadAt(payload, offset+headerSize); err != nil {
+		return nil, fmt.Errorf("error reading record: %w", err)
+	}
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) {
+		return nil, errCorrupted
+	}
+
+	rec := &Record{}
+	if err := json.Unmarshal(payload, rec); err != nil {
+		return nil, errCorrupted
+	}
+	rec.created = time.Unix(0, int64(binary.LittleEndian.Uint64(header[8:16])))
+	rec.size = headerSize + length
+	return rec, nil
+}
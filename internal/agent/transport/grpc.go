@@ -0,0 +1,196 @@
+package transport
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+	"metrics/internal/infra/api/grpcapi"
+	"metrics/internal/logger"
+	"metrics/internal/pb"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrUnavailable is returned if the gRPC server is not available, the batch could be resent.
+	ErrUnavailable = errors.New("server is unavailable")
+	// ErrRejected is returned if the server rejected the batch, e.g. it is invalid or is not signed properly.
+	// Sending the same batch again fails too.
+	ErrRejected = errors.New("batch is rejected by the server")
+)
+
+// defaultSendTimeout limits sending of a batch with its confirmation,
+// so a stalled server or a half-open connection does not block the agent.
+const defaultSendTimeout = 30 * time.Second
+
+// GRPCClient sends metrics to the StreamUpdate stream of the gRPC API.
+// The stream is opened on the first call and is kept open between calls, it is reopened after an error.
+// A call returns after the server has confirmed the batch, the server reports a failed batch by closing the stream.
+// A batch which is not confirmed in time resets the stream and ErrUnavailable is returned.
+type GRPCClient struct {
+	conn        *grpc.ClientConn
+	client      pb.MetricsClient
+	stream      pb.Metrics_StreamUpdateClient
+	cancel      context.CancelFunc
+	mux         *sync.Mutex
+	signHashKey string
+	pubKey      *rsa.PublicKey
+	timeout     time.Duration
+}
+
+func NewGRPCClient(serverAddress string, signHashKey string, pubKey *rsa.PublicKey, opts ...grpc.DialOption) (*GRPCClient, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)),
+	}, opts...)
+	conn, err := grpc.NewClient(serverAddress, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating gRPC client: %w", err)
+	}
+	return &GRPCClient{
+		conn:        conn,
+		client:      pb.NewMetricsClient(conn),
+		mux:         &sync.Mutex{},
+		signHashKey: signHashKey,
+		pubKey:      pubKey,
+		timeout:     defaultSendTimeout,
+	}, nil
+}
+
+// batch builds MetricsBatch with the same guarantees as the HTTP request: the data is encrypted
+// if the public key is set and the encrypted data is signed if the hash key is set.
+func (c *GRPCClient) batch(key string, data []model.MetricsV2) (*pb.MetricsBatch, error) {
+	req := &pb.BatchUpdateRequest{Metrics: make([]*pb.Metric, 0, len(data)), IdempotencyKey: key}
+	for i := range data {
+		req.Metrics = append(req.Metrics, grpcapi.ToPB(&data[i]))
+	}
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling batch: %w", err)
+	}
+
+	if c.pubKey != nil {
+		body, err = service.Encrypt(c.pubKey, body)
+		if err != nil {
+			return nil, fmt.Errorf("error encrypting batch: %w", err)
+		}
+	}
+
+	batch := &pb.MetricsBatch{Data: body}
+	if c.signHashKey != "" {
+		batch.Signature = pb.SignData(c.signHashKey, body)
+	}
+	return batch, nil
+}
+
+func (c *GRPCClient) SendMetric(key string, data []model.MetricsV2) error {
+	batch, err := c.batch(key, data)
+	if err != nil {
+		return err
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.stream == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		stream, err := c.client.StreamUpdate(ctx)
+		if err != nil {
+			cancel()
+			return c.wrapError("error opening stream", err)
+		}
+		c.stream, c.cancel = stream, cancel
+	}
+
+	// поток отменяется, если сервер не подтвердил пакет вовремя
+	timer := time.AfterFunc(c.timeout, c.cancel)
+	resp, err := c.exchange(batch)
+	if !timer.Stop() {
+		c.reset()
+		return fmt.Errorf("batch is not confirmed in %s: %w", c.timeout, ErrUnavailable)
+	}
+	if err == nil {
+		err = c.checkResponse(key, resp)
+	}
+	if err != nil {
+		c.reset()
+		return err
+	}
+	logger.Log.Debug("Metric was send", zap.Int("metrics", len(data)))
+	return nil
+}
+
+// exchange sends the batch and receives its confirmation.
+func (c *GRPCClient) exchange(batch *pb.MetricsBatch) (*pb.StreamUpdateResponse, error) {
+	if err := c.stream.Send(batch); err != nil {
+		// Send returns io.EOF if the server has closed the stream, the status is returned by Recv
+		_, err = c.stream.Recv()
+		return nil, c.wrapError("error sending batch", err)
+	}
+	resp, err := c.stream.Recv()
+	if err != nil {
+		return nil, c.wrapError("error receiving confirmation", err)
+	}
+	return resp, nil
+}
+
+// checkResponse checks that the response confirms the batch with the key.
+func (c *GRPCClient) checkResponse(key string, resp *pb.StreamUpdateResponse) error {
+	if resp.GetIdempotencyKey() != key {
+		return fmt.Errorf("confirmation of another batch: %s", resp.GetIdempotencyKey())
+	}
+	if c.signHashKey != "" && resp.GetSignature() != pb.SignData(c.signHashKey, []byte(key)) {
+		return errors.New("confirmation signature is not valid")
+	}
+	return nil
+}
+
+// Close closes the stream and the connection. Batches sent before are already confirmed by the server.
+func (c *GRPCClient) Close() error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	var errs []error
+	if c.stream != nil {
+		timer := time.AfterFunc(c.timeout, c.cancel)
+		if err := c.stream.CloseSend(); err != nil {
+			errs = append(errs, c.wrapError("error closing stream", err))
+		} else if _, err := c.stream.Recv(); !errors.Is(err, io.EOF) {
+			errs = append(errs, c.wrapError("error closing stream", err))
+		}
+		timer.Stop()
+		c.reset()
+	}
+	if err := c.conn.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("error closing connection: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *GRPCClient) reset() {
+	c.cancel()
+	c.stream, c.cancel = nil, nil
+}
+
+func (c *GRPCClient) wrapError(msg string, err error) error {
+	switch status.Code(err) {
+	case codes.Unavailable:
+		return fmt.Errorf("%s: %w: %w", msg, ErrUnavailable, err)
+	case codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied:
+		return fmt.Errorf("%s: %w: %w", msg, ErrRejected, err)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
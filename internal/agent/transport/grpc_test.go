@@ -0,0 +1,173 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"metrics/internal/core/config"
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+	"metrics/internal/infra/api/grpcapi"
+	"metrics/internal/infra/store/memory"
+	"metrics/internal/pb"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func startGRPCServer(t *testing.T, hashKey string, privateKey *rsa.PrivateKey) (*service.MetricService, grpc.DialOption) {
+	var wg sync.WaitGroup
+	store, err := memory.NewStore(
+		context.Background(),
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+		},
+	)
+	require.NoError(t, err)
+
+	metricService := service.NewMetricService(store)
+	api := grpcapi.NewAPI(&config.Config{HashKey: hashKey}, metricService, service.NewSystemService(store), privateKey)
+	listener := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = api.Serve(listener)
+	}()
+	t.Cleanup(func() {
+		require.NoError(t, api.Shutdown(context.Background()))
+	})
+
+	dialer := grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	})
+	return metricService, dialer
+}
+
+func TestGRPCSendMetric(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	metricService, dialer := startGRPCServer(t, "secret", privateKey)
+
+	client, err := NewGRPCClient("passthrough:///bufnet", "secret", &privateKey.PublicKey, dialer)
+	require.NoError(t, err)
+
+	delta := int64(3)
+	value := 1.5
+	data := []model.MetricsV2{
+		{ID: "PollCount", MType: model.CounterType, Delta: &delta},
+		{ID: "Alloc", MType: model.GaugeType, Value: &value},
+	}
+	require.NoError(t, client.SendMetric(NewIdempotencyKey(), data))
+	require.NoError(t, client.SendMetric(NewIdempotencyKey(), data))
+	require.NoError(t, client.Close())
+
+	counter, err := metricService.GetMetric(context.Background(), &model.MetricsV2{ID: "PollCount", MType: model.CounterType})
+	require.NoError(t, err)
+	require.NotNil(t, counter)
+	assert.Equal(t, int64(6), *counter.Delta)
+
+	gauge, err := metricService.GetMetric(context.Background(), &model.MetricsV2{ID: "Alloc", MType: model.GaugeType})
+	require.NoError(t, err)
+	require.NotNil(t, gauge)
+	assert.Equal(t, 1.5, *gauge.Value)
+}
+
+func TestGRPCSendMetricRetry(t *testing.T) {
+	metricService, dialer := startGRPCServer(t, "", nil)
+
+	client, err := NewGRPCClient("passthrough:///bufnet", "", nil, dialer)
+	require.NoError(t, err)
+
+	delta := int64(3)
+	data := []model.MetricsV2{{ID: "PollCount", MType: model.CounterType, Delta: &delta}}
+	key := NewIdempotencyKey()
+	require.NoError(t, client.SendMetric(key, data))
+	require.NoError(t, client.SendMetric(key, data))
+	require.NoError(t, client.Close())
+
+	counter, err := metricService.GetMetric(context.Background(), &model.MetricsV2{ID: "PollCount", MType: model.CounterType})
+	require.NoError(t, err)
+	require.NotNil(t, counter)
+	assert.Equal(t, int64(3), *counter.Delta)
+}
+
+func TestGRPCSendMetricWrongSignature(t *testing.T) {
+	metricService, dialer := startGRPCServer(t, "secret", nil)
+
+	client, err := NewGRPCClient("passthrough:///bufnet", "wrong", nil, dialer)
+	require.NoError(t, err)
+
+	delta := int64(1)
+	data := []model.MetricsV2{{ID: "PollCount", MType: model.CounterType, Delta: &delta}}
+
+	// вызов завершается только после подтверждения пакета сервером
+	err = client.SendMetric(NewIdempotencyKey(), data)
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.ErrorIs(t, err, ErrRejected)
+	require.NoError(t, client.Close())
+
+	counter, err := metricService.GetMetric(context.Background(), &model.MetricsV2{ID: "PollCount", MType: model.CounterType})
+	require.NoError(t, err)
+	assert.Nil(t, counter)
+}
+
+func TestGRPCSendMetricUnavailable(t *testing.T) {
+	listener := bufconn.Listen(1024)
+	require.NoError(t, listener.Close())
+	dialer := grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	})
+
+	client, err := NewGRPCClient("passthrough:///bufnet", "", nil, dialer)
+	require.NoError(t, err)
+	defer client.Close()
+
+	delta := int64(1)
+	err = client.SendMetric("", []model.MetricsV2{{ID: "PollCount", MType: model.CounterType, Delta: &delta}})
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+// stalledServer accepts batches, but never confirms them.
+type stalledServer struct {
+	pb.UnimplementedMetricsServer
+}
+
+func (s *stalledServer) StreamUpdate(stream pb.Metrics_StreamUpdateServer) error {
+	<-stream.Context().Done()
+	return nil
+}
+
+func TestGRPCSendMetricTimeout(t *testing.T) {
+	server := grpc.NewServer()
+	pb.RegisterMetricsServer(server, &stalledServer{})
+	listener := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+	dialer := grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	})
+
+	client, err := NewGRPCClient("passthrough:///bufnet", "", nil, dialer)
+	require.NoError(t, err)
+	client.timeout = 50 * time.Millisecond
+
+	delta := int64(1)
+	data := []model.MetricsV2{{ID: "PollCount", MType: model.CounterType, Delta: &delta}}
+	assert.ErrorIs(t, client.SendMetric(NewIdempotencyKey(), data), ErrUnavailable)
+	assert.ErrorIs(t, client.SendMetric(NewIdempotencyKey(), data), ErrUnavailable)
+	// закрытие не блокируется зависшим сервером
+	require.NoError(t, client.Close())
+}
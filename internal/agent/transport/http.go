@@ -66,7 +66,7 @@ func (c *HTTPClient) sign(data *[]byte) string {
 }
 
 // HTTTP Client to sent metrics to MetricEndpoint
-func (c *HTTPClient) SendMetric(data []model.MetricsV2) error {
+func (c *HTTPClient) SendMetric(key string, data []model.MetricsV2) error {
 	body, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("error marshalling request body: %w", err)
@@ -89,6 +89,9 @@ func (c *HTTPClient) SendMetric(data []model.MetricsV2) error {
 	}
 	req.Header.Set("Content-Encoding", "gzip")
 	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
 
 	if c.signHashKey != "" {
 		signature := c.sign(&body)
@@ -109,7 +112,16 @@ func (c *HTTPClient) SendMetric(data []model.MetricsV2) error {
 			return fmt.Errorf("reading body error: %w, code: %d", err, resp.StatusCode)
 		}
 
-		return fmt.Errorf("request error: %s, code: %d", string(body), resp.StatusCode)
+		err = fmt.Errorf("request error: %s, code: %d", string(body), resp.StatusCode)
+		if rejected(resp.StatusCode) {
+			return fmt.Errorf("%w: %w", ErrRejected, err)
+		}
+		return err
 	}
 	return nil
 }
+
+// rejected reports whether the status means the request is invalid, so it fails again if it is resent.
+func rejected(code int) bool {
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
@@ -1,6 +1,8 @@
 package transport
 
 import (
+	"errors"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 
@@ -105,14 +107,34 @@ func TestSendMetric(t *testing.T) {
 		require.NoError(t, err)
 
 		assert.Equal(t, expectedMetrics, actualMetrics)
+		assert.Equal(t, "batch-1", c.GetHeader("Idempotency-Key"))
 	})
 	testSrv := httptest.NewServer(srv)
 
 	client := NewClient(testSrv.URL, "", nil)
 
 	// Call the function being tested
-	err := client.SendMetric(expectedMetrics)
+	err := client.SendMetric("batch-1", expectedMetrics)
 
 	// Verify the result
 	require.NoError(t, err)
 }
+
+func TestSendMetricRejected(t *testing.T) {
+	for code, rejected := range map[int]bool{
+		http.StatusBadRequest:          true,
+		http.StatusUnauthorized:        true,
+		http.StatusTooManyRequests:     false,
+		http.StatusServiceUnavailable:  false,
+		http.StatusInternalServerError: false,
+	} {
+		testSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(code)
+		}))
+		err := NewClient(testSrv.URL, "", nil).SendMetric("batch-1", nil)
+		testSrv.Close()
+
+		require.Error(t, err)
+		assert.Equal(t, rejected, errors.Is(err, ErrRejected), code)
+	}
+}
@@ -0,0 +1,13 @@
+package transport
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// NewIdempotencyKey returns a random key identifying the batch on the server.
+func NewIdempotencyKey() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b) // rand.Read never returns an error
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,359 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"metrics/internal/core/model"
+	"metrics/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// State of an alert.
+//   - pending: condition is true, but not longer than the rule For duration;
+//   - firing: condition is true longer than the rule For duration;
+//   - resolved: condition of the firing alert became false. Resolved alert is dropped on the next evaluation.
+type State string
+
+const (
+	StatePending  State = "pending"
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+// AlertNameLabel is added to alert labels and contains the rule name.
+const AlertNameLabel = "alertname"
+
+// Store is a part of service.Store used to evaluate rules.
+type Store interface {
+	ListGauge(ctx context.Context) ([]*model.Gauge, error)
+	ListCounter(ctx context.Context) ([]*model.Counter, error)
+	ListSamples(ctx context.Context, req *model.MetricsV2, from, to time.Time) ([]*model.Sample, error)
+}
+
+type Alert struct {
+	ActiveAt    time.Time         `json:"active_at"`
+	FiredAt     *time.Time        `json:"fired_at,omitempty"`
+	ResolvedAt  *time.Time        `json:"resolved_at,omitempty"`
+	Labels      model.Labels      `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Rule        string            `json:"rule"`
+	Metric      string            `json:"metric"`
+	State       State             `json:"state"`
+	Value       float64           `json:"value"`
+}
+
+// series is a metric which satisfies the rule condition.
+type series struct {
+	labels model.Labels
+	value  float64
+}
+
+// Engine evaluates alerting rules periodically and keeps state of the alerts.
+// Firing and resolved alerts are passed to the dispatcher if it is set.
+type Engine struct {
+	store      Store
+	dispatcher *Dispatcher
+	mux        *sync.RWMutex
+	quit       chan bool
+	now        func() time.Time
+	alerts     map[string]*Alert
+	startedAt  time.Time
+	rules      []*Rule
+	interval   time.Duration
+}
+
+func NewEngine(store Store, rules []*Rule, interval time.Duration, dispatcher *Dispatcher) *Engine {
+	return &Engine{
+		store:      store,
+		dispatcher: dispatcher,
+		mux:        &sync.RWMutex{},
+		quit:       make(chan bool),
+		now:        time.Now,
+		alerts:     make(map[string]*Alert),
+		startedAt:  time.Now(),
+		rules:      rules,
+		interval:   interval,
+	}
+}
+
+// Run evaluates rules with the engine interval until Close is called.
+func (e *Engine) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	if len(e.rules) == 0 {
+		logger.Log.Info("No alerting rules, alerting engine is not started")
+		return
+	}
+	logger.Log.Info("Alerting engine started", zap.Int("rules", len(e.rules)), zap.Duration("interval", e.interval))
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	// уведомления отправляются отдельно, чтобы медленный получатель не задерживал вычисление правил
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	notifications := make(chan []*Alert, 1)
+	defer close(notifications)
+	wg.Add(1)
+	go e.dispatch(ctx, wg, notifications)
+
+	for {
+		select {
+		case <-e.quit:
+			logger.Log.Info("Close alerting engine cicle")
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			notify, err := e.evaluate(ctx)
+			if err != nil {
+				logger.Log.Error("Alerting rules evaluation error", zap.Error(err))
+			}
+			// отправка еще не закончена, непереданные уведомления заменяются текущими
+			select {
+			case <-notifications:
+			default:
+			}
+			notifications <- notify
+		}
+	}
+}
+
+// dispatch sends alerts of the latest evaluation. Groups which were not sent are kept by the dispatcher,
+// so skipped evaluations are not lost.
+func (e *Engine) dispatch(ctx context.Context, wg *sync.WaitGroup, notifications <-chan []*Alert) {
+	defer wg.Done()
+	for notify := range notifications {
+		if e.dispatcher == nil {
+			continue
+		}
+		if err := e.dispatcher.Dispatch(ctx, e.rules, notify, e.now()); err != nil {
+			logger.Log.Error("Alert notifications dispatch error", zap.Error(err))
+		}
+	}
+}
+
+func (e *Engine) Close() {
+	close(e.quit)
+}
+
+// Rules returns loaded alerting rules.
+func (e *Engine) Rules() []*Rule {
+	return e.rules
+}
+
+// Alerts returns pending and firing alerts sorted by rule and labels.
+func (e *Engine) Alerts() []*Alert {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
+	res := make([]*Alert, 0, len(e.alerts))
+	for _, a := range e.alerts {
+		if a.State == StateResolved {
+			continue
+		}
+		alert := *a
+		res = append(res, &alert)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Rule != res[j].Rule {
+			return res[i].Rule < res[j].Rule
+		}
+		return res[i].Labels.String() < res[j].Labels.String()
+	})
+	return res
+}
+
+// Evaluate checks all rules once, updates alert states and sends notifications.
+// Alerts of a rule which evaluation failed keep their state.
+func (e *Engine) Evaluate(ctx context.Context) error {
+	notify, err := e.evaluate(ctx)
+	if e.dispatcher != nil {
+		if dispatchErr := e.dispatcher.Dispatch(ctx, e.rules, notify, e.now()); dispatchErr != nil {
+			err = errors.Join(err, dispatchErr)
+		}
+	}
+	return err
+}
+
+// evaluate checks all rules and returns firing and resolved alerts to be sent.
+func (e *Engine) evaluate(ctx context.Context) ([]*Alert, error) {
+	now := e.now()
+
+	var errs []error
+	failed := make(map[string]bool)
+	results := make(map[*Rule][]*series, len(e.rules))
+	for _, rule := range e.rules {
+		res, err := e.evaluateRule(ctx, rule, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", rule.Name, err))
+			failed[rule.Name] = true
+			continue
+		}
+		results[rule] = res
+	}
+
+	return e.update(results, failed, now), errors.Join(errs...)
+}
+
+// update changes alert states with the evaluation results and returns copies of firing and resolved alerts.
+func (e *Engine) update(results map[*Rule][]*series, failed map[string]bool, now time.Time) []*Alert {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	for key, alert := range e.alerts {
+		if alert.State == StateResolved {
+			delete(e.alerts, key)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for rule, res := range results {
+		for _, s := range res {
+			key := model.MetricKey(rule.Name, s.labels)
+			seen[key] = true
+
+			alert, ok := e.alerts[key]
+			if !ok {
+				alert = newAlert(rule, s.labels, now)
+				e.alerts[key] = alert
+			}
+			alert.Value = s.value
+			if alert.State == StatePending && now.Sub(alert.ActiveAt) >= time.Duration(rule.For) {
+				firedAt := now
+				alert.State = StateFiring
+				alert.FiredAt = &firedAt
+				logger.Log.Info("Alert is firing", zap.String("rule", rule.Name), zap.String("labels", s.labels.String()))
+			}
+		}
+	}
+
+	for key, alert := range e.alerts {
+		if seen[key] || failed[alert.Rule] {
+			continue
+		}
+		switch alert.State {
+		case StatePending:
+			delete(e.alerts, key)
+		case StateFiring:
+			resolvedAt := now
+			alert.State = StateResolved
+			alert.ResolvedAt = &resolvedAt
+			logger.Log.Info("Alert is resolved", zap.String("rule", alert.Rule), zap.String("labels", alert.Labels.String()))
+		}
+	}
+
+	notify := make([]*Alert, 0, len(e.alerts))
+	for _, a := range e.alerts {
+		if a.State != StatePending {
+			alert := *a
+			notify = append(notify, &alert)
+		}
+	}
+	return notify
+}
+
+func (e *Engine) evaluateRule(ctx context.Context, rule *Rule, now time.Time) ([]*series, error) {
+	candidates, err := e.listSeries(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	if rule.IsAbsence() {
+		return e.absent(ctx, rule, candidates, now)
+	}
+
+	res := make([]*series, 0)
+	for _, s := range candidates {
+		if rule.Condition.Check(s.value, *rule.Threshold) {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+// absent returns metrics which have no samples for the rule AbsentFor period.
+// If there is no metric at all the single alert with labels from equality matchers is raised,
+// but not earlier than AbsentFor after the engine start.
+func (e *Engine) absent(ctx context.Context, rule *Rule, candidates []*series, now time.Time) ([]*series, error) {
+	from := now.Add(-time.Duration(rule.AbsentFor))
+	if len(candidates) == 0 {
+		if e.startedAt.After(from) {
+			return nil, nil
+		}
+		labels := model.Labels{}
+		for _, m := range rule.matchers {
+			if m.Type == model.MatchEqual {
+				labels[m.Name] = m.Value
+			}
+		}
+		return []*series{{labels: labels}}, nil
+	}
+
+	res := make([]*series, 0)
+	for _, s := range candidates {
+		req := &model.MetricsV2{ID: rule.Metric, MType: rule.Type, Labels: s.labels}
+		samples, err := e.store.ListSamples(ctx, req, from, now)
+		if err != nil {
+			return nil, fmt.Errorf("listing samples error: %w", err)
+		}
+		if len(samples) == 0 {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+// listSeries returns stored metrics with the rule name, type and labels.
+func (e *Engine) listSeries(ctx context.Context, rule *Rule) ([]*series, error) {
+	res := make([]*series, 0)
+	switch rule.Type {
+	case model.GaugeType:
+		gauges, err := e.store.ListGauge(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing gauges error: %w", err)
+		}
+		for _, g := range gauges {
+			if g.Name == rule.Metric && model.MatchLabels(g.Labels, rule.matchers) {
+				res = append(res, &series{labels: g.Labels, value: g.Value})
+			}
+		}
+	case model.CounterType:
+		counters, err := e.store.ListCounter(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing counters error: %w", err)
+		}
+		for _, c := range counters {
+			if c.Name == rule.Metric && model.MatchLabels(c.Labels, rule.matchers) {
+				res = append(res, &series{labels: c.Labels, value: float64(c.Value)})
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported metric type: %s", rule.Type)
+	}
+	return res, nil
+}
+
+func newAlert(rule *Rule, seriesLabels model.Labels, now time.Time) *Alert {
+	labels := seriesLabels.Copy()
+	if labels == nil {
+		labels = model.Labels{}
+	}
+	for k, v := range rule.Labels {
+		labels[k] = v
+	}
+	labels[AlertNameLabel] = rule.Name
+
+	return &Alert{
+		ActiveAt:    now,
+		Labels:      labels,
+		Annotations: rule.Annotations,
+		Rule:        rule.Name,
+		Metric:      model.MetricKey(rule.Metric, seriesLabels),
+		State:       StatePending,
+	}
+}
@@ -0,0 +1,280 @@
+package alert
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"metrics/internal/core/model"
+	"metrics/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newTestRule(t *testing.T, rule *Rule) *Rule {
+	require.NoError(t, rule.Validate())
+	return rule
+}
+
+func TestEngineThreshold(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockStore(ctrl)
+
+	threshold := 1e9
+	rule := newTestRule(t, &Rule{
+		Name:      "HighHeap",
+		Metric:    "HeapAlloc",
+		Type:      model.GaugeType,
+		Condition: Greater,
+		Threshold: &threshold,
+		For:       Duration(2 * time.Minute),
+		Labels:    map[string]string{"severity": "critical"},
+	})
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	engine := NewEngine(store, []*Rule{rule}, time.Second, nil)
+	engine.now = func() time.Time { return now }
+
+	value := 2e9
+	store.EXPECT().ListGauge(gomock.Any()).DoAndReturn(func(context.Context) ([]*model.Gauge, error) {
+		return []*model.Gauge{
+			{Name: "HeapAlloc", Labels: model.Labels{"host": "a"}, Value: value},
+			{Name: "HeapAlloc", Labels: model.Labels{"host": "b"}, Value: 1},
+			{Name: "Alloc", Value: 3e9},
+		}, nil
+	}).AnyTimes()
+
+	ctx := context.Background()
+
+	// condition is true, but not long enough
+	require.NoError(t, engine.Evaluate(ctx))
+	alerts := engine.Alerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StatePending, alerts[0].State)
+	assert.Equal(t, model.Labels{"host": "a", "severity": "critical", AlertNameLabel: "HighHeap"}, alerts[0].Labels)
+	assert.Equal(t, `HeapAlloc{host="a"}`, alerts[0].Metric)
+	assert.Equal(t, 2e9, alerts[0].Value)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, engine.Evaluate(ctx))
+	assert.Equal(t, StatePending, engine.Alerts()[0].State)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, engine.Evaluate(ctx))
+	alerts = engine.Alerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StateFiring, alerts[0].State)
+	require.NotNil(t, alerts[0].FiredAt)
+	assert.Equal(t, now, *alerts[0].FiredAt)
+
+	// condition became false: alert is resolved and dropped on the next evaluation
+	value = 1
+	now = now.Add(time.Minute)
+	require.NoError(t, engine.Evaluate(ctx))
+	assert.Empty(t, engine.Alerts())
+	assert.Equal(t, StateResolved, engine.alerts[`HighHeap{host="a"}`].State)
+
+	require.NoError(t, engine.Evaluate(ctx))
+	assert.Empty(t, engine.alerts)
+}
+
+func TestEnginePendingIsDropped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockStore(ctrl)
+
+	threshold := 10.0
+	rule := newTestRule(t, &Rule{
+		Name:      "TooManyPolls",
+		Metric:    "PollCount",
+		Type:      model.CounterType,
+		Condition: GreaterOrEqual,
+		Threshold: &threshold,
+		For:       Duration(time.Minute),
+	})
+	engine := NewEngine(store, []*Rule{rule}, time.Second, nil)
+
+	gomock.InOrder(
+		store.EXPECT().ListCounter(gomock.Any()).Return([]*model.Counter{{Name: "PollCount", Value: 10}}, nil),
+		store.EXPECT().ListCounter(gomock.Any()).Return([]*model.Counter{{Name: "PollCount", Value: 5}}, nil),
+	)
+
+	ctx := context.Background()
+	require.NoError(t, engine.Evaluate(ctx))
+	require.Len(t, engine.Alerts(), 1)
+
+	require.NoError(t, engine.Evaluate(ctx))
+	assert.Empty(t, engine.alerts)
+}
+
+func TestEngineAbsence(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockStore(ctrl)
+
+	rule := newTestRule(t, &Rule{
+		Name:      "NoPolls",
+		Metric:    "PollCount",
+		Type:      model.CounterType,
+		AbsentFor: Duration(time.Minute),
+		Match:     []string{"host=a"},
+	})
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	engine := NewEngine(store, []*Rule{rule}, time.Second, nil)
+	engine.startedAt = now
+	engine.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// there is no metric yet, wait AbsentFor after the start
+	store.EXPECT().ListCounter(gomock.Any()).Return(nil, nil).Times(2)
+	require.NoError(t, engine.Evaluate(ctx))
+	assert.Empty(t, engine.Alerts())
+
+	now = now.Add(time.Minute)
+	require.NoError(t, engine.Evaluate(ctx))
+	alerts := engine.Alerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StateFiring, alerts[0].State)
+	assert.Equal(t, model.Labels{"host": "a", AlertNameLabel: "NoPolls"}, alerts[0].Labels)
+
+	// metric is reported
+	counters := []*model.Counter{{Name: "PollCount", Labels: model.Labels{"host": "a"}, Value: 1}}
+	req := &model.MetricsV2{ID: "PollCount", MType: model.CounterType, Labels: model.Labels{"host": "a"}}
+	store.EXPECT().ListCounter(gomock.Any()).Return(counters, nil)
+	store.EXPECT().ListSamples(gomock.Any(), req, now.Add(-time.Minute), now).
+		Return([]*model.Sample{{Timestamp: now, Value: 1}}, nil)
+	require.NoError(t, engine.Evaluate(ctx))
+	assert.Empty(t, engine.Alerts())
+
+	// no updates for a minute
+	now = now.Add(2 * time.Minute)
+	store.EXPECT().ListCounter(gomock.Any()).Return(counters, nil)
+	store.EXPECT().ListSamples(gomock.Any(), req, now.Add(-time.Minute), now).Return(nil, nil)
+	require.NoError(t, engine.Evaluate(ctx))
+	alerts = engine.Alerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StateFiring, alerts[0].State)
+	assert.Equal(t, `PollCount{host="a"}`, alerts[0].Metric)
+}
+
+func TestEngineFailedRuleKeepsAlerts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockStore(ctrl)
+
+	threshold := 0.0
+	rule := newTestRule(t, &Rule{
+		Name:      "Any",
+		Metric:    "Alloc",
+		Type:      model.GaugeType,
+		Condition: Greater,
+		Threshold: &threshold,
+	})
+	engine := NewEngine(store, []*Rule{rule}, time.Second, nil)
+
+	gomock.InOrder(
+		store.EXPECT().ListGauge(gomock.Any()).Return([]*model.Gauge{{Name: "Alloc", Value: 1}}, nil),
+		store.EXPECT().ListGauge(gomock.Any()).Return(nil, assert.AnError),
+	)
+
+	ctx := context.Background()
+	require.NoError(t, engine.Evaluate(ctx))
+	require.Len(t, engine.Alerts(), 1)
+	assert.Equal(t, StateFiring, engine.Alerts()[0].State)
+
+	assert.ErrorIs(t, engine.Evaluate(ctx), assert.AnError)
+	require.Len(t, engine.Alerts(), 1)
+	assert.Equal(t, StateFiring, engine.Alerts()[0].State)
+}
+
+func TestEngineNotifiesFiringAlerts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockStore(ctrl)
+
+	threshold := 0.0
+	rule := newTestRule(t, &Rule{
+		Name:      "Any",
+		Metric:    "Alloc",
+		Type:      model.GaugeType,
+		Condition: Greater,
+		Threshold: &threshold,
+		For:       Duration(time.Minute),
+		Receivers: []string{"ops"},
+	})
+	ops := &fakeNotifier{}
+	engine := NewEngine(store, []*Rule{rule}, time.Second, NewDispatcher(map[string]Notifier{"ops": ops}))
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	engine.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	gomock.InOrder(
+		store.EXPECT().ListGauge(gomock.Any()).Return([]*model.Gauge{{Name: "Alloc", Value: 1}}, nil).Times(2),
+		store.EXPECT().ListGauge(gomock.Any()).Return(nil, nil),
+	)
+
+	// pending alert is not sent
+	require.NoError(t, engine.Evaluate(ctx))
+	assert.Empty(t, ops.sent)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, engine.Evaluate(ctx))
+	require.Len(t, ops.sent, 1)
+	assert.Equal(t, StateFiring, ops.sent[0].Status)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, engine.Evaluate(ctx))
+	require.Len(t, ops.sent, 2)
+	assert.Equal(t, StateResolved, ops.sent[1].Status)
+}
+
+// blockingNotifier blocks until the context is canceled.
+type blockingNotifier struct{}
+
+func (blockingNotifier) Notify(ctx context.Context, _ *Notification) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestEngineRunSlowReceiver(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mocks.NewMockStore(ctrl)
+
+	threshold := 0.0
+	rule := newTestRule(t, &Rule{
+		Name:      "Any",
+		Metric:    "Alloc",
+		Type:      model.GaugeType,
+		Condition: Greater,
+		Threshold: &threshold,
+		Receivers: []string{"ops"},
+	})
+	engine := NewEngine(
+		store, []*Rule{rule}, 10*time.Millisecond, NewDispatcher(map[string]Notifier{"ops": blockingNotifier{}}),
+	)
+
+	// правила вычисляются, пока получатель не отвечает
+	evaluated := make(chan struct{}, 10)
+	store.EXPECT().ListGauge(gomock.Any()).DoAndReturn(func(context.Context) ([]*model.Gauge, error) {
+		select {
+		case evaluated <- struct{}{}:
+		default:
+		}
+		return []*model.Gauge{{Name: "Alloc", Value: 1}}, nil
+	}).MinTimes(3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go engine.Run(ctx, &wg)
+	for range 3 {
+		select {
+		case <-evaluated:
+		case <-time.After(time.Second):
+			t.Fatal("rules are not evaluated")
+		}
+	}
+	cancel()
+	wg.Wait()
+}
@@ -0,0 +1,267 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"metrics/internal/core/model"
+	"metrics/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// DefaultRepeatInterval is used for rules without repeat_interval.
+const DefaultRepeatInterval = Duration(4 * time.Hour)
+
+// ReceiverConfig describes a notification channel. Exactly one of Webhook, File or Email must be set.
+type ReceiverConfig struct {
+	Webhook *WebhookConfig `json:"webhook,omitempty"`
+	File    *FileConfig    `json:"file,omitempty"`
+	Email   *EmailConfig   `json:"email,omitempty"`
+	Name    string         `json:"name"`
+}
+
+// WebhookConfig: notifications are POSTed as JSON in the Alertmanager webhook format.
+type WebhookConfig struct {
+	URL         string   `json:"url"`
+	Timeout     Duration `json:"timeout,omitempty"`
+	MaxAttempts int      `json:"max_attempts,omitempty"`
+}
+
+// FileConfig: notifications are appended to the file, one JSON per line.
+type FileConfig struct {
+	Path string `json:"path"`
+}
+
+// EmailConfig: notifications are sent by SMTP. Smarthost is a host:port of the SMTP server,
+// PLAIN authentication is used if Username is set. Timeout limits the SMTP session.
+type EmailConfig struct {
+	Smarthost string   `json:"smarthost"`
+	From      string   `json:"from"`
+	Username  string   `json:"username,omitempty"`
+	Password  string   `json:"password,omitempty"`
+	To        []string `json:"to"`
+	Timeout   Duration `json:"timeout,omitempty"`
+}
+
+func (c *ReceiverConfig) Validate() error {
+	if c.Name == "" {
+		return errors.New("receiver name is required")
+	}
+	channels := 0
+	if c.Webhook != nil {
+		channels++
+		if c.Webhook.URL == "" {
+			return fmt.Errorf("receiver %s: webhook url is required", c.Name)
+		}
+	}
+	if c.File != nil {
+		channels++
+		if c.File.Path == "" {
+			return fmt.Errorf("receiver %s: file path is required", c.Name)
+		}
+	}
+	if c.Email != nil {
+		channels++
+		if c.Email.Smarthost == "" || c.Email.From == "" || len(c.Email.To) == 0 {
+			return fmt.Errorf("receiver %s: email smarthost, from and to are required", c.Name)
+		}
+	}
+	if channels != 1 {
+		return fmt.Errorf("receiver %s: exactly one of webhook, file or email must be set", c.Name)
+	}
+	return nil
+}
+
+// Notification is a group of alerts sent to a receiver.
+// Status is firing if at least one alert of the group is firing.
+type Notification struct {
+	GroupLabels model.Labels
+	Receiver    string
+	GroupKey    string
+	Status      State
+	Alerts      []*Alert
+}
+
+// Notifier sends notifications to a channel.
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
+
+// group is a state of the alerts group already sent to a receiver.
+type group struct {
+	lastSent time.Time
+	sent     *batch // the last sent batch, it is used to resolve the group if its alerts are gone
+	firing   string // keys of firing alerts which were sent
+}
+
+// batch is alerts of a group prepared to be sent.
+type batch struct {
+	rule     *Rule
+	labels   model.Labels
+	receiver string
+	key      string
+	alerts   []*Alert
+}
+
+// Dispatcher routes alerts to receivers of their rules.
+// A group is sent when the set of its firing alerts changes or the rule repeat interval has passed.
+// When all alerts of a sent group are resolved, the resolved notification is sent once.
+type Dispatcher struct {
+	notifiers map[string]Notifier
+	groups    map[string]*group
+	mux       *sync.Mutex
+}
+
+func NewDispatcher(notifiers map[string]Notifier) *Dispatcher {
+	return &Dispatcher{
+		notifiers: notifiers,
+		groups:    make(map[string]*group),
+		mux:       &sync.Mutex{},
+	}
+}
+
+// Dispatch sends notifications for firing and resolved alerts. Pending alerts are ignored.
+// A group which failed to be sent is retried on the next call. If alerts of a sent group are not passed anymore,
+// e.g. resolved alerts are removed by the engine, the group is resolved with the last sent alerts.
+func (d *Dispatcher) Dispatch(ctx context.Context, rules []*Rule, alerts []*Alert, now time.Time) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	batches := d.batches(rules, alerts)
+
+	var errs []error
+	for key, b := range batches {
+		firing := firingKeys(b.alerts)
+		g, sent := d.groups[key]
+		switch {
+		case firing == "" && !sent:
+			continue
+		case firing != "" && sent && g.firing == firing && now.Sub(g.lastSent) < time.Duration(b.rule.RepeatInterval):
+			continue
+		}
+
+		if err := d.send(ctx, b); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if firing == "" {
+			delete(d.groups, key)
+			continue
+		}
+		d.groups[key] = &group{lastSent: now, sent: b, firing: firing}
+	}
+
+	for key, g := range d.groups {
+		if _, ok := batches[key]; ok {
+			continue
+		}
+		if err := d.send(ctx, resolvedBatch(g.sent, now)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(d.groups, key)
+	}
+	return errors.Join(errs...)
+}
+
+// resolvedBatch returns the batch with copies of alerts which are resolved now if they were not resolved before.
+func resolvedBatch(b *batch, now time.Time) *batch {
+	res := *b
+	res.alerts = make([]*Alert, 0, len(b.alerts))
+	for _, a := range b.alerts {
+		alert := *a
+		if alert.State != StateResolved {
+			alert.State = StateResolved
+			alert.ResolvedAt = &now
+		}
+		res.alerts = append(res.alerts, &alert)
+	}
+	return &res
+}
+
+func (d *Dispatcher) batches(rules []*Rule, alerts []*Alert) map[string]*batch {
+	byName := make(map[string]*Rule, len(rules))
+	for _, rule := range rules {
+		byName[rule.Name] = rule
+	}
+
+	batches := make(map[string]*batch)
+	for _, alert := range alerts {
+		if alert.State == StatePending {
+			continue
+		}
+		rule, ok := byName[alert.Rule]
+		if !ok {
+			continue
+		}
+
+		labels := model.Labels{AlertNameLabel: rule.Name}
+		for _, name := range rule.GroupBy {
+			if value, ok := alert.Labels[name]; ok {
+				labels[name] = value
+			}
+		}
+		groupKey := "{}:{" + labels.String() + "}"
+
+		for _, receiver := range rule.Receivers {
+			key := receiver + "/" + groupKey
+			b, ok := batches[key]
+			if !ok {
+				b = &batch{rule: rule, labels: labels, receiver: receiver, key: groupKey}
+				batches[key] = b
+			}
+			b.alerts = append(b.alerts, alert)
+		}
+	}
+	return batches
+}
+
+func (d *Dispatcher) send(ctx context.Context, b *batch) error {
+	notifier, ok := d.notifiers[b.receiver]
+	if !ok {
+		return fmt.Errorf("unknown receiver: %s", b.receiver)
+	}
+
+	status := StateResolved
+	for _, alert := range b.alerts {
+		if alert.State == StateFiring {
+			status = StateFiring
+			break
+		}
+	}
+	n := &Notification{
+		GroupLabels: b.labels,
+		Receiver:    b.receiver,
+		GroupKey:    b.key,
+		Status:      status,
+		Alerts:      b.alerts,
+	}
+	if err := notifier.Notify(ctx, n); err != nil {
+		return fmt.Errorf("sending %s to receiver %s error: %w", b.key, b.receiver, err)
+	}
+	logger.Log.Info(
+		"Alert notification sent",
+		zap.String("receiver", b.receiver),
+		zap.String("group", b.key),
+		zap.String("status", string(status)),
+		zap.Int("alerts", len(b.alerts)),
+	)
+	return nil
+}
+
+func firingKeys(alerts []*Alert) string {
+	keys := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		if alert.State == StateFiring {
+			keys = append(keys, alert.Labels.String())
+		}
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "\n")
+}
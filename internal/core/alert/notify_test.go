@@ -0,0 +1,153 @@
+package alert
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"metrics/internal/core/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNotifier struct {
+	err  error
+	sent []*Notification
+}
+
+func (n *fakeNotifier) Notify(_ context.Context, notification *Notification) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func testAlert(host, instance string, state State) *Alert {
+	return &Alert{
+		Rule:   "HighHeap",
+		State:  state,
+		Labels: model.Labels{AlertNameLabel: "HighHeap", "host": host, "instance": instance},
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	rules := []*Rule{{
+		Name:           "HighHeap",
+		Receivers:      []string{"ops"},
+		GroupBy:        []string{"host"},
+		RepeatInterval: Duration(time.Hour),
+	}}
+	ops := &fakeNotifier{}
+	d := NewDispatcher(map[string]Notifier{"ops": ops})
+
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// pending alerts are not sent, alerts are grouped by host
+	alerts := []*Alert{
+		testAlert("a", "1", StateFiring),
+		testAlert("a", "2", StateFiring),
+		testAlert("b", "1", StatePending),
+	}
+	require.NoError(t, d.Dispatch(ctx, rules, alerts, now))
+	require.Len(t, ops.sent, 1)
+	assert.Equal(t, StateFiring, ops.sent[0].Status)
+	assert.Equal(t, `{}:{alertname="HighHeap",host="a"}`, ops.sent[0].GroupKey)
+	assert.Equal(t, model.Labels{AlertNameLabel: "HighHeap", "host": "a"}, ops.sent[0].GroupLabels)
+	assert.Len(t, ops.sent[0].Alerts, 2)
+
+	// nothing changed, repeat interval has not passed
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, d.Dispatch(ctx, rules, alerts, now))
+	assert.Len(t, ops.sent, 1)
+
+	// repeat interval has passed
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, d.Dispatch(ctx, rules, alerts, now))
+	assert.Len(t, ops.sent, 2)
+
+	// one of alerts is resolved, the group changed
+	now = now.Add(time.Minute)
+	alerts[1] = testAlert("a", "2", StateResolved)
+	require.NoError(t, d.Dispatch(ctx, rules, alerts, now))
+	require.Len(t, ops.sent, 3)
+	assert.Equal(t, StateFiring, ops.sent[2].Status)
+	assert.Len(t, ops.sent[2].Alerts, 2)
+
+	// all alerts are resolved, resolved notification is sent once
+	now = now.Add(time.Minute)
+	alerts = []*Alert{testAlert("a", "1", StateResolved)}
+	require.NoError(t, d.Dispatch(ctx, rules, alerts, now))
+	require.Len(t, ops.sent, 4)
+	assert.Equal(t, StateResolved, ops.sent[3].Status)
+
+	require.NoError(t, d.Dispatch(ctx, rules, alerts, now))
+	assert.Len(t, ops.sent, 4)
+	assert.Empty(t, d.groups)
+}
+
+func TestDispatcherRetriesFailedGroup(t *testing.T) {
+	rules := []*Rule{{Name: "HighHeap", Receivers: []string{"ops"}, RepeatInterval: DefaultRepeatInterval}}
+	ops := &fakeNotifier{err: assert.AnError}
+	d := NewDispatcher(map[string]Notifier{"ops": ops})
+
+	ctx := context.Background()
+	now := time.Now()
+	alerts := []*Alert{testAlert("a", "1", StateFiring)}
+
+	assert.ErrorIs(t, d.Dispatch(ctx, rules, alerts, now), assert.AnError)
+	assert.Empty(t, ops.sent)
+
+	ops.err = nil
+	require.NoError(t, d.Dispatch(ctx, rules, alerts, now.Add(time.Second)))
+	assert.Len(t, ops.sent, 1)
+}
+
+func TestDispatcherRetriesResolvedGroup(t *testing.T) {
+	rules := []*Rule{{Name: "HighHeap", Receivers: []string{"ops"}, RepeatInterval: DefaultRepeatInterval}}
+	ops := &fakeNotifier{}
+	d := NewDispatcher(map[string]Notifier{"ops": ops})
+
+	ctx := context.Background()
+	now := time.Now()
+	require.NoError(t, d.Dispatch(ctx, rules, []*Alert{testAlert("a", "1", StateFiring)}, now))
+	require.Len(t, ops.sent, 1)
+
+	// the resolved alert is sent once and removed by the engine, so it is not passed anymore
+	ops.err = assert.AnError
+	assert.ErrorIs(t, d.Dispatch(ctx, rules, []*Alert{testAlert("a", "1", StateResolved)}, now), assert.AnError)
+	assert.ErrorIs(t, d.Dispatch(ctx, rules, nil, now.Add(time.Second)), assert.AnError)
+
+	ops.err = nil
+	require.NoError(t, d.Dispatch(ctx, rules, nil, now.Add(2*time.Second)))
+	require.Len(t, ops.sent, 2)
+	assert.Equal(t, StateResolved, ops.sent[1].Status)
+	require.Len(t, ops.sent[1].Alerts, 1)
+	assert.Equal(t, StateResolved, ops.sent[1].Alerts[0].State)
+	assert.NotNil(t, ops.sent[1].Alerts[0].ResolvedAt)
+
+	require.NoError(t, d.Dispatch(ctx, rules, nil, now.Add(3*time.Second)))
+	assert.Len(t, ops.sent, 2)
+}
+
+func TestDispatcherRouting(t *testing.T) {
+	rules := []*Rule{
+		{Name: "HighHeap", Receivers: []string{"ops", "audit"}, RepeatInterval: DefaultRepeatInterval},
+		{Name: "NoPolls", Receivers: []string{"audit"}, RepeatInterval: DefaultRepeatInterval},
+		{Name: "Silent", RepeatInterval: DefaultRepeatInterval},
+	}
+	ops, audit := &fakeNotifier{}, &fakeNotifier{}
+	d := NewDispatcher(map[string]Notifier{"ops": ops, "audit": audit})
+
+	alerts := []*Alert{
+		{Rule: "HighHeap", State: StateFiring, Labels: model.Labels{AlertNameLabel: "HighHeap"}},
+		{Rule: "NoPolls", State: StateFiring, Labels: model.Labels{AlertNameLabel: "NoPolls"}},
+		{Rule: "Silent", State: StateFiring, Labels: model.Labels{AlertNameLabel: "Silent"}},
+	}
+	require.NoError(t, d.Dispatch(context.Background(), rules, alerts, time.Now()))
+	require.Len(t, ops.sent, 1)
+	assert.Equal(t, "HighHeap", ops.sent[0].Alerts[0].Rule)
+	assert.Len(t, audit.sent, 2)
+}
@@ -0,0 +1,207 @@
+// Package alert implements server-side alerting: rules are evaluated periodically against the metrics store
+// and produce alerts with pending, firing and resolved states.
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"metrics/internal/core/model"
+)
+
+// Duration is time.Duration which is read from JSON as a string like "90s" or "2m".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"2m\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Condition is a comparison operator of the threshold rule.
+type Condition string
+
+const (
+	Greater        Condition = ">"
+	GreaterOrEqual Condition = ">="
+	Less           Condition = "<"
+	LessOrEqual    Condition = "<="
+	Equal          Condition = "=="
+	NotEqual       Condition = "!="
+)
+
+// Check compares the value with the threshold.
+func (c Condition) Check(value, threshold float64) bool {
+	switch c {
+	case Greater:
+		return value > threshold
+	case GreaterOrEqual:
+		return value >= threshold
+	case Less:
+		return value < threshold
+	case LessOrEqual:
+		return value <= threshold
+	case Equal:
+		return value == threshold
+	case NotEqual:
+		return value != threshold
+	default:
+		return false
+	}
+}
+
+// Rule describes when an alert should be raised. There are two kinds of rules:
+//   - threshold: `HeapAlloc > 1e9 for 2m`, set Condition, Threshold and optionally For;
+//   - absence: `no PollCount update for 60s`, set AbsentFor. It requires the metric history of the store.
+//
+// The rule is applied to every stored metric with the given name and type which labels satisfy Match.
+//
+// Firing alerts of the rule are sent to Receivers. Alerts with equal values of GroupBy labels are sent
+// in one notification, which is repeated every RepeatInterval while the alerts are firing.
+type Rule struct {
+	Threshold      *float64          `json:"threshold,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Annotations    map[string]string `json:"annotations,omitempty"`
+	Name           string            `json:"name"`
+	Metric         string            `json:"metric"`
+	Type           model.MetricType  `json:"type"`
+	Condition      Condition         `json:"condition,omitempty"`
+	Match          []string          `json:"match,omitempty"`
+	Receivers      []string          `json:"receivers,omitempty"`
+	GroupBy        []string          `json:"group_by,omitempty"`
+	For            Duration          `json:"for,omitempty"`
+	AbsentFor      Duration          `json:"absent_for,omitempty"`
+	RepeatInterval Duration          `json:"repeat_interval,omitempty"`
+
+	matchers []*model.LabelMatcher
+}
+
+// IsAbsence reports whether the rule checks that the metric is not updated.
+func (r *Rule) IsAbsence() bool {
+	return r.AbsentFor > 0
+}
+
+// CheckHistory returns an error if the store keeps no metric history, but absence rules need it.
+func CheckHistory(rules []*Rule, history bool) error {
+	if history {
+		return nil
+	}
+	for _, rule := range rules {
+		if rule.IsAbsence() {
+			return fmt.Errorf("rule %s: absent_for requires the metric history, set the memory store history size", rule.Name)
+		}
+	}
+	return nil
+}
+
+// Validate checks the rule and prepares label matchers.
+func (r *Rule) Validate() error {
+	if r.Name == "" {
+		return errors.New("rule name is required")
+	}
+	if r.Metric == "" {
+		return fmt.Errorf("rule %s: metric is required", r.Name)
+	}
+	if r.Type != model.GaugeType && r.Type != model.CounterType {
+		return fmt.Errorf("rule %s: unsupported metric type: %s", r.Name, r.Type)
+	}
+	if r.IsAbsence() {
+		if r.Threshold != nil {
+			return fmt.Errorf("rule %s: absent_for could not be used with threshold", r.Name)
+		}
+	} else {
+		if r.Threshold == nil {
+			return fmt.Errorf("rule %s: threshold or absent_for is required", r.Name)
+		}
+		switch r.Condition {
+		case Greater, GreaterOrEqual, Less, LessOrEqual, Equal, NotEqual:
+		default:
+			return fmt.Errorf("rule %s: unknown condition: %q", r.Name, r.Condition)
+		}
+	}
+	if r.For < 0 {
+		return fmt.Errorf("rule %s: for could not be negative", r.Name)
+	}
+	if r.RepeatInterval < 0 {
+		return fmt.Errorf("rule %s: repeat_interval could not be negative", r.Name)
+	}
+	if r.RepeatInterval == 0 {
+		r.RepeatInterval = DefaultRepeatInterval
+	}
+
+	matchers, err := model.ParseLabelMatchers(r.Match)
+	if err != nil {
+		return fmt.Errorf("rule %s: %w", r.Name, err)
+	}
+	r.matchers = matchers
+	return nil
+}
+
+// RulesFile is a content of the alerting rules file.
+// DefaultReceivers are used for rules without own receivers.
+type RulesFile struct {
+	Receivers        []*ReceiverConfig `json:"receivers,omitempty"`
+	DefaultReceivers []string          `json:"default_receivers,omitempty"`
+	Rules            []*Rule           `json:"rules"`
+}
+
+// LoadRules reads and validates rules and receivers from the JSON file. Empty path means no rules.
+func LoadRules(path string) (*RulesFile, error) {
+	if path == "" {
+		return &RulesFile{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading alerting rules file error: %w", err)
+	}
+	var file RulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("unmarshal alerting rules error: %w", err)
+	}
+
+	receivers := make(map[string]bool, len(file.Receivers))
+	for _, receiver := range file.Receivers {
+		if err := receiver.Validate(); err != nil {
+			return nil, err
+		}
+		if receivers[receiver.Name] {
+			return nil, fmt.Errorf("duplicate receiver name: %s", receiver.Name)
+		}
+		receivers[receiver.Name] = true
+	}
+
+	names := make(map[string]bool, len(file.Rules))
+	for _, rule := range file.Rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("duplicate rule name: %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if len(rule.Receivers) == 0 {
+			rule.Receivers = file.DefaultReceivers
+		}
+		for _, name := range rule.Receivers {
+			if !receivers[name] {
+				return nil, fmt.Errorf("rule %s: unknown receiver: %s", rule.Name, name)
+			}
+		}
+	}
+	return &file, nil
+}
@@ -0,0 +1,132 @@
+package alert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"metrics/internal/core/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name: "threshold and absence rules",
+			content: `{
+				"receivers": [
+					{"name": "ops", "webhook": {"url": "http://localhost:9093/hook"}},
+					{"name": "audit", "file": {"path": "/tmp/alerts.ndjson"}}
+				],
+				"default_receivers": ["ops"],
+				"rules": [
+					{"name": "HighHeap", "metric": "HeapAlloc", "type": "gauge", "condition": ">", "threshold": 1e9, "for": "2m"},
+					{
+						"name": "NoPolls", "metric": "PollCount", "type": "counter", "absent_for": "60s", "match": ["host=a"],
+						"receivers": ["audit"], "repeat_interval": "1h"
+					}
+				]
+			}`,
+		},
+		{
+			name:    "unknown condition",
+			content: `{"rules": [{"name": "r", "metric": "m", "type": "gauge", "condition": "<>", "threshold": 1}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "no threshold",
+			content: `{"rules": [{"name": "r", "metric": "m", "type": "gauge", "condition": ">"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "histogram is not supported",
+			content: `{"rules": [{"name": "r", "metric": "m", "type": "histogram", "absent_for": "1m"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid duration",
+			content: `{"rules": [{"name": "r", "metric": "m", "type": "gauge", "condition": ">", "threshold": 1, "for": "2"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid matcher",
+			content: `{"rules": [{"name": "r", "metric": "m", "type": "gauge", "absent_for": "1m", "match": ["host"]}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown receiver",
+			content: `{"rules": [{"name": "r", "metric": "m", "type": "gauge", "absent_for": "1m", "receivers": ["ops"]}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "receiver without channel",
+			content: `{"receivers": [{"name": "ops"}], "rules": []}`,
+			wantErr: true,
+		},
+		{
+			name:    "receiver with two channels",
+			content: `{"receivers": [{"name": "ops", "file": {"path": "a"}, "webhook": {"url": "b"}}], "rules": []}`,
+			wantErr: true,
+		},
+		{
+			name: "duplicate name",
+			content: `{"rules": [
+				{"name": "r", "metric": "m", "type": "gauge", "absent_for": "1m"},
+				{"name": "r", "metric": "m2", "type": "gauge", "absent_for": "1m"}
+			]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			file, err := LoadRules(path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			rules := file.Rules
+			require.Len(t, rules, 2)
+
+			assert.False(t, rules[0].IsAbsence())
+			assert.Equal(t, 1e9, *rules[0].Threshold)
+			assert.Equal(t, Duration(2*time.Minute), rules[0].For)
+
+			assert.True(t, rules[1].IsAbsence())
+			assert.Equal(t, model.CounterType, rules[1].Type)
+			assert.Equal(t, Duration(time.Minute), rules[1].AbsentFor)
+			assert.Len(t, rules[1].matchers, 1)
+
+			assert.Equal(t, []string{"ops"}, rules[0].Receivers)
+			assert.Equal(t, DefaultRepeatInterval, rules[0].RepeatInterval)
+			assert.Equal(t, []string{"audit"}, rules[1].Receivers)
+			assert.Equal(t, Duration(time.Hour), rules[1].RepeatInterval)
+		})
+	}
+}
+
+func TestLoadRulesEmptyPath(t *testing.T) {
+	file, err := LoadRules("")
+	require.NoError(t, err)
+	assert.Empty(t, file.Rules)
+}
+
+func TestCheckHistory(t *testing.T) {
+	rules := []*Rule{
+		{Name: "HighHeap", Threshold: new(float64)},
+		{Name: "NoPolls", AbsentFor: Duration(time.Minute)},
+	}
+	assert.NoError(t, CheckHistory(rules, true))
+	assert.ErrorContains(t, CheckHistory(rules, false), "rule NoPolls: absent_for requires the metric history")
+	assert.NoError(t, CheckHistory(rules[:1], false))
+}
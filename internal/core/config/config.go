@@ -14,15 +14,27 @@ import (
 )
 
 type ServerConfig struct {
-	Address  string
-	LogLevel string
+	Address               string
+	GRPCAddress           string // empty - gRPC API is disabled
+	StatsDAddress         string // UDP address, empty - StatsD listener is disabled
+	GraphiteAddress       string // TCP address, empty - Graphite listener is disabled
+	LogLevel              string
+	StatsDFlushInterval   int64 // seconds
+	GraphiteFlushInterval int64 // seconds
 }
 
 type StorageConfig struct {
-	FileStoragePath string
-	DatabaseDSN     string
-	StoreIntreval   int64
-	Restore         bool
+	FileStoragePath  string
+	DatabaseDSN      string
+	StoreIntreval    int64
+	HistoryRetention int64 // seconds, 0 - keep samples until they are overwritten
+	HistorySize      int   // amount of samples kept per metric by memory store, 0 - disabled
+	Restore          bool
+}
+
+type AlertingConfig struct {
+	RulesFile          string // JSON file with alerting rules, empty - alerting is disabled
+	EvaluationInterval int64  // seconds
 }
 
 type Config struct {
@@ -30,17 +42,27 @@ type Config struct {
 	HashKey   string
 	CryptoKey string
 	Storage   StorageConfig
+	Alerting  AlertingConfig
 }
 
 type JSONConfig struct {
-	Address         *string `json:"address,omitempty"`
-	LogLevel        *string `json:"log_level,omitempty"`
-	HashKey         *string `json:"key,omitempty"`
-	CryptoKey       *string `json:"crypto_key,omitempty"`
-	FileStoragePath *string `json:"file_storage_path,omitempty"`
-	DatabaseDSN     *string `json:"database_dsn,omitempty"`
-	StoreIntreval   *int64  `json:"store_interval,omitempty"`
-	Restore         *bool   `json:"restore,omitempty"`
+	Address          *string `json:"address,omitempty"`
+	GRPCAddress      *string `json:"grpc_address,omitempty"`
+	StatsDAddress    *string `json:"statsd_address,omitempty"`
+	StatsDFlush      *int64  `json:"statsd_flush_interval,omitempty"`
+	GraphiteAddress  *string `json:"graphite_address,omitempty"`
+	GraphiteFlush    *int64  `json:"graphite_flush_interval,omitempty"`
+	LogLevel         *string `json:"log_level,omitempty"`
+	HashKey          *string `json:"key,omitempty"`
+	CryptoKey        *string `json:"crypto_key,omitempty"`
+	FileStoragePath  *string `json:"file_storage_path,omitempty"`
+	DatabaseDSN      *string `json:"database_dsn,omitempty"`
+	StoreIntreval    *int64  `json:"store_interval,omitempty"`
+	Restore          *bool   `json:"restore,omitempty"`
+	HistorySize      *int    `json:"history_size,omitempty"`
+	HistoryRetention *int64  `json:"history_retention,omitempty"`
+	AlertRules       *string `json:"alert_rules,omitempty"`
+	AlertInterval    *int64  `json:"alert_interval,omitempty"`
 }
 
 func loadJSONConfig(path string) (cfg *JSONConfig, err error) {
@@ -61,14 +83,25 @@ func NewConfig() (*Config, error) {
 	// Create config with default values
 	cfg := Config{
 		Server: ServerConfig{
-			Address:  "localhost:8080",
-			LogLevel: "info",
+			Address:               "localhost:8080",
+			GRPCAddress:           "",
+			StatsDAddress:         "",
+			GraphiteAddress:       "",
+			LogLevel:              "info",
+			StatsDFlushInterval:   10,
+			GraphiteFlushInterval: 10,
 		},
 		Storage: StorageConfig{
-			FileStoragePath: "/tmp/metrics-db.json",
-			DatabaseDSN:     "",
-			StoreIntreval:   300,
-			Restore:         true,
+			FileStoragePath:  "/tmp/metrics-db.json",
+			DatabaseDSN:      "",
+			StoreIntreval:    300,
+			Restore:          true,
+			HistorySize:      120,
+			HistoryRetention: 0,
+		},
+		Alerting: AlertingConfig{
+			RulesFile:          "",
+			EvaluationInterval: 15,
 		},
 		HashKey:   "",
 		CryptoKey: "",
@@ -76,13 +109,26 @@ func NewConfig() (*Config, error) {
 
 	// Read commant args to serparate variables
 	var jsonCfgPath, jsonCfgPathFull string
-	var serverAddress, serverLogLevel string
+	var serverAddress, serverGRPCAddress, serverLogLevel string
 	var storageStoreIntreval int64
 	var storageFileStoragePath, storageDatabaseDSN string
 	var storageRestore bool
 	var hashKey, cryptoKey string
+	var historySize int
+	var historyRetention int64
+	var alertRules string
+	var alertInterval int64
+	var statsdAddress string
+	var statsdFlush int64
+	var graphiteAddress string
+	var graphiteFlush int64
 
 	flag.StringVar(&serverAddress, "a", "", "address and port to run server")
+	flag.StringVar(&serverGRPCAddress, "grpc-address", "", "address and port to run gRPC server, e.g. localhost:3200")
+	flag.StringVar(&statsdAddress, "statsd-address", "", "UDP address and port to receive StatsD metrics")
+	flag.Int64Var(&statsdFlush, "statsd-flush-interval", 0, "StatsD metrics flush interval in seconds")
+	flag.StringVar(&graphiteAddress, "graphite-address", "", "TCP address and port to receive Graphite metrics")
+	flag.Int64Var(&graphiteFlush, "graphite-flush-interval", 0, "Graphite metrics flush interval in seconds")
 	flag.StringVar(&serverLogLevel, "l", "", "Log levle: debug, info, warn, error, panic, fatal")
 	flag.Int64Var(&storageStoreIntreval, "i", 0, "Dump DB to file with given interval. 0 - means to write all changes immediately")
 	flag.StringVar(&storageFileStoragePath, "f", "", "Path to dump file")
@@ -90,6 +136,10 @@ func NewConfig() (*Config, error) {
 	flag.StringVar(&storageDatabaseDSN, "d", "", "Database connection string")
 	flag.StringVar(&hashKey, "k", "", "Hash key to check request signature")
 	flag.StringVar(&cryptoKey, "crypto-key", "", "Path to private key")
+	flag.IntVar(&historySize, "history-size", -1, "Amount of samples kept per metric by memory store. 0 - disabled")
+	flag.Int64Var(&historyRetention, "history-retention", 0, "Max age of history samples in seconds. 0 - unlimited")
+	flag.StringVar(&alertRules, "alert-rules", "", "Path to alerting rules file")
+	flag.Int64Var(&alertInterval, "alert-interval", 0, "Alerting rules evaluation interval in seconds")
 	flag.StringVar(&jsonCfgPath, "с", "", "json configuration file")
 	flag.StringVar(&jsonCfgPathFull, "config", "", "json configuration file")
 
@@ -117,6 +167,65 @@ func NewConfig() (*Config, error) {
 		cfg.Server.Address = *jsonCfg.Address
 	}
 
+	// GRPC_ADDRESS
+	if value, exists := os.LookupEnv("GRPC_ADDRESS"); exists {
+		cfg.Server.GRPCAddress = value
+	} else if serverGRPCAddress != "" {
+		cfg.Server.GRPCAddress = serverGRPCAddress
+	} else if jsonCfg != nil && jsonCfg.GRPCAddress != nil {
+		cfg.Server.GRPCAddress = *jsonCfg.GRPCAddress
+	}
+
+	// STATSD_ADDRESS
+	if value, exists := os.LookupEnv("STATSD_ADDRESS"); exists {
+		cfg.Server.StatsDAddress = value
+	} else if statsdAddress != "" {
+		cfg.Server.StatsDAddress = statsdAddress
+	} else if jsonCfg != nil && jsonCfg.StatsDAddress != nil {
+		cfg.Server.StatsDAddress = *jsonCfg.StatsDAddress
+	}
+
+	// STATSD_FLUSH_INTERVAL
+	if value, exists := os.LookupEnv("STATSD_FLUSH_INTERVAL"); exists {
+		interval, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("STATSD_FLUSH_INTERVAL convertation error: %w", err)
+		}
+		cfg.Server.StatsDFlushInterval = interval
+	} else if statsdFlush != 0 {
+		cfg.Server.StatsDFlushInterval = statsdFlush
+	} else if jsonCfg != nil && jsonCfg.StatsDFlush != nil {
+		cfg.Server.StatsDFlushInterval = *jsonCfg.StatsDFlush
+	}
+	if cfg.Server.StatsDFlushInterval <= 0 {
+		return nil, fmt.Errorf("StatsD flush interval must be positive, got %d", cfg.Server.StatsDFlushInterval)
+	}
+
+	// GRAPHITE_ADDRESS
+	if value, exists := os.LookupEnv("GRAPHITE_ADDRESS"); exists {
+		cfg.Server.GraphiteAddress = value
+	} else if graphiteAddress != "" {
+		cfg.Server.GraphiteAddress = graphiteAddress
+	} else if jsonCfg != nil && jsonCfg.GraphiteAddress != nil {
+		cfg.Server.GraphiteAddress = *jsonCfg.GraphiteAddress
+	}
+
+	// GRAPHITE_FLUSH_INTERVAL
+	if value, exists := os.LookupEnv("GRAPHITE_FLUSH_INTERVAL"); exists {
+		interval, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("GRAPHITE_FLUSH_INTERVAL convertation error: %w", err)
+		}
+		cfg.Server.GraphiteFlushInterval = interval
+	} else if graphiteFlush != 0 {
+		cfg.Server.GraphiteFlushInterval = graphiteFlush
+	} else if jsonCfg != nil && jsonCfg.GraphiteFlush != nil {
+		cfg.Server.GraphiteFlushInterval = *jsonCfg.GraphiteFlush
+	}
+	if cfg.Server.GraphiteFlushInterval <= 0 {
+		return nil, fmt.Errorf("graphite flush interval must be positive, got %d", cfg.Server.GraphiteFlushInterval)
+	}
+
 	// LOG_LEVEL
 	if value, exists := os.LookupEnv("LOG_LEVEL"); exists {
 		cfg.Server.LogLevel = value
@@ -170,6 +279,57 @@ func NewConfig() (*Config, error) {
 		cfg.Storage.DatabaseDSN = *jsonCfg.DatabaseDSN
 	}
 
+	// HISTORY_SIZE
+	if value, exists := os.LookupEnv("HISTORY_SIZE"); exists {
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("HISTORY_SIZE convertation error: %w", err)
+		}
+		cfg.Storage.HistorySize = size
+	} else if historySize >= 0 {
+		cfg.Storage.HistorySize = historySize
+	} else if jsonCfg != nil && jsonCfg.HistorySize != nil {
+		cfg.Storage.HistorySize = *jsonCfg.HistorySize
+	}
+
+	// HISTORY_RETENTION
+	if value, exists := os.LookupEnv("HISTORY_RETENTION"); exists {
+		retention, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("HISTORY_RETENTION convertation error: %w", err)
+		}
+		cfg.Storage.HistoryRetention = retention
+	} else if historyRetention != 0 {
+		cfg.Storage.HistoryRetention = historyRetention
+	} else if jsonCfg != nil && jsonCfg.HistoryRetention != nil {
+		cfg.Storage.HistoryRetention = *jsonCfg.HistoryRetention
+	}
+
+	// ALERT_RULES
+	if value, exists := os.LookupEnv("ALERT_RULES"); exists {
+		cfg.Alerting.RulesFile = value
+	} else if alertRules != "" {
+		cfg.Alerting.RulesFile = alertRules
+	} else if jsonCfg != nil && jsonCfg.AlertRules != nil {
+		cfg.Alerting.RulesFile = *jsonCfg.AlertRules
+	}
+
+	// ALERT_INTERVAL
+	if value, exists := os.LookupEnv("ALERT_INTERVAL"); exists {
+		interval, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("ALERT_INTERVAL convertation error: %w", err)
+		}
+		cfg.Alerting.EvaluationInterval = interval
+	} else if alertInterval != 0 {
+		cfg.Alerting.EvaluationInterval = alertInterval
+	} else if jsonCfg != nil && jsonCfg.AlertInterval != nil {
+		cfg.Alerting.EvaluationInterval = *jsonCfg.AlertInterval
+	}
+	if cfg.Alerting.EvaluationInterval <= 0 {
+		return nil, fmt.Errorf("alerting evaluation interval must be positive, got %d", cfg.Alerting.EvaluationInterval)
+	}
+
 	// KEY
 	if value, exists := os.LookupEnv("KEY"); exists && value != "" {
 		cfg.HashKey = value
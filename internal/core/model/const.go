@@ -7,6 +7,7 @@ func (mt *MetricType) String() string {
 }
 
 const (
-	GaugeType   MetricType = "gauge"
-	CounterType MetricType = "counter"
+	GaugeType     MetricType = "gauge"
+	CounterType   MetricType = "counter"
+	HistogramType MetricType = "histogram"
 )
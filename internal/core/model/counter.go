@@ -6,12 +6,18 @@ import (
 )
 
 type Counter struct {
-	Name  string
-	Value int64
+	Labels Labels
+	Name   string
+	Value  int64
 }
 
-func NewCounter(name string) *Counter {
-	return &Counter{Name: name}
+func NewCounter(name string, labels Labels) *Counter {
+	return &Counter{Name: name, Labels: labels.Copy()}
+}
+
+// Key returns the storage identity of the counter.
+func (c *Counter) Key() string {
+	return MetricKey(c.Name, c.Labels)
 }
 
 func (c *Counter) Type() MetricType {
@@ -5,12 +5,18 @@ import (
 )
 
 type Gauge struct {
-	Name  string
-	Value float64
+	Labels Labels
+	Name   string
+	Value  float64
 }
 
-func NewGauge(name string) *Gauge {
-	return &Gauge{Name: name}
+func NewGauge(name string, labels Labels) *Gauge {
+	return &Gauge{Name: name, Labels: labels.Copy()}
+}
+
+// Key returns the storage identity of the gauge.
+func (g *Gauge) Key() string {
+	return MetricKey(g.Name, g.Labels)
 }
 
 func (g *Gauge) Type() MetricType {
@@ -0,0 +1,167 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultHistogramBuckets are upper bounds used when a histogram is created without explicit buckets.
+var DefaultHistogramBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// HistogramValue describes histogram data in API requests and responses.
+// Counts are per bucket (not cumulative), the last one counts values above the highest bound (+Inf).
+type HistogramValue struct {
+	Buckets []float64 `json:"buckets"`
+	Counts  []int64   `json:"counts"`
+	Sum     float64   `json:"sum"`
+}
+
+// Validate checks that bounds are sorted and counts match them.
+func (hv *HistogramValue) Validate() error {
+	for i := 1; i < len(hv.Buckets); i++ {
+		if hv.Buckets[i] <= hv.Buckets[i-1] {
+			return errors.New("histogram buckets must be sorted in ascending order")
+		}
+	}
+	if len(hv.Counts) != len(hv.Buckets)+1 {
+		return fmt.Errorf(
+			"histogram must have %d counts for %d buckets, got %d", len(hv.Buckets)+1, len(hv.Buckets), len(hv.Counts),
+		)
+	}
+	for _, c := range hv.Counts {
+		if c < 0 {
+			return fmt.Errorf("histogram bucket count could not be negative (%d)", c)
+		}
+	}
+	return nil
+}
+
+type Histogram struct {
+	Labels  Labels
+	Name    string
+	Buckets []float64
+	Counts  []int64
+	Sum     float64
+}
+
+// NewHistogram creates empty histogram. DefaultHistogramBuckets are used if buckets is nil.
+func NewHistogram(name string, labels Labels, buckets []float64) *Histogram {
+	if buckets == nil {
+		buckets = DefaultHistogramBuckets
+	}
+	b := make([]float64, len(buckets))
+	copy(b, buckets)
+	return &Histogram{
+		Name:    name,
+		Labels:  labels.Copy(),
+		Buckets: b,
+		Counts:  make([]int64, len(b)+1),
+	}
+}
+
+// NewHistogramFromRequest creates empty histogram with buckets taken from the request if it has them.
+func NewHistogramFromRequest(req *MetricsV2) *Histogram {
+	if req.Histogram != nil {
+		return NewHistogram(req.ID, req.Labels, req.Histogram.Buckets)
+	}
+	return NewHistogram(req.ID, req.Labels, nil)
+}
+
+// Key returns the storage identity of the histogram.
+func (h *Histogram) Key() string {
+	return MetricKey(h.Name, h.Labels)
+}
+
+func (h *Histogram) Type() MetricType {
+	return HistogramType
+}
+
+// Count returns total amount of observations.
+func (h *Histogram) Count() int64 {
+	var total int64
+	for _, c := range h.Counts {
+		total += c
+	}
+	return total
+}
+
+func (h *Histogram) StringValue() string {
+	items := make([]string, 0, len(h.Counts))
+	for i, c := range h.Counts {
+		bound := "+Inf"
+		if i < len(h.Buckets) {
+			bound = strconv.FormatFloat(h.Buckets[i], 'f', -1, 64)
+		}
+		items = append(items, bound+":"+strconv.FormatInt(c, 10))
+	}
+	return fmt.Sprintf(
+		"count=%d sum=%s buckets=[%s]",
+		h.Count(), strconv.FormatFloat(h.Sum, 'f', -1, 64), strings.Join(items, " "),
+	)
+}
+
+// Observe adds a single value to the histogram.
+func (h *Histogram) Observe(value float64) {
+	i := 0
+	for i < len(h.Buckets) && value > h.Buckets[i] {
+		i++
+	}
+	h.Counts[i]++
+	h.Sum += value
+}
+
+// Merge adds bucket counts and sum of the given value, like a counter adds its delta.
+// Buckets of the value must be equal to the histogram buckets.
+func (h *Histogram) Merge(value *HistogramValue) error {
+	if err := value.Validate(); err != nil {
+		return err
+	}
+	if !equalBuckets(h.Buckets, value.Buckets) {
+		return fmt.Errorf("histogram (%s) buckets %v do not match stored buckets %v", h.Name, value.Buckets, h.Buckets)
+	}
+	for i, c := range value.Counts {
+		h.Counts[i] += c
+	}
+	h.Sum += value.Sum
+	return nil
+}
+
+// Apply updates histogram with request data: bucket counts are merged if req.Histogram is set,
+// otherwise req.Value is observed as a single value.
+func (h *Histogram) Apply(req *MetricsV2) error {
+	switch {
+	case req.Histogram != nil:
+		return h.Merge(req.Histogram)
+	case req.Value != nil:
+		h.Observe(*req.Value)
+		return nil
+	default:
+		return errors.New("incorrect value")
+	}
+}
+
+// Payload returns a copy of histogram data for API responses.
+func (h *Histogram) Payload() *HistogramValue {
+	hv := &HistogramValue{
+		Buckets: make([]float64, len(h.Buckets)),
+		Counts:  make([]int64, len(h.Counts)),
+		Sum:     h.Sum,
+	}
+	copy(hv.Buckets, h.Buckets)
+	copy(hv.Counts, h.Counts)
+	return hv
+}
+
+func equalBuckets(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
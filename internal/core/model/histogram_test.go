@@ -0,0 +1,73 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramObserve(t *testing.T) {
+	h := NewHistogram("test", nil, []float64{1, 5, 10})
+
+	for _, v := range []float64{0.5, 1, 3, 7, 100} {
+		h.Observe(v)
+	}
+
+	assert.Equal(t, []int64{2, 1, 1, 1}, h.Counts)
+	assert.Equal(t, int64(5), h.Count())
+	assert.InEpsilon(t, 111.5, h.Sum, 0.0001)
+}
+
+func TestHistogramMerge(t *testing.T) {
+	h := NewHistogram("test", nil, []float64{1, 5})
+
+	err := h.Merge(&HistogramValue{Buckets: []float64{1, 5}, Counts: []int64{1, 2, 3}, Sum: 10})
+	require.NoError(t, err)
+	err = h.Merge(&HistogramValue{Buckets: []float64{1, 5}, Counts: []int64{1, 0, 1}, Sum: 7})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int64{2, 2, 4}, h.Counts)
+	assert.InEpsilon(t, 17.0, h.Sum, 0.0001)
+}
+
+func TestHistogramMergeFailed(t *testing.T) {
+	tests := []struct {
+		value *HistogramValue
+		name  string
+	}{
+		{
+			name:  "different buckets",
+			value: &HistogramValue{Buckets: []float64{1, 10}, Counts: []int64{1, 1, 1}},
+		},
+		{
+			name:  "counts length",
+			value: &HistogramValue{Buckets: []float64{1, 5}, Counts: []int64{1, 1}},
+		},
+		{
+			name:  "negative count",
+			value: &HistogramValue{Buckets: []float64{1, 5}, Counts: []int64{1, -1, 1}},
+		},
+		{
+			name:  "unsorted buckets",
+			value: &HistogramValue{Buckets: []float64{5, 1}, Counts: []int64{1, 1, 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHistogram("test", nil, []float64{1, 5})
+			err := h.Merge(tt.value)
+			assert.Error(t, err)
+			assert.Equal(t, []int64{0, 0, 0}, h.Counts)
+		})
+	}
+}
+
+func TestHistogramStringValue(t *testing.T) {
+	h := NewHistogram("test", nil, []float64{0.5, 1})
+	h.Observe(0.1)
+	h.Observe(2)
+
+	assert.Equal(t, "count=2 sum=2.1 buckets=[0.5:1 1:0 +Inf:1]", h.StringValue())
+}
@@ -0,0 +1,223 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrAmbiguousMetric is returned when label matchers select more than one metric where only one is expected.
+var ErrAmbiguousMetric = errors.New("label matchers select more than one metric")
+
+// Labels is an optional set of metric dimensions (host, region, etc).
+// A metric is identified by its name together with the labels.
+type Labels map[string]string
+
+// Names returns sorted label names.
+func (l Labels) Names() []string {
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String returns canonical representation of the labels sorted by name: `env="prod",host="a"`.
+// It is used as a part of the metric storage identity.
+func (l Labels) String() string {
+	var sb strings.Builder
+	for i, name := range l.Names() {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(l[name]))
+	}
+	return sb.String()
+}
+
+// Copy returns a copy of the labels, nil labels stay nil.
+func (l Labels) Copy() Labels {
+	if l == nil {
+		return nil
+	}
+	res := make(Labels, len(l))
+	for k, v := range l {
+		res[k] = v
+	}
+	return res
+}
+
+// ParseLabels parses labels from the canonical representation made by Labels.String.
+func ParseLabels(s string) (Labels, error) {
+	if s == "" {
+		return nil, nil
+	}
+	labels := Labels{}
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("could not parse labels, label name expected: %q", s)
+		}
+		name := s[:eq]
+		quoted, err := strconv.QuotedPrefix(s[eq+1:])
+		if err != nil {
+			return nil, fmt.Errorf("could not parse labels, quoted value expected for %s: %w", name, err)
+		}
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse label %s value: %w", name, err)
+		}
+		labels[name] = value
+
+		s = s[eq+1+len(quoted):]
+		if s != "" {
+			if s[0] != ',' {
+				return nil, fmt.Errorf("could not parse labels, comma expected: %q", s)
+			}
+			s = s[1:]
+		}
+	}
+	return labels, nil
+}
+
+// MetricKey builds the storage identity of a metric: name and sorted label set, e.g. `HeapAlloc{host="a"}`.
+// Metrics without labels are identified by the name only.
+func MetricKey(name string, labels Labels) string {
+	if len(labels) == 0 {
+		return name
+	}
+	return name + "{" + labels.String() + "}"
+}
+
+// ParseMetricKey parses the metric identity made by MetricKey: `HeapAlloc` or `HeapAlloc{host="a"}`.
+func ParseMetricKey(s string) (string, Labels, error) {
+	i := strings.IndexByte(s, '{')
+	if i < 0 {
+		return s, nil, nil
+	}
+	if i == 0 || !strings.HasSuffix(s, "}") {
+		return "", nil, fmt.Errorf("could not parse metric: %q", s)
+	}
+	labels, err := ParseLabels(s[i+1 : len(s)-1])
+	if err != nil {
+		return "", nil, err
+	}
+	return s[:i], labels, nil
+}
+
+// MatchType is an operator of the label matcher.
+type MatchType string
+
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+// LabelMatcher filters metrics by label value, the same way as Prometheus selectors do.
+// Missing label is treated as a label with empty value.
+type LabelMatcher struct {
+	re    *regexp.Regexp
+	Name  string
+	Type  MatchType
+	Value string
+}
+
+// NewLabelMatcher creates matcher, regexp values are anchored to match the whole label value.
+func NewLabelMatcher(name string, mType MatchType, value string) (*LabelMatcher, error) {
+	m := &LabelMatcher{Name: name, Type: mType, Value: value}
+	switch mType {
+	case MatchEqual, MatchNotEqual:
+	case MatchRegexp, MatchNotRegexp:
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid label matcher regexp %q: %w", value, err)
+		}
+		m.re = re
+	default:
+		return nil, fmt.Errorf("unknown label match type: %s", mType)
+	}
+	return m, nil
+}
+
+// ParseLabelMatcher parses matchers like `host=a`, `host!=a`, `host=~web-.*` or `host!~web-.*`.
+// The value could be quoted.
+func ParseLabelMatcher(s string) (*LabelMatcher, error) {
+	i := strings.IndexAny(s, "=!")
+	if i <= 0 {
+		return nil, fmt.Errorf("could not parse label matcher: %q", s)
+	}
+	name, rest := strings.TrimSpace(s[:i]), s[i:]
+
+	var mType MatchType
+	for _, t := range []MatchType{MatchRegexp, MatchNotRegexp, MatchNotEqual, MatchEqual} {
+		if strings.HasPrefix(rest, string(t)) {
+			mType = t
+			break
+		}
+	}
+	if mType == "" {
+		return nil, fmt.Errorf("could not parse label matcher operator: %q", s)
+	}
+
+	value := strings.TrimSpace(rest[len(mType):])
+	if strings.HasPrefix(value, `"`) {
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse label matcher value: %w", err)
+		}
+		value = unquoted
+	}
+	return NewLabelMatcher(name, mType, value)
+}
+
+// ParseLabelMatchers parses a list of matchers, see ParseLabelMatcher.
+func ParseLabelMatchers(items []string) ([]*LabelMatcher, error) {
+	matchers := make([]*LabelMatcher, 0, len(items))
+	for _, item := range items {
+		m, err := ParseLabelMatcher(item)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+func (m *LabelMatcher) String() string {
+	return m.Name + string(m.Type) + strconv.Quote(m.Value)
+}
+
+// Matches checks the labels satisfy the matcher.
+func (m *LabelMatcher) Matches(labels Labels) bool {
+	value := labels[m.Name]
+	switch m.Type {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(value)
+	case MatchNotRegexp:
+		return !m.re.MatchString(value)
+	default:
+		return false
+	}
+}
+
+// MatchLabels checks the labels satisfy all matchers.
+func MatchLabels(labels Labels, matchers []*LabelMatcher) bool {
+	for _, m := range matchers {
+		if !m.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
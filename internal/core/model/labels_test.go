@@ -0,0 +1,75 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelsString(t *testing.T) {
+	labels := Labels{"region": "eu", "host": `a"b`}
+	assert.Equal(t, `host="a\"b",region="eu"`, labels.String())
+	assert.Equal(t, `HeapAlloc{host="a\"b",region="eu"}`, MetricKey("HeapAlloc", labels))
+	assert.Equal(t, "HeapAlloc", MetricKey("HeapAlloc", nil))
+
+	parsed, err := ParseLabels(labels.String())
+	require.NoError(t, err)
+	assert.Equal(t, labels, parsed)
+
+	parsed, err = ParseLabels("")
+	require.NoError(t, err)
+	assert.Nil(t, parsed)
+
+	_, err = ParseLabels(`host=a`)
+	require.Error(t, err)
+}
+
+func TestParseMetricKey(t *testing.T) {
+	name, labels, err := ParseMetricKey(`HeapAlloc{host="a",region="eu"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "HeapAlloc", name)
+	assert.Equal(t, Labels{"host": "a", "region": "eu"}, labels)
+
+	name, labels, err = ParseMetricKey("HeapAlloc")
+	require.NoError(t, err)
+	assert.Equal(t, "HeapAlloc", name)
+	assert.Nil(t, labels)
+
+	for _, key := range []string{`{host="a"}`, `HeapAlloc{host="a"`, `HeapAlloc{host=a}`} {
+		_, _, err = ParseMetricKey(key)
+		require.Error(t, err, key)
+	}
+}
+
+func TestLabelMatcher(t *testing.T) {
+	labels := Labels{"host": "web-1", "env": "prod"}
+
+	tests := []struct {
+		matcher string
+		matches bool
+	}{
+		{matcher: "host=web-1", matches: true},
+		{matcher: `host="web-1"`, matches: true},
+		{matcher: "host=web-2", matches: false},
+		{matcher: "host!=web-2", matches: true},
+		{matcher: "host=~web-.*", matches: true},
+		{matcher: "host=~web", matches: false},
+		{matcher: "host!~db-.*", matches: true},
+		{matcher: "region=", matches: true},
+		{matcher: "region!=", matches: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.matcher, func(t *testing.T) {
+			m, err := ParseLabelMatcher(tt.matcher)
+			require.NoError(t, err)
+			assert.Equal(t, tt.matches, m.Matches(labels))
+		})
+	}
+
+	for _, invalid := range []string{"host", "=a", "host=~(", "host!a"} {
+		_, err := ParseLabelMatcher(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -1,14 +1,18 @@
 package model
 
+import "time"
+
 type MetricRequest struct {
 	Name string     `uri:"name" binding:"required"`
-	Type MetricType `uri:"type" binding:"required" oneof:"gauge counter"`
+	Type MetricType `uri:"type" binding:"required" oneof:"gauge counter histogram"`
 }
 
 type MetricResponse struct {
-	Name  string
-	Type  MetricType
-	Value string
+	Labels    Labels
+	Histogram *HistogramValue // bucket counts, set for histograms only
+	Name      string
+	Type      MetricType
+	Value     string
 }
 
 type ListMetricResponse struct {
@@ -17,14 +21,63 @@ type ListMetricResponse struct {
 
 type MetricUpdateRequest struct {
 	Name  string     `uri:"name" binding:"required"`
-	Type  MetricType `uri:"type" binding:"required" oneof:"gauge counter"`
+	Type  MetricType `uri:"type" binding:"required" oneof:"gauge counter histogram"`
 	Value string     `uri:"value" binding:"required"`
 }
 
+// IdempotencyKeyTTL is how long the stores remember keys of applied batches.
+const IdempotencyKeyTTL = time.Hour
+
+// MaxIdempotencyKeyLength limits the batch key length.
+const MaxIdempotencyKeyLength = 255
+
 // MetricsV2 описывает схему ответа и запроса для метрик.
 type MetricsV2 struct {
-	Delta *int64     `json:"delta,omitempty"`
-	Value *float64   `json:"value,omitempty"`
-	ID    string     `json:"id"`
-	MType MetricType `json:"type"`
+	Delta     *int64          `json:"delta,omitempty"`
+	Value     *float64        `json:"value,omitempty"`
+	Histogram *HistogramValue `json:"histogram,omitempty"`
+	Labels    Labels          `json:"labels,omitempty"`
+	ID        string          `json:"id"`
+	MType     MetricType      `json:"type"`
+}
+
+// Key returns the storage identity of the requested metric: name and sorted label set.
+func (m *MetricsV2) Key() string {
+	return MetricKey(m.ID, m.Labels)
+}
+
+// Sample is a metric value at the moment of time.
+// For counters it is the accumulated value, for histograms it is the number of observations.
+type Sample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// HistoryRequest describes the request of metric values between two timestamps.
+type HistoryRequest struct {
+	From   time.Time  `json:"from"`
+	To     time.Time  `json:"to"`
+	Labels Labels     `json:"labels,omitempty"`
+	ID     string     `json:"id"`
+	MType  MetricType `json:"type"`
+}
+
+// HistoryResponse contains metric values ordered by time.
+type HistoryResponse struct {
+	Labels  Labels     `json:"labels,omitempty"`
+	ID      string     `json:"id"`
+	MType   MetricType `json:"type"`
+	Samples []*Sample  `json:"samples"`
+}
+
+// UnsupportedSeries is a series received by an ingestion endpoint which could not be stored.
+type UnsupportedSeries struct {
+	Series string `json:"series"`
+	Reason string `json:"reason"`
+}
+
+// WriteResponse is a result of an ingestion endpoint (remote write, OTLP, etc).
+type WriteResponse struct {
+	Unsupported []*UnsupportedSeries `json:"unsupported,omitempty"`
+	Written     int                  `json:"written"`
 }
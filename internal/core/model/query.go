@@ -0,0 +1,159 @@
+package model
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MetricsSort is a sort key of the metrics query.
+type MetricsSort string
+
+const (
+	SortByName MetricsSort = "name" // name, labels, type
+	SortByType MetricsSort = "type" // type, name, labels
+)
+
+// Limits of the metrics query page size.
+const (
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 1000
+)
+
+// MetricsCursor is the position of the last metric of the page, the next page starts after it.
+type MetricsCursor struct {
+	ID     string     `json:"id"`
+	Labels string     `json:"labels,omitempty"` // canonical labels, see Labels.String
+	MType  MetricType `json:"type"`
+}
+
+// NewMetricsCursor returns the cursor pointing to the metric.
+func NewMetricsCursor(m *MetricsV2) *MetricsCursor {
+	return &MetricsCursor{ID: m.ID, Labels: m.Labels.String(), MType: m.MType}
+}
+
+// Encode returns the opaque cursor representation used by API.
+func (c *MetricsCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeMetricsCursor parses the cursor made by MetricsCursor.Encode.
+func DecodeMetricsCursor(s string) (*MetricsCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	c := &MetricsCursor{}
+	if err = json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if c.ID == "" {
+		return nil, errors.New("invalid cursor: metric name is required")
+	}
+	return c, nil
+}
+
+// MetricsQuery describes the page of metrics selected by type and name.
+// Metrics are ordered by the sort key, the page starts after the After cursor.
+type MetricsQuery struct {
+	After *MetricsCursor
+	MType MetricType // empty for all types
+	Match string     // name glob: * matches any sequence, ? matches a single character
+	Sort  MetricsSort
+	Desc  bool
+	Limit int
+}
+
+// Validate checks the query type, sort key and limit.
+func (q *MetricsQuery) Validate() error {
+	switch q.MType {
+	case "", GaugeType, CounterType, HistogramType:
+	default:
+		return fmt.Errorf("unknown metric type: %s", q.MType)
+	}
+	switch q.Sort {
+	case SortByName, SortByType:
+	default:
+		return fmt.Errorf("unknown sort key: %s", q.Sort)
+	}
+	if q.Limit < 1 || q.Limit > MaxQueryLimit {
+		return fmt.Errorf("limit must be between 1 and %d", MaxQueryLimit)
+	}
+	return nil
+}
+
+// Matches checks the metric satisfies type and name filters of the query.
+func (q *MetricsQuery) Matches(name string, mType MetricType) bool {
+	if q.MType != "" && q.MType != mType {
+		return false
+	}
+	return q.Match == "" || MatchGlob(q.Match, name)
+}
+
+// Compare compares metric positions in the query order.
+func (q *MetricsQuery) Compare(a, b *MetricsCursor) int {
+	var res int
+	switch q.Sort {
+	case SortByType:
+		res = cmp.Or(
+			strings.Compare(string(a.MType), string(b.MType)),
+			strings.Compare(a.ID, b.ID),
+			strings.Compare(a.Labels, b.Labels),
+		)
+	default:
+		res = cmp.Or(
+			strings.Compare(a.ID, b.ID),
+			strings.Compare(a.Labels, b.Labels),
+			strings.Compare(string(a.MType), string(b.MType)),
+		)
+	}
+	if q.Desc {
+		return -res
+	}
+	return res
+}
+
+// MetricsPage is a result of the metrics query. NextCursor is empty on the last page.
+type MetricsPage struct {
+	Metrics    []*MetricsV2 `json:"metrics"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// MatchGlob checks the whole name matches the pattern with * and ? wildcards.
+func MatchGlob(pattern, name string) bool {
+	// star - позиция последней * в шаблоне, retry - позиция в имени, с которой её сопоставление продолжится
+	star, retry := -1, 0
+	p, n := 0, 0
+	for n < len(name) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, retry = p, n
+			p++
+			continue
+		case p < len(pattern) && pattern[p] == '?':
+			_, size := utf8.DecodeRuneInString(name[n:])
+			p++
+			n += size
+			continue
+		case p < len(pattern) && pattern[p] == name[n]:
+			p++
+			n++
+			continue
+		}
+		if star < 0 {
+			return false
+		}
+		_, size := utf8.DecodeRuneInString(name[retry:])
+		retry += size
+		p, n = star+1, retry
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
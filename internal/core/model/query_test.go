@@ -0,0 +1,79 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		matches bool
+	}{
+		{pattern: "Heap*", name: "HeapAlloc", matches: true},
+		{pattern: "Heap*", name: "Heap", matches: true},
+		{pattern: "Heap*", name: "StackInuse", matches: false},
+		{pattern: "*Alloc", name: "HeapAlloc", matches: true},
+		{pattern: "*Alloc", name: "HeapAllocs", matches: false},
+		{pattern: "*a*a*", name: "banana", matches: true},
+		{pattern: "Gauge?", name: "Gauge1", matches: true},
+		{pattern: "Gauge?", name: "Gauge", matches: false},
+		{pattern: "Gauge?", name: "Gauge12", matches: false},
+		{pattern: "?ö", name: "ßö", matches: true},
+		{pattern: "HeapAlloc", name: "HeapAlloc", matches: true},
+		{pattern: "*", name: "", matches: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.matches, MatchGlob(tt.pattern, tt.name))
+		})
+	}
+}
+
+func TestMetricsCursor(t *testing.T) {
+	cursor := NewMetricsCursor(&MetricsV2{ID: "HeapAlloc", MType: GaugeType, Labels: Labels{"host": "a"}})
+	assert.Equal(t, &MetricsCursor{ID: "HeapAlloc", MType: GaugeType, Labels: `host="a"`}, cursor)
+
+	decoded, err := DecodeMetricsCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, cursor, decoded)
+
+	_, err = DecodeMetricsCursor("not a cursor")
+	require.Error(t, err)
+	_, err = DecodeMetricsCursor((&MetricsCursor{}).Encode())
+	require.Error(t, err)
+}
+
+func TestMetricsQuery(t *testing.T) {
+	gauge := &MetricsCursor{ID: "b", MType: GaugeType}
+	counter := &MetricsCursor{ID: "a", MType: CounterType}
+	labeled := &MetricsCursor{ID: "a", MType: CounterType, Labels: `host="a"`}
+
+	q := &MetricsQuery{Sort: SortByName, Limit: 10}
+	require.NoError(t, q.Validate())
+	assert.Negative(t, q.Compare(counter, gauge))
+	assert.Negative(t, q.Compare(counter, labeled))
+	assert.Zero(t, q.Compare(gauge, gauge))
+
+	q = &MetricsQuery{Sort: SortByType, Desc: true, Limit: 10}
+	assert.Positive(t, q.Compare(counter, gauge))
+	assert.Positive(t, q.Compare(counter, labeled))
+
+	q = &MetricsQuery{MType: CounterType, Match: "Poll*"}
+	assert.True(t, q.Matches("PollCount", CounterType))
+	assert.False(t, q.Matches("PollCount", GaugeType))
+	assert.False(t, q.Matches("RandomValue", CounterType))
+
+	for _, q := range []*MetricsQuery{
+		{Sort: SortByName},
+		{Sort: SortByName, Limit: MaxQueryLimit + 1},
+		{Sort: "value", Limit: 10},
+		{Sort: SortByName, MType: "unknown", Limit: 10},
+	} {
+		require.Error(t, q.Validate())
+	}
+}
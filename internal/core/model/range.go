@@ -0,0 +1,73 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RangeFunction aggregates samples of a single step of the range query.
+type RangeFunction string
+
+const (
+	FnAvg      RangeFunction = "avg"
+	FnMin      RangeFunction = "min"
+	FnMax      RangeFunction = "max"
+	FnSum      RangeFunction = "sum"
+	FnP95      RangeFunction = "p95"
+	FnRate     RangeFunction = "rate"     // per-second increase, counters and histograms only
+	FnIncrease RangeFunction = "increase" // counters and histograms only
+)
+
+// MaxRangePoints limits the amount of steps of the range query.
+const MaxRangePoints = 11000
+
+// RangeQuery requests samples of the metric between Start and End aggregated by Step.
+type RangeQuery struct {
+	Start  time.Time
+	End    time.Time
+	Labels Labels
+	ID     string
+	MType  MetricType
+	Fn     RangeFunction
+	Step   time.Duration
+}
+
+// Validate checks the query type, function, step and the amount of steps.
+func (q *RangeQuery) Validate() error {
+	switch q.MType {
+	case GaugeType, CounterType, HistogramType:
+	default:
+		return fmt.Errorf("unknown metric type: %s", q.MType.String())
+	}
+	switch q.Fn {
+	case FnAvg, FnMin, FnMax, FnSum, FnP95:
+	case FnRate, FnIncrease:
+		if q.MType == GaugeType {
+			return fmt.Errorf("%s is not supported for gauges", q.Fn)
+		}
+	default:
+		return fmt.Errorf("unknown range function: %s", q.Fn)
+	}
+	if q.Step <= 0 {
+		return errors.New("step must be positive")
+	}
+	if q.End.Before(q.Start) {
+		return errors.New("range start could not be after the end")
+	}
+	if q.End.Sub(q.Start)/q.Step >= MaxRangePoints {
+		return fmt.Errorf("range has more than %d steps, increase the step", MaxRangePoints)
+	}
+	return nil
+}
+
+// RangeResponse contains aggregated values of the steps ordered by time.
+// A point timestamp is the step start, steps without samples are skipped.
+type RangeResponse struct {
+	Labels Labels        `json:"labels,omitempty"`
+	ID     string        `json:"id"`
+	MType  MetricType    `json:"type"`
+	Fn     RangeFunction `json:"fn"`
+	Step   float64       `json:"step"` // seconds
+	Points []*Sample     `json:"points"`
+}
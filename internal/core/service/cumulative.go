@@ -0,0 +1,236 @@
+package service
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"metrics/internal/core/model"
+)
+
+// DefaultCumulativeTTL is how long the CumulativeTracker remembers a series which is not updated.
+const DefaultCumulativeTTL = time.Hour
+
+// cumulativeSeries is a state of a counter series.
+type cumulativeSeries struct {
+	timestamp time.Time // timestamp of the last value reported by the source
+	updatedAt time.Time // local time of the last update, used to forget the series
+	last      float64
+	total     float64 // increase since the series was seen first time, resets are taken into account
+	emitted   int64   // part of the total already returned as deltas
+}
+
+// cumulativeHistogram is a state of a cumulative histogram.
+type cumulativeHistogram struct {
+	timestamp time.Time
+	updatedAt time.Time
+	last      *model.HistogramValue
+	carry     *model.HistogramValue // rolled back increase, it is added to the next delta
+}
+
+// CumulativeTracker converts cumulative counters (Prometheus remote write, OTLP) into deltas,
+// because model.Counter values are increased by deltas.
+// A decrease of the value is treated as a counter reset. The first value of a series is the baseline
+// and its delta is zero, so the source total is not added again to the stored counter after the server restart.
+// Deltas which were not stored are returned by Rollback, they are returned again by the next call.
+type CumulativeTracker struct {
+	mux        *sync.Mutex
+	now        func() time.Time
+	series     map[string]*cumulativeSeries
+	histograms map[string]*cumulativeHistogram
+	cleanedAt  time.Time
+	ttl        time.Duration
+}
+
+func NewCumulativeTracker(ttl time.Duration) *CumulativeTracker {
+	return &CumulativeTracker{
+		mux:        &sync.Mutex{},
+		now:        time.Now,
+		series:     make(map[string]*cumulativeSeries),
+		histograms: make(map[string]*cumulativeHistogram),
+		cleanedAt:  time.Now(),
+		ttl:        ttl,
+	}
+}
+
+// Delta returns integer increase of the series since the previous call. Fractional increase is carried over
+// to the next calls, so rounding errors are not accumulated.
+// ok is false if the value is older than the previous one of the series or is not finite, such value must be skipped.
+func (t *CumulativeTracker) Delta(key string, timestamp time.Time, value float64) (delta int64, ok bool) {
+	if !finite(value) {
+		return 0, false
+	}
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	now := t.now()
+	t.cleanup(now)
+
+	s, exists := t.series[key]
+	if !exists {
+		s = &cumulativeSeries{}
+		t.series[key] = s
+	} else {
+		if timestamp.Before(s.timestamp) {
+			return 0, false
+		}
+		if value >= s.last {
+			s.total += value - s.last
+		} else {
+			// counter reset, the source started from zero
+			s.total += value
+		}
+	}
+	s.timestamp = timestamp
+	s.updatedAt = now
+	s.last = value
+	return s.emit(), true
+}
+
+// Add returns integer part of the increase reported as a delta (OTLP delta temporality).
+// Fractional part is carried over to the next calls like in Delta. Not finite values are skipped.
+func (t *CumulativeTracker) Add(key string, value float64) int64 {
+	if !finite(value) {
+		return 0
+	}
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	now := t.now()
+	t.cleanup(now)
+
+	s, exists := t.series[key]
+	if !exists {
+		s = &cumulativeSeries{}
+		t.series[key] = s
+	}
+	s.total += value
+	s.updatedAt = now
+	return s.emit()
+}
+
+func finite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+func (s *cumulativeSeries) emit() int64 {
+	delta := int64(math.Round(s.total)) - s.emitted
+	s.emitted += delta
+	return delta
+}
+
+// Rollback returns deltas of counters and histograms which were not stored, e.g. the storage is not available.
+// They are added to the next deltas of the series, so a retried request is not lost.
+func (t *CumulativeTracker) Rollback(metrics []*model.MetricsV2) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	for _, m := range metrics {
+		key := m.Key()
+		switch {
+		case m.MType == model.CounterType && m.Delta != nil:
+			if s, ok := t.series[key]; ok {
+				s.emitted -= *m.Delta
+			}
+		case m.MType == model.HistogramType && m.Histogram != nil:
+			if h, ok := t.histograms[key]; ok {
+				h.carry = addHistogram(h.carry, m.Histogram)
+			}
+		}
+	}
+}
+
+// HistogramDelta returns increase of bucket counts and sum of the cumulative histogram since the previous call.
+// The first value is the baseline, its delta has zero counts.
+// Decrease of the total count or changed buckets are treated as a reset.
+// ok is false if the value is older than the previous one of the series, such value must be skipped.
+func (t *CumulativeTracker) HistogramDelta(
+	key string, timestamp time.Time, value *model.HistogramValue,
+) (delta *model.HistogramValue, ok bool) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	now := t.now()
+	t.cleanup(now)
+
+	h, exists := t.histograms[key]
+	if exists && timestamp.Before(h.timestamp) {
+		return nil, false
+	}
+	delta = &model.HistogramValue{
+		Buckets: slices.Clone(value.Buckets),
+		Counts:  slices.Clone(value.Counts),
+		Sum:     value.Sum,
+	}
+	switch {
+	case !exists:
+		// baseline
+		clear(delta.Counts)
+		delta.Sum = 0
+		h = &cumulativeHistogram{}
+		t.histograms[key] = h
+	case !histogramReset(h.last, value):
+		for i := range delta.Counts {
+			delta.Counts[i] -= h.last.Counts[i]
+		}
+		delta.Sum -= h.last.Sum
+	}
+	if h.carry != nil {
+		delta = addHistogram(delta, h.carry)
+		h.carry = nil
+	}
+	h.timestamp = timestamp
+	h.updatedAt = now
+	h.last = value
+	return delta, true
+}
+
+// addHistogram adds the increase to the histogram. If buckets are changed, the increase is dropped.
+func addHistogram(h, increase *model.HistogramValue) *model.HistogramValue {
+	if h == nil {
+		return &model.HistogramValue{
+			Buckets: slices.Clone(increase.Buckets),
+			Counts:  slices.Clone(increase.Counts),
+			Sum:     increase.Sum,
+		}
+	}
+	if !slices.Equal(h.Buckets, increase.Buckets) || len(h.Counts) != len(increase.Counts) {
+		return h
+	}
+	for i := range h.Counts {
+		h.Counts[i] += increase.Counts[i]
+	}
+	h.Sum += increase.Sum
+	return h
+}
+
+func histogramReset(last, value *model.HistogramValue) bool {
+	if !slices.Equal(last.Buckets, value.Buckets) || len(last.Counts) != len(value.Counts) {
+		return true
+	}
+	for i := range value.Counts {
+		if value.Counts[i] < last.Counts[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanup forgets series which are not updated longer than ttl. It runs not more often than once per ttl.
+func (t *CumulativeTracker) cleanup(now time.Time) {
+	if t.ttl <= 0 || now.Sub(t.cleanedAt) < t.ttl {
+		return
+	}
+	for key, s := range t.series {
+		if now.Sub(s.updatedAt) >= t.ttl {
+			delete(t.series, key)
+		}
+	}
+	for key, h := range t.histograms {
+		if now.Sub(h.updatedAt) >= t.ttl {
+			delete(t.histograms, key)
+		}
+	}
+	t.cleanedAt = now
+}
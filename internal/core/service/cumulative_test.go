@@ -0,0 +1,161 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"metrics/internal/core/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCumulativeTracker(t *testing.T) {
+	tracker := NewCumulativeTracker(time.Hour)
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name  string
+		value float64
+		shift time.Duration
+		delta int64
+		ok    bool
+	}{
+		{name: "first value is the baseline", value: 10, shift: 0, delta: 0, ok: true},
+		{name: "increase", value: 15, shift: time.Second, delta: 5, ok: true},
+		{name: "same value", value: 15, shift: 2 * time.Second, delta: 0, ok: true},
+		{name: "out of order", value: 100, shift: time.Second, delta: 0, ok: false},
+		{name: "reset", value: 3, shift: 3 * time.Second, delta: 3, ok: true},
+		{name: "fraction is carried", value: 3.4, shift: 4 * time.Second, delta: 0, ok: true},
+		{name: "fraction is carried 2", value: 3.8, shift: 5 * time.Second, delta: 1, ok: true},
+	}
+	for _, step := range steps {
+		delta, ok := tracker.Delta("requests", ts.Add(step.shift), step.value)
+		assert.Equal(t, step.ok, ok, step.name)
+		assert.Equal(t, step.delta, delta, step.name)
+	}
+
+	delta, ok := tracker.Delta(`requests{host="b"}`, ts, 7)
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), delta)
+}
+
+func TestCumulativeTrackerRollback(t *testing.T) {
+	tracker := NewCumulativeTracker(time.Hour)
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tracker.Delta("requests", ts, 10)
+	delta, ok := tracker.Delta("requests", ts.Add(time.Second), 15)
+	require.True(t, ok)
+	require.Equal(t, int64(5), delta)
+
+	// сохранение не удалось, тот же запрос повторяется
+	tracker.Rollback([]*model.MetricsV2{{ID: "requests", MType: model.CounterType, Delta: &delta}})
+	delta, ok = tracker.Delta("requests", ts.Add(time.Second), 15)
+	require.True(t, ok)
+	assert.Equal(t, int64(5), delta)
+
+	// неизвестные серии пропускаются
+	tracker.Rollback([]*model.MetricsV2{{ID: "errors", MType: model.CounterType, Delta: &delta}})
+	delta, _ = tracker.Delta("errors", ts, 3)
+	assert.Equal(t, int64(0), delta)
+}
+
+func TestCumulativeTrackerForgetsSeries(t *testing.T) {
+	tracker := NewCumulativeTracker(time.Minute)
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+
+	tracker.Delta("requests", now, 10)
+	now = now.Add(2 * time.Minute)
+
+	// the series was forgotten, the value is the baseline again
+	delta, ok := tracker.Delta("requests", now, 12)
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), delta)
+	delta, _ = tracker.Delta("requests", now.Add(time.Second), 13)
+	assert.Equal(t, int64(1), delta)
+}
+
+func TestCumulativeTrackerHistogramDelta(t *testing.T) {
+	tracker := NewCumulativeTracker(time.Hour)
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	buckets := []float64{0.1, 1}
+
+	delta, ok := tracker.HistogramDelta("latency", ts, &model.HistogramValue{Buckets: buckets, Counts: []int64{1, 2, 0}, Sum: 1.5})
+	require.True(t, ok)
+	assert.Equal(t, &model.HistogramValue{Buckets: buckets, Counts: []int64{0, 0, 0}}, delta)
+
+	delta, ok = tracker.HistogramDelta(
+		"latency", ts.Add(time.Second), &model.HistogramValue{Buckets: buckets, Counts: []int64{2, 2, 1}, Sum: 4},
+	)
+	require.True(t, ok)
+	assert.Equal(t, &model.HistogramValue{Buckets: buckets, Counts: []int64{1, 0, 1}, Sum: 2.5}, delta)
+
+	_, ok = tracker.HistogramDelta("latency", ts, &model.HistogramValue{Buckets: buckets, Counts: []int64{5, 5, 5}, Sum: 10})
+	assert.False(t, ok)
+
+	// reset
+	delta, ok = tracker.HistogramDelta(
+		"latency", ts.Add(2*time.Second), &model.HistogramValue{Buckets: buckets, Counts: []int64{0, 1, 0}, Sum: 0.5},
+	)
+	require.True(t, ok)
+	assert.Equal(t, &model.HistogramValue{Buckets: buckets, Counts: []int64{0, 1, 0}, Sum: 0.5}, delta)
+}
+
+func TestCumulativeTrackerHistogramRollback(t *testing.T) {
+	tracker := NewCumulativeTracker(time.Hour)
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	buckets := []float64{1}
+
+	tracker.HistogramDelta("latency", ts, &model.HistogramValue{Buckets: buckets, Counts: []int64{1, 0}, Sum: 0.5})
+	delta, ok := tracker.HistogramDelta(
+		"latency", ts.Add(time.Second), &model.HistogramValue{Buckets: buckets, Counts: []int64{2, 1}, Sum: 3},
+	)
+	require.True(t, ok)
+	tracker.Rollback([]*model.MetricsV2{{ID: "latency", MType: model.HistogramType, Histogram: delta}})
+
+	delta, ok = tracker.HistogramDelta(
+		"latency", ts.Add(2*time.Second), &model.HistogramValue{Buckets: buckets, Counts: []int64{3, 1}, Sum: 4},
+	)
+	require.True(t, ok)
+	assert.Equal(t, &model.HistogramValue{Buckets: buckets, Counts: []int64{2, 1}, Sum: 3.5}, delta)
+}
+
+func TestCumulativeTrackerAdd(t *testing.T) {
+	tracker := NewCumulativeTracker(time.Hour)
+
+	deltas := make([]int64, 0, 5)
+	for range 5 {
+		deltas = append(deltas, tracker.Add("requests", 0.4))
+	}
+	// дробные приращения переносятся
+	assert.Equal(t, []int64{0, 1, 0, 1, 0}, deltas)
+
+	delta := int64(7)
+	assert.Equal(t, delta, tracker.Add("requests", 7))
+	tracker.Rollback([]*model.MetricsV2{{ID: "requests", MType: model.CounterType, Delta: &delta}})
+	assert.Equal(t, int64(7), tracker.Add("requests", 0))
+}
+
+func TestCumulativeTrackerNotFinite(t *testing.T) {
+	tracker := NewCumulativeTracker(time.Hour)
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, value := range []float64{math.NaN(), math.Inf(1), 10, math.NaN(), 15} {
+		delta, ok := tracker.Delta("requests", ts.Add(time.Duration(i)*time.Second), value)
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			assert.False(t, ok)
+			continue
+		}
+		assert.True(t, ok)
+		if value == 15 {
+			assert.Equal(t, int64(5), delta)
+		}
+	}
+
+	assert.Equal(t, int64(0), tracker.Add("jobs", math.Inf(-1)))
+	assert.Equal(t, int64(0), tracker.Add("jobs", math.NaN()))
+	assert.Equal(t, int64(2), tracker.Add("jobs", 2))
+}
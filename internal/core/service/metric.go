@@ -5,20 +5,30 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"metrics/internal/core/model"
 )
 
 type Store interface {
 	BatchUpsertMetrics(ctx context.Context, metrics []*model.MetricsV2) ([]*model.MetricsV2, error)
+	IdempotentBatchUpsertMetrics(ctx context.Context, key string, metrics []*model.MetricsV2) ([]*model.MetricsV2, error)
 	GetGauge(ctx context.Context, req *model.MetricsV2) (*model.Gauge, error)
 	SetGauge(ctx context.Context, gauge *model.Gauge) error
 	ListGauge(ctx context.Context) ([]*model.Gauge, error)
 	GetCounter(ctx context.Context, req *model.MetricsV2) (*model.Counter, error)
 	SetCounter(ctx context.Context, counter *model.Counter) error
 	ListCounter(ctx context.Context) ([]*model.Counter, error)
+	GetHistogram(ctx context.Context, req *model.MetricsV2) (*model.Histogram, error)
+	SetHistogram(ctx context.Context, histogram *model.Histogram) error
+	ListHistogram(ctx context.Context) ([]*model.Histogram, error)
+	ListSamples(ctx context.Context, req *model.MetricsV2, from, to time.Time) ([]*model.Sample, error)
+	QueryMetrics(ctx context.Context, q *model.MetricsQuery) ([]*model.MetricsV2, error)
 }
 
+// DefaultHistoryPeriod is used when the history request has no start time.
+const DefaultHistoryPeriod = time.Hour
+
 type Metric interface {
 	StringValue() string
 	Type() model.MetricType
@@ -34,7 +44,8 @@ func NewMetricService(store Store) *MetricService {
 	}
 }
 
-func (m *MetricService) ListMetrics(ctx context.Context) (*model.ListMetricResponse, error) {
+// ListMetrics returns all stored metrics which labels satisfy all the matchers.
+func (m *MetricService) ListMetrics(ctx context.Context, matchers ...*model.LabelMatcher) (*model.ListMetricResponse, error) {
 	gagues, err := m.store.ListGauge(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list gauges: %w", err)
@@ -45,26 +56,57 @@ func (m *MetricService) ListMetrics(ctx context.Context) (*model.ListMetricRespo
 		return nil, fmt.Errorf("failed to list gauges: %w", err)
 	}
 
-	result := model.ListMetricResponse{Metrics: make([]*model.MetricResponse, 0, len(gagues)+len(counters))}
+	histograms, err := m.store.ListHistogram(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list histograms: %w", err)
+	}
+
+	result := model.ListMetricResponse{
+		Metrics: make([]*model.MetricResponse, 0, len(gagues)+len(counters)+len(histograms)),
+	}
 
 	for _, gauge := range gagues {
+		if !model.MatchLabels(gauge.Labels, matchers) {
+			continue
+		}
 		result.Metrics = append(
 			result.Metrics,
 			&model.MetricResponse{
-				Name:  gauge.Name,
-				Type:  gauge.Type(),
-				Value: gauge.StringValue(),
+				Name:   gauge.Name,
+				Labels: gauge.Labels,
+				Type:   gauge.Type(),
+				Value:  gauge.StringValue(),
 			},
 		)
 	}
 
 	for _, counter := range counters {
+		if !model.MatchLabels(counter.Labels, matchers) {
+			continue
+		}
 		result.Metrics = append(
 			result.Metrics,
 			&model.MetricResponse{
-				Name:  counter.Name,
-				Type:  counter.Type(),
-				Value: counter.StringValue(),
+				Name:   counter.Name,
+				Labels: counter.Labels,
+				Type:   counter.Type(),
+				Value:  counter.StringValue(),
+			},
+		)
+	}
+
+	for _, histogram := range histograms {
+		if !model.MatchLabels(histogram.Labels, matchers) {
+			continue
+		}
+		result.Metrics = append(
+			result.Metrics,
+			&model.MetricResponse{
+				Name:      histogram.Name,
+				Labels:    histogram.Labels,
+				Histogram: histogram.Payload(),
+				Type:      histogram.Type(),
+				Value:     histogram.StringValue(),
 			},
 		)
 	}
@@ -72,6 +114,28 @@ func (m *MetricService) ListMetrics(ctx context.Context) (*model.ListMetricRespo
 	return &result, nil
 }
 
+// QueryMetrics returns the page of metrics selected by the query.
+// The store is asked for one extra metric to know whether the next page exists.
+func (m *MetricService) QueryMetrics(ctx context.Context, q *model.MetricsQuery) (*model.MetricsPage, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	storeQuery := *q
+	storeQuery.Limit = q.Limit + 1
+	metrics, err := m.store.QueryMetrics(ctx, &storeQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics: %w", err)
+	}
+
+	page := &model.MetricsPage{Metrics: metrics}
+	if len(metrics) > q.Limit {
+		page.Metrics = metrics[:q.Limit]
+		page.NextCursor = model.NewMetricsCursor(page.Metrics[q.Limit-1]).Encode()
+	}
+	return page, nil
+}
+
 func (m *MetricService) GetCounter(ctx context.Context, req *model.MetricsV2) (*model.MetricsV2, error) {
 	counter, err := m.store.GetCounter(ctx, req)
 	if err != nil {
@@ -81,9 +145,10 @@ func (m *MetricService) GetCounter(ctx context.Context, req *model.MetricsV2) (*
 		return nil, nil
 	}
 	return &model.MetricsV2{
-		ID:    req.ID,
-		MType: model.CounterType,
-		Delta: &counter.Value,
+		ID:     req.ID,
+		Labels: counter.Labels,
+		MType:  model.CounterType,
+		Delta:  &counter.Value,
 	}, nil
 }
 
@@ -96,9 +161,26 @@ func (m *MetricService) GetGauge(ctx context.Context, req *model.MetricsV2) (*mo
 		return nil, nil
 	}
 	return &model.MetricsV2{
-		ID:    req.ID,
-		MType: model.GaugeType,
-		Value: &gauge.Value,
+		ID:     req.ID,
+		Labels: gauge.Labels,
+		MType:  model.GaugeType,
+		Value:  &gauge.Value,
+	}, nil
+}
+
+func (m *MetricService) GetHistogram(ctx context.Context, req *model.MetricsV2) (*model.MetricsV2, error) {
+	histogram, err := m.store.GetHistogram(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch histogram from the store: %w", err)
+	}
+	if histogram == nil {
+		return nil, nil
+	}
+	return &model.MetricsV2{
+		ID:        req.ID,
+		Labels:    histogram.Labels,
+		MType:     model.HistogramType,
+		Histogram: histogram.Payload(),
 	}, nil
 }
 
@@ -108,18 +190,80 @@ func (m *MetricService) GetMetric(ctx context.Context, req *model.MetricsV2) (*m
 		return m.GetCounter(ctx, req)
 	case model.GaugeType:
 		return m.GetGauge(ctx, req)
+	case model.HistogramType:
+		return m.GetHistogram(ctx, req)
 	default:
 		return nil, fmt.Errorf("unknown metric type: %s", req.MType)
 	}
 }
 
+// FindMetric returns the metric with the requested name and type which labels satisfy the matchers.
+// Without matchers it is the same as GetMetric. If matchers select several metrics ErrAmbiguousMetric is returned.
+func (m *MetricService) FindMetric(
+	ctx context.Context, req *model.MetricsV2, matchers []*model.LabelMatcher,
+) (*model.MetricsV2, error) {
+	if len(matchers) == 0 {
+		return m.GetMetric(ctx, req)
+	}
+
+	var found []*model.MetricsV2
+	switch req.MType {
+	case model.GaugeType:
+		gauges, err := m.store.ListGauge(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list gauges: %w", err)
+		}
+		for _, g := range gauges {
+			if g.Name == req.ID && model.MatchLabels(g.Labels, matchers) {
+				v := g.Value
+				found = append(found, &model.MetricsV2{ID: g.Name, Labels: g.Labels, MType: model.GaugeType, Value: &v})
+			}
+		}
+	case model.CounterType:
+		counters, err := m.store.ListCounter(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list counters: %w", err)
+		}
+		for _, c := range counters {
+			if c.Name == req.ID && model.MatchLabels(c.Labels, matchers) {
+				v := c.Value
+				found = append(found, &model.MetricsV2{ID: c.Name, Labels: c.Labels, MType: model.CounterType, Delta: &v})
+			}
+		}
+	case model.HistogramType:
+		histograms, err := m.store.ListHistogram(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list histograms: %w", err)
+		}
+		for _, h := range histograms {
+			if h.Name == req.ID && model.MatchLabels(h.Labels, matchers) {
+				found = append(
+					found,
+					&model.MetricsV2{ID: h.Name, Labels: h.Labels, MType: model.HistogramType, Histogram: h.Payload()},
+				)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown metric type: %s", req.MType)
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, nil
+	case 1:
+		return found[0], nil
+	default:
+		return nil, model.ErrAmbiguousMetric
+	}
+}
+
 func (m *MetricService) upsertGaugeValue(ctx context.Context, req *model.MetricsV2) (*model.MetricsV2, error) {
 	gauge, err := m.store.GetGauge(ctx, req)
 	if err != nil {
 		return &model.MetricsV2{}, fmt.Errorf("failed to fetch %s from the store: %w", req.MType.String(), err)
 	}
 	if gauge == nil {
-		gauge = model.NewGauge(req.ID)
+		gauge = model.NewGauge(req.ID, req.Labels)
 	}
 
 	if req.Value == nil {
@@ -132,9 +276,10 @@ func (m *MetricService) upsertGaugeValue(ctx context.Context, req *model.Metrics
 	}
 
 	return &model.MetricsV2{
-		ID:    req.ID,
-		MType: model.GaugeType,
-		Value: &gauge.Value,
+		ID:     req.ID,
+		Labels: gauge.Labels,
+		MType:  model.GaugeType,
+		Value:  &gauge.Value,
 	}, nil
 }
 
@@ -144,7 +289,7 @@ func (m *MetricService) upsertCounterValue(ctx context.Context, req *model.Metri
 		return nil, fmt.Errorf("failed to fetch counter from the store: %w", err)
 	}
 	if counter == nil {
-		counter = model.NewCounter(req.ID)
+		counter = model.NewCounter(req.ID, req.Labels)
 	}
 
 	if req.Delta == nil {
@@ -161,9 +306,37 @@ func (m *MetricService) upsertCounterValue(ctx context.Context, req *model.Metri
 	}
 
 	return &model.MetricsV2{
-		ID:    req.ID,
-		MType: model.CounterType,
-		Delta: &counter.Value,
+		ID:     req.ID,
+		Labels: counter.Labels,
+		MType:  model.CounterType,
+		Delta:  &counter.Value,
+	}, nil
+}
+
+func (m *MetricService) upsertHistogramValue(ctx context.Context, req *model.MetricsV2) (*model.MetricsV2, error) {
+	histogram, err := m.store.GetHistogram(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch histogram from the store: %w", err)
+	}
+	if histogram == nil {
+		histogram = model.NewHistogramFromRequest(req)
+	}
+
+	err = histogram.Apply(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update histogram (%s): %w", req.ID, err)
+	}
+
+	err = m.store.SetHistogram(ctx, histogram)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save histogram value: %w", err)
+	}
+
+	return &model.MetricsV2{
+		ID:        req.ID,
+		Labels:    histogram.Labels,
+		MType:     model.HistogramType,
+		Histogram: histogram.Payload(),
 	}, nil
 }
 
@@ -173,6 +346,8 @@ func (m *MetricService) UpsertMetricValue(ctx context.Context, req *model.Metric
 		return m.upsertCounterValue(ctx, req)
 	case model.GaugeType:
 		return m.upsertGaugeValue(ctx, req)
+	case model.HistogramType:
+		return m.upsertHistogramValue(ctx, req)
 	default:
 		return nil, fmt.Errorf("unknown metric type: %s", req.MType.String())
 	}
@@ -182,6 +357,54 @@ func (m *MetricService) BatchUpsertMetricValue(ctx context.Context, batch []*mod
 	return m.store.BatchUpsertMetrics(ctx, batch)
 }
 
+// IdempotentBatchUpsertMetricValue applies the batch once per key, a retried batch returns the result
+// of the first one. The batch without the key is always applied.
+func (m *MetricService) IdempotentBatchUpsertMetricValue(
+	ctx context.Context, key string, batch []*model.MetricsV2,
+) ([]*model.MetricsV2, error) {
+	if key == "" {
+		return m.store.BatchUpsertMetrics(ctx, batch)
+	}
+	if len(key) > model.MaxIdempotencyKeyLength {
+		return nil, fmt.Errorf("idempotency key is longer than %d", model.MaxIdempotencyKeyLength)
+	}
+	return m.store.IdempotentBatchUpsertMetrics(ctx, key, batch)
+}
+
+// GetHistory returns metric values recorded between req.From and req.To ordered by time.
+// The end defaults to the current time and the start to DefaultHistoryPeriod before the end.
+func (m *MetricService) GetHistory(ctx context.Context, req *model.HistoryRequest) (*model.HistoryResponse, error) {
+	switch req.MType {
+	case model.GaugeType, model.CounterType, model.HistogramType:
+	default:
+		return nil, fmt.Errorf("unknown metric type: %s", req.MType.String())
+	}
+
+	to := req.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from := req.From
+	if from.IsZero() {
+		from = to.Add(-DefaultHistoryPeriod)
+	}
+	if from.After(to) {
+		return nil, errors.New("history start could not be after the end")
+	}
+
+	samples, err := m.store.ListSamples(ctx, &model.MetricsV2{ID: req.ID, MType: req.MType, Labels: req.Labels}, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s history from the store: %w", req.MType.String(), err)
+	}
+
+	return &model.HistoryResponse{
+		ID:      req.ID,
+		Labels:  req.Labels,
+		MType:   req.MType,
+		Samples: samples,
+	}, nil
+}
+
 func (m *MetricService) BuildMetricRequest(
 	name string, mType model.MetricType, value string, mustParseValue bool,
 ) (*model.MetricsV2, error) {
@@ -203,10 +426,10 @@ func (m *MetricService) BuildMetricRequest(
 			break
 		}
 		reqV2.Delta = &v
-	case model.GaugeType:
+	case model.GaugeType, model.HistogramType:
 		v, err := strconv.ParseFloat(value, 64)
 		if err != nil && mustParseValue {
-			return nil, fmt.Errorf("failed to parse gauge value: %w", err)
+			return nil, fmt.Errorf("failed to parse %s value: %w", mType.String(), err)
 		}
 		if err != nil {
 			break
@@ -3,10 +3,13 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
 	"metrics/internal/core/model"
 	"metrics/internal/mocks"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
@@ -72,3 +75,72 @@ func BenchmarkGetGauge(b *testing.B) {
 		_, _ = metricService.GetGauge(ctx, &req)
 	}
 }
+
+func TestGetHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+
+	to := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	samples := []*model.Sample{{Timestamp: to.Add(-time.Minute), Value: 1}}
+	metric := &model.MetricsV2{ID: "HeapAlloc", MType: model.GaugeType, Labels: model.Labels{"host": "a"}}
+
+	mock := mocks.NewMockStore(ctrl)
+	mock.EXPECT().ListSamples(ctx, metric, to.Add(-DefaultHistoryPeriod), to).Return(samples, nil)
+
+	metricService := NewMetricService(mock)
+
+	actual, err := metricService.GetHistory(
+		ctx,
+		&model.HistoryRequest{ID: "HeapAlloc", MType: model.GaugeType, Labels: model.Labels{"host": "a"}, To: to},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, samples, actual.Samples)
+
+	_, err = metricService.GetHistory(ctx, &model.HistoryRequest{ID: "HeapAlloc", MType: "unknown"})
+	require.Error(t, err)
+
+	_, err = metricService.GetHistory(
+		ctx,
+		&model.HistoryRequest{ID: "HeapAlloc", MType: model.GaugeType, From: to, To: to.Add(-time.Second)},
+	)
+	require.Error(t, err)
+}
+
+func TestQueryMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+
+	var one, two, three int64 = 1, 2, 3
+	metrics := []*model.MetricsV2{
+		{ID: "counter_1", MType: model.CounterType, Delta: &one},
+		{ID: "counter_2", MType: model.CounterType, Delta: &two},
+		{ID: "counter_3", MType: model.CounterType, Delta: &three},
+	}
+	q := &model.MetricsQuery{MType: model.CounterType, Sort: model.SortByName, Limit: 2}
+
+	mock := mocks.NewMockStore(ctrl)
+	mock.EXPECT().
+		QueryMetrics(ctx, &model.MetricsQuery{MType: model.CounterType, Sort: model.SortByName, Limit: 3}).
+		Return(metrics, nil)
+	mock.EXPECT().
+		QueryMetrics(ctx, &model.MetricsQuery{MType: model.CounterType, Sort: model.SortByName, Limit: 4}).
+		Return(metrics, nil)
+
+	metricService := NewMetricService(mock)
+
+	page, err := metricService.QueryMetrics(ctx, q)
+	require.NoError(t, err)
+	assert.Equal(t, metrics[:2], page.Metrics)
+	cursor, err := model.DecodeMetricsCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, model.NewMetricsCursor(metrics[1]), cursor)
+
+	q.Limit = 3
+	page, err = metricService.QueryMetrics(ctx, q)
+	require.NoError(t, err)
+	assert.Equal(t, metrics, page.Metrics)
+	assert.Empty(t, page.NextCursor)
+
+	_, err = metricService.QueryMetrics(ctx, &model.MetricsQuery{Sort: model.SortByName})
+	require.Error(t, err)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"metrics/internal/core/model"
+)
+
+// RangeLookback is how long before the range start rate and increase look for the previous sample,
+// so the first step has the increase even if samples are rarer than steps.
+const RangeLookback = time.Hour
+
+// RangeService evaluates range queries over metric samples recorded by the store.
+// It is used by REST API and could be reused by other APIs.
+type RangeService struct {
+	store Store
+}
+
+func NewRangeService(store Store) *RangeService {
+	return &RangeService{store: store}
+}
+
+// step accumulates samples of a single step.
+type step struct {
+	values   []float64
+	increase float64
+	seen     bool
+}
+
+// QueryRange splits the range into steps and aggregates samples of every step by the query function.
+//   - avg, min, max, sum and p95 are calculated over sample values of the step;
+//   - increase is a sum of differences between consecutive samples. A decrease of the value is a counter reset,
+//     so the value after the reset is the increase. The last sample within RangeLookback (or the step if it is
+//     longer) before the range start is the first previous one;
+//   - rate is the increase divided by the step duration.
+func (r *RangeService) QueryRange(ctx context.Context, q *model.RangeQuery) (*model.RangeResponse, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	cumulative := q.Fn == model.FnRate || q.Fn == model.FnIncrease
+
+	from := q.Start
+	if cumulative {
+		from = from.Add(-max(q.Step, RangeLookback))
+	}
+	samples, err := r.store.ListSamples(ctx, &model.MetricsV2{ID: q.ID, MType: q.MType, Labels: q.Labels}, from, q.End)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s samples from the store: %w", q.MType.String(), err)
+	}
+
+	steps := make([]step, int(q.End.Sub(q.Start)/q.Step)+1)
+	var prev *model.Sample
+	for _, s := range samples {
+		if s.Timestamp.Before(q.Start) {
+			prev = s
+			continue
+		}
+		st := &steps[int(s.Timestamp.Sub(q.Start)/q.Step)]
+		if !cumulative {
+			st.values = append(st.values, s.Value)
+			st.seen = true
+			continue
+		}
+		if prev != nil {
+			increase := s.Value - prev.Value
+			if increase < 0 {
+				increase = s.Value
+			}
+			st.increase += increase
+			st.seen = true
+		}
+		prev = s
+	}
+
+	res := &model.RangeResponse{
+		ID:     q.ID,
+		Labels: q.Labels,
+		MType:  q.MType,
+		Fn:     q.Fn,
+		Step:   q.Step.Seconds(),
+		Points: make([]*model.Sample, 0, len(steps)),
+	}
+	for i, st := range steps {
+		if !st.seen {
+			continue
+		}
+		res.Points = append(res.Points, &model.Sample{
+			Timestamp: q.Start.Add(time.Duration(i) * q.Step),
+			Value:     aggregate(q.Fn, &st, q.Step),
+		})
+	}
+	return res, nil
+}
+
+func aggregate(fn model.RangeFunction, st *step, duration time.Duration) float64 {
+	switch fn {
+	case model.FnIncrease:
+		return st.increase
+	case model.FnRate:
+		return st.increase / duration.Seconds()
+	case model.FnMin:
+		return slices.Min(st.values)
+	case model.FnMax:
+		return slices.Max(st.values)
+	case model.FnP95:
+		return quantile(st.values, 0.95)
+	}
+
+	var sum float64
+	for _, v := range st.values {
+		sum += v
+	}
+	if fn == model.FnSum {
+		return sum
+	}
+	return sum / float64(len(st.values))
+}
+
+// quantile returns the nearest-rank quantile of the values.
+func quantile(values []float64, q float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"metrics/internal/core/model"
+	"metrics/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestQueryRange(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+	point := func(seconds int, value float64) *model.Sample {
+		return &model.Sample{Timestamp: at(seconds), Value: value}
+	}
+	gauges := []*model.Sample{
+		{Timestamp: at(0), Value: 4},
+		{Timestamp: at(20), Value: 1},
+		{Timestamp: at(40), Value: 7},
+		{Timestamp: at(130), Value: 3},
+	}
+	// счетчик сбрасывается между 70 и 80 секундами
+	counters := []*model.Sample{
+		{Timestamp: at(-30), Value: 10},
+		{Timestamp: at(10), Value: 16},
+		{Timestamp: at(50), Value: 22},
+		{Timestamp: at(70), Value: 25},
+		{Timestamp: at(80), Value: 4},
+		{Timestamp: at(100), Value: 10},
+	}
+	gauge := &model.MetricsV2{ID: "HeapAlloc", MType: model.GaugeType, Labels: model.Labels{"host": "a"}}
+	counter := &model.MetricsV2{ID: "PollCount", MType: model.CounterType}
+
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	mock := mocks.NewMockStore(ctrl)
+	mock.EXPECT().ListSamples(ctx, gauge, at(0), at(150)).Return(gauges, nil).AnyTimes()
+	mock.EXPECT().ListSamples(ctx, counter, at(-3600), at(150)).Return(counters, nil).AnyTimes()
+
+	rangeService := NewRangeService(mock)
+
+	tests := []struct {
+		metric   *model.MetricsV2
+		fn       model.RangeFunction
+		expected []*model.Sample
+	}{
+		{metric: gauge, fn: model.FnAvg, expected: []*model.Sample{point(0, 4), point(120, 3)}},
+		{metric: gauge, fn: model.FnMin, expected: []*model.Sample{point(0, 1), point(120, 3)}},
+		{metric: gauge, fn: model.FnMax, expected: []*model.Sample{point(0, 7), point(120, 3)}},
+		{metric: gauge, fn: model.FnSum, expected: []*model.Sample{point(0, 12), point(120, 3)}},
+		{metric: gauge, fn: model.FnP95, expected: []*model.Sample{point(0, 7), point(120, 3)}},
+		{metric: counter, fn: model.FnIncrease, expected: []*model.Sample{point(0, 12), point(60, 13)}},
+		{metric: counter, fn: model.FnRate, expected: []*model.Sample{point(0, 0.2), point(60, 13.0/60)}},
+		{metric: counter, fn: model.FnMax, expected: []*model.Sample{point(0, 22), point(60, 25)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.metric.MType)+" "+string(tt.fn), func(t *testing.T) {
+			q := &model.RangeQuery{
+				ID:     tt.metric.ID,
+				Labels: tt.metric.Labels,
+				MType:  tt.metric.MType,
+				Fn:     tt.fn,
+				Start:  at(0),
+				End:    at(150),
+				Step:   time.Minute,
+			}
+			if tt.fn != model.FnRate && tt.fn != model.FnIncrease && tt.metric.MType == model.CounterType {
+				mock.EXPECT().ListSamples(ctx, counter, at(0), at(150)).Return(counters[1:], nil)
+			}
+			actual, err := rangeService.QueryRange(ctx, q)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual.Points)
+			assert.Equal(t, 60.0, actual.Step)
+		})
+	}
+}
+
+func TestQueryRangeSparseSamples(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	counter := &model.MetricsV2{ID: "PollCount", MType: model.CounterType}
+
+	// значения приходят раз в 5 минут, шаг - минута
+	ctrl := gomock.NewController(t)
+	mock := mocks.NewMockStore(ctrl)
+	mock.EXPECT().ListSamples(gomock.Any(), counter, start.Add(-RangeLookback), start.Add(10*time.Minute)).Return(
+		[]*model.Sample{
+			{Timestamp: start.Add(-4 * time.Minute), Value: 10},
+			{Timestamp: start.Add(time.Minute), Value: 15},
+			{Timestamp: start.Add(6 * time.Minute), Value: 18},
+		}, nil,
+	)
+
+	res, err := NewRangeService(mock).QueryRange(context.Background(), &model.RangeQuery{
+		ID:    counter.ID,
+		MType: counter.MType,
+		Fn:    model.FnIncrease,
+		Start: start,
+		End:   start.Add(10 * time.Minute),
+		Step:  time.Minute,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*model.Sample{
+		{Timestamp: start.Add(time.Minute), Value: 5},
+		{Timestamp: start.Add(6 * time.Minute), Value: 3},
+	}, res.Points)
+}
+
+func TestQueryRangeValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	rangeService := NewRangeService(mocks.NewMockStore(ctrl))
+
+	end := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	valid := model.RangeQuery{
+		ID:    "HeapAlloc",
+		MType: model.GaugeType,
+		Fn:    model.FnAvg,
+		Start: end.Add(-time.Hour),
+		End:   end,
+		Step:  time.Minute,
+	}
+
+	tests := map[string]func(q *model.RangeQuery){
+		"unknown type":     func(q *model.RangeQuery) { q.MType = "unknown" },
+		"unknown function": func(q *model.RangeQuery) { q.Fn = "median" },
+		"rate of gauge":    func(q *model.RangeQuery) { q.Fn = model.FnRate },
+		"zero step":        func(q *model.RangeQuery) { q.Step = 0 },
+		"start after end":  func(q *model.RangeQuery) { q.Start = end.Add(time.Second) },
+		"too many steps":   func(q *model.RangeQuery) { q.Step = time.Millisecond },
+	}
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			q := valid
+			change(&q)
+			_, err := rangeService.QueryRange(context.Background(), &q)
+			require.Error(t, err)
+		})
+	}
+}
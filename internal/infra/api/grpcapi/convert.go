@@ -0,0 +1,102 @@
+package grpcapi
+
+import (
+	"fmt"
+	"strconv"
+
+	"metrics/internal/core/model"
+	"metrics/internal/pb"
+)
+
+func toModelType(t pb.MetricType) (model.MetricType, error) {
+	switch t {
+	case pb.MetricType_METRIC_TYPE_GAUGE:
+		return model.GaugeType, nil
+	case pb.MetricType_METRIC_TYPE_COUNTER:
+		return model.CounterType, nil
+	case pb.MetricType_METRIC_TYPE_HISTOGRAM:
+		return model.HistogramType, nil
+	default:
+		return "", fmt.Errorf("unknown metric type: %s", t)
+	}
+}
+
+func toPBType(t model.MetricType) pb.MetricType {
+	switch t {
+	case model.GaugeType:
+		return pb.MetricType_METRIC_TYPE_GAUGE
+	case model.CounterType:
+		return pb.MetricType_METRIC_TYPE_COUNTER
+	case model.HistogramType:
+		return pb.MetricType_METRIC_TYPE_HISTOGRAM
+	default:
+		return pb.MetricType_METRIC_TYPE_UNSPECIFIED
+	}
+}
+
+// ToModel converts protobuf metric to the model.
+func ToModel(m *pb.Metric) (*model.MetricsV2, error) {
+	if m == nil {
+		return nil, fmt.Errorf("metric is required")
+	}
+	mType, err := toModelType(m.GetType())
+	if err != nil {
+		return nil, err
+	}
+	res := &model.MetricsV2{
+		ID:    m.GetId(),
+		MType: mType,
+		Delta: m.Delta,
+		Value: m.Value,
+	}
+	if len(m.GetLabels()) > 0 {
+		res.Labels = model.Labels(m.GetLabels())
+	}
+	if h := m.GetHistogram(); h != nil {
+		res.Histogram = &model.HistogramValue{Buckets: h.GetBuckets(), Counts: h.GetCounts(), Sum: h.GetSum()}
+	}
+	return res, nil
+}
+
+// ToPB converts the model to protobuf metric.
+func ToPB(m *model.MetricsV2) *pb.Metric {
+	res := &pb.Metric{
+		Id:     m.ID,
+		Type:   toPBType(m.MType),
+		Labels: m.Labels,
+		Delta:  m.Delta,
+		Value:  m.Value,
+	}
+	if m.Histogram != nil {
+		res.Histogram = &pb.Histogram{Buckets: m.Histogram.Buckets, Counts: m.Histogram.Counts, Sum: m.Histogram.Sum}
+	}
+	return res
+}
+
+// fromResponse converts an item of the metrics list to protobuf metric.
+func fromResponse(m *model.MetricResponse) (*pb.Metric, error) {
+	res := &pb.Metric{
+		Id:     m.Name,
+		Type:   toPBType(m.Type),
+		Labels: m.Labels,
+	}
+	switch m.Type {
+	case model.GaugeType:
+		value, err := strconv.ParseFloat(m.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("gauge %s has invalid value: %w", m.Name, err)
+		}
+		res.Value = &value
+	case model.CounterType:
+		delta, err := strconv.ParseInt(m.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("counter %s has invalid value: %w", m.Name, err)
+		}
+		res.Delta = &delta
+	case model.HistogramType:
+		if m.Histogram != nil {
+			res.Histogram = &pb.Histogram{Buckets: m.Histogram.Buckets, Counts: m.Histogram.Counts, Sum: m.Histogram.Sum}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,149 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+	"metrics/internal/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// MetricsServer implements pb.MetricsServer with the same services as the REST API.
+type MetricsServer struct {
+	pb.UnimplementedMetricsServer
+	metricService *service.MetricService
+	systemService service.Pinger
+}
+
+func NewMetricsServer(metricService *service.MetricService, systemService *service.SystemService) *MetricsServer {
+	return &MetricsServer{metricService: metricService, systemService: systemService}
+}
+
+func (s *MetricsServer) Update(ctx context.Context, req *pb.UpdateRequest) (*pb.UpdateResponse, error) {
+	metric, err := ToModel(req.GetMetric())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid metric: %s", err)
+	}
+	res, err := s.metricService.UpsertMetricValue(ctx, metric)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "error setting metric value: %s", err)
+	}
+	return &pb.UpdateResponse{Metric: ToPB(res)}, nil
+}
+
+func (s *MetricsServer) BatchUpdate(ctx context.Context, req *pb.BatchUpdateRequest) (*pb.BatchUpdateResponse, error) {
+	batch, err := batchToModel(req.GetMetrics())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid metric: %s", err)
+	}
+	res, err := s.metricService.IdempotentBatchUpsertMetricValue(ctx, req.GetIdempotencyKey(), batch)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "error updating metrics: %s", err)
+	}
+
+	metrics := make([]*pb.Metric, 0, len(res))
+	for _, m := range res {
+		metrics = append(metrics, ToPB(m))
+	}
+	return &pb.BatchUpdateResponse{Metrics: metrics}, nil
+}
+
+func (s *MetricsServer) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
+	mType, err := toModelType(req.GetType())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	query := &model.MetricsV2{ID: req.GetId(), MType: mType}
+	if len(req.GetLabels()) > 0 {
+		query.Labels = model.Labels(req.GetLabels())
+	}
+
+	metric, err := s.metricService.GetMetric(ctx, query)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error getting metric: %s", err)
+	}
+	if metric == nil {
+		return nil, status.Error(codes.NotFound, "metric not found")
+	}
+	return &pb.GetResponse{Metric: ToPB(metric)}, nil
+}
+
+func (s *MetricsServer) List(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	matchers, err := model.ParseLabelMatchers(req.GetMatch())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	list, err := s.metricService.ListMetrics(ctx, matchers...)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error getting list of metrics: %s", err)
+	}
+
+	metrics := make([]*pb.Metric, 0, len(list.Metrics))
+	for _, m := range list.Metrics {
+		metric, err := fromResponse(m)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		metrics = append(metrics, metric)
+	}
+	return &pb.ListResponse{Metrics: metrics}, nil
+}
+
+func (s *MetricsServer) Ping(ctx context.Context, _ *pb.PingRequest) (*pb.PingResponse, error) {
+	if err := s.systemService.Ping(ctx); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "storage is not available: %s", err)
+	}
+	return &pb.PingResponse{}, nil
+}
+
+// StreamUpdate updates batches until the client closes the stream and confirms every updated batch.
+// The data of batches is already checked and decrypted by the stream interceptors.
+func (s *MetricsServer) StreamUpdate(stream pb.Metrics_StreamUpdateServer) error {
+	var batches, metrics int64
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req pb.BatchUpdateRequest
+		if err := proto.Unmarshal(msg.GetData(), &req); err != nil {
+			return status.Errorf(codes.InvalidArgument, "error unmarshalling batch: %s", err)
+		}
+		batch, err := batchToModel(req.GetMetrics())
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid metric: %s", err)
+		}
+		_, err = s.metricService.IdempotentBatchUpsertMetricValue(stream.Context(), req.GetIdempotencyKey(), batch)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "error updating metrics: %s", err)
+		}
+		batches++
+		metrics += int64(len(batch))
+		resp := &pb.StreamUpdateResponse{Batches: batches, Metrics: metrics, IdempotencyKey: req.GetIdempotencyKey()}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func batchToModel(metrics []*pb.Metric) ([]*model.MetricsV2, error) {
+	batch := make([]*model.MetricsV2, 0, len(metrics))
+	for _, m := range metrics {
+		metric, err := ToModel(m)
+		if err != nil {
+			return nil, err
+		}
+		batch = append(batch, metric)
+	}
+	return batch, nil
+}
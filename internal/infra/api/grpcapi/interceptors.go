@@ -0,0 +1,185 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/rsa"
+	"strings"
+	"time"
+
+	"metrics/internal/core/service"
+	"metrics/internal/pb"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// LoggerInterceptor logs every call, like the ZapLogger middleware of the REST API.
+func LoggerInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logger.Info(
+			info.FullMethod,
+			zap.String("method", info.FullMethod),
+			zap.String("code", status.Code(err).String()),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return resp, err
+	}
+}
+
+// LoggerStreamInterceptor is LoggerInterceptor for streaming calls.
+func LoggerStreamInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logger.Info(
+			info.FullMethod,
+			zap.String("method", info.FullMethod),
+			zap.String("code", status.Code(err).String()),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return err
+	}
+}
+
+// GzipInterceptor compresses responses if the client accepts gzip. Compressed requests are decompressed
+// by gRPC itself, the gzip codec is registered by import of the encoding/gzip package.
+func GzipInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := setGzipCompressor(ctx); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// GzipStreamInterceptor is GzipInterceptor for streaming calls.
+func GzipStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := setGzipCompressor(ss.Context()); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func setGzipCompressor(ctx context.Context) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+	for _, accept := range md.Get("grpc-accept-encoding") {
+		if strings.Contains(accept, gzip.Name) {
+			if err := grpc.SetSendCompressor(ctx, gzip.Name); err != nil {
+				return status.Errorf(codes.Internal, "error setting compressor: %s", err)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// SignatureInterceptor checks the request signature from pb.SignatureHeader metadata
+// and signs the response with the header of the same name.
+func SignatureInterceptor(hashKey string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		msg, ok := req.(proto.Message)
+		if !ok {
+			return nil, status.Error(codes.Internal, "request is not a protobuf message")
+		}
+		var signature string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(pb.SignatureHeader); len(values) > 0 {
+				signature = values[0]
+			}
+		}
+		valid, err := pb.Sign(hashKey, msg)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if signature != valid {
+			return nil, status.Error(codes.Unauthenticated, "signature is not valid")
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if respMsg, ok := resp.(proto.Message); ok {
+			respSignature, err := pb.Sign(hashKey, respMsg)
+			if err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+			if err := grpc.SetHeader(ctx, metadata.Pairs(pb.SignatureHeader, respSignature)); err != nil {
+				return nil, status.Errorf(codes.Internal, "error setting signature header: %s", err)
+			}
+		}
+		return resp, nil
+	}
+}
+
+// batchStream calls check for every received MetricsBatch.
+type batchStream struct {
+	grpc.ServerStream
+	check func(batch *pb.MetricsBatch) error
+}
+
+func (s *batchStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if batch, ok := m.(*pb.MetricsBatch); ok {
+		return s.check(batch)
+	}
+	return nil
+}
+
+// signedStream signs responses of the batch stream. The response confirms the batch, so the signature
+// is calculated from the idempotency key and is sent in the response itself, the header is sent once per stream.
+type signedStream struct {
+	batchStream
+	hashKey string
+}
+
+func (s *signedStream) SendMsg(m any) error {
+	if resp, ok := m.(*pb.StreamUpdateResponse); ok {
+		resp.Signature = pb.SignData(s.hashKey, []byte(resp.GetIdempotencyKey()))
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// SignatureStreamInterceptor checks the signature of every received MetricsBatch and signs the responses.
+// The signature of the batch is calculated from the data as it is sent, i.e. encrypted.
+func SignatureStreamInterceptor(hashKey string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		check := func(batch *pb.MetricsBatch) error {
+			if batch.GetSignature() != pb.SignData(hashKey, batch.GetData()) {
+				return status.Error(codes.Unauthenticated, "signature is not valid")
+			}
+			return nil
+		}
+		return handler(srv, &signedStream{batchStream: batchStream{ServerStream: ss, check: check}, hashKey: hashKey})
+	}
+}
+
+// DecryptStreamInterceptor decrypts data of every received MetricsBatch, like the DecryptReqBody middleware.
+// It must follow SignatureStreamInterceptor in the chain.
+func DecryptStreamInterceptor(privateKey *rsa.PrivateKey) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		check := func(batch *pb.MetricsBatch) error {
+			data, err := service.Decrypt(privateKey, batch.GetData())
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "error decrypting batch: %s", err)
+			}
+			batch.Data = data
+			return nil
+		}
+		return handler(srv, &batchStream{ServerStream: ss, check: check})
+	}
+}
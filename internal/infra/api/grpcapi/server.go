@@ -0,0 +1,85 @@
+// Package grpcapi implements gRPC API server. It provides the same methods as the REST API.
+package grpcapi
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"net"
+
+	"metrics/internal/core/config"
+	"metrics/internal/core/service"
+	"metrics/internal/logger"
+	"metrics/internal/pb"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+type API struct {
+	srv *grpc.Server
+}
+
+// NewAPI creates gRPC server with interceptors equivalent to the REST API middlewares.
+// The private key is used to decrypt batches of StreamUpdate, it could be nil.
+func NewAPI(
+	cfg *config.Config,
+	metricService *service.MetricService,
+	systemService *service.SystemService,
+	privateKey *rsa.PrivateKey,
+) *API {
+	interceptors := []grpc.UnaryServerInterceptor{
+		LoggerInterceptor(logger.Log),
+		GzipInterceptor(),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		LoggerStreamInterceptor(logger.Log),
+		GzipStreamInterceptor(),
+	}
+	if cfg.HashKey != "" {
+		interceptors = append(interceptors, SignatureInterceptor(cfg.HashKey))
+		streamInterceptors = append(streamInterceptors, SignatureStreamInterceptor(cfg.HashKey))
+	}
+	if privateKey != nil {
+		streamInterceptors = append(streamInterceptors, DecryptStreamInterceptor(privateKey))
+	}
+
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(interceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
+	pb.RegisterMetricsServer(srv, NewMetricsServer(metricService, systemService))
+	return &API{srv: srv}
+}
+
+// Run gRPC server. It blocks until the server is stopped.
+func (api *API) Run(runAddr string) error {
+	logger.Log.Info("Run gRPC server", zap.String("Addres", runAddr))
+	listener, err := net.Listen("tcp", runAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen %s: %w", runAddr, err)
+	}
+	return api.Serve(listener)
+}
+
+// Serve accepts connections on the listener. It blocks until the server is stopped.
+func (api *API) Serve(listener net.Listener) error {
+	return api.srv.Serve(listener)
+}
+
+// Shutdown waits for running calls to finish. If the context is done earlier, the server is stopped immediately.
+func (api *API) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		api.srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		api.srv.Stop()
+		return ctx.Err()
+	}
+}
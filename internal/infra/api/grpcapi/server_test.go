@@ -0,0 +1,148 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"metrics/internal/core/config"
+	"metrics/internal/core/service"
+	"metrics/internal/infra/store/memory"
+	"metrics/internal/pb"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestClient(t *testing.T, hashKey string) pb.MetricsClient {
+	var wg sync.WaitGroup
+	store, err := memory.NewStore(
+		context.Background(),
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+		},
+	)
+	require.NoError(t, err)
+
+	api := NewAPI(&config.Config{HashKey: hashKey}, service.NewMetricService(store), service.NewSystemService(store), nil)
+	listener := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = api.Serve(listener)
+	}()
+	t.Cleanup(func() {
+		require.NoError(t, api.Shutdown(context.Background()))
+	})
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewMetricsClient(conn)
+}
+
+func TestMetricsServer(t *testing.T) {
+	client := newTestClient(t, "")
+	ctx := context.Background()
+
+	delta := int64(5)
+	counter := &pb.Metric{Id: "PollCount", Type: pb.MetricType_METRIC_TYPE_COUNTER, Delta: &delta}
+	_, err := client.Update(ctx, &pb.UpdateRequest{Metric: counter})
+	require.NoError(t, err)
+	resp, err := client.Update(ctx, &pb.UpdateRequest{Metric: counter}, grpc.UseCompressor(gzip.Name))
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), resp.GetMetric().GetDelta())
+
+	value := 1.5
+	batch, err := client.BatchUpdate(ctx, &pb.BatchUpdateRequest{Metrics: []*pb.Metric{
+		{Id: "Alloc", Type: pb.MetricType_METRIC_TYPE_GAUGE, Value: &value, Labels: map[string]string{"host": "a"}},
+		{Id: "Alloc", Type: pb.MetricType_METRIC_TYPE_GAUGE, Value: &value, Labels: map[string]string{"host": "b"}},
+		{Id: "Latency", Type: pb.MetricType_METRIC_TYPE_HISTOGRAM, Value: &value},
+	}})
+	require.NoError(t, err)
+	assert.Len(t, batch.GetMetrics(), 3)
+
+	got, err := client.Get(ctx, &pb.GetRequest{
+		Id:     "Alloc",
+		Type:   pb.MetricType_METRIC_TYPE_GAUGE,
+		Labels: map[string]string{"host": "b"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, got.GetMetric().GetValue())
+	assert.Equal(t, map[string]string{"host": "b"}, got.GetMetric().GetLabels())
+
+	_, err = client.Get(ctx, &pb.GetRequest{Id: "Unknown", Type: pb.MetricType_METRIC_TYPE_GAUGE})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	list, err := client.List(ctx, &pb.ListRequest{Match: []string{"host=a"}})
+	require.NoError(t, err)
+	require.Len(t, list.GetMetrics(), 1)
+	assert.Equal(t, "Alloc", list.GetMetrics()[0].GetId())
+
+	list, err = client.List(ctx, &pb.ListRequest{})
+	require.NoError(t, err)
+	assert.Len(t, list.GetMetrics(), 4)
+
+	_, err = client.Update(ctx, &pb.UpdateRequest{Metric: &pb.Metric{Id: "a"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.List(ctx, &pb.ListRequest{Match: []string{"host"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// memory store doesn't support ping
+	_, err = client.Ping(ctx, &pb.PingRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestSignatureInterceptor(t *testing.T) {
+	const key = "secret"
+	client := newTestClient(t, key)
+
+	delta := int64(1)
+	req := &pb.UpdateRequest{Metric: &pb.Metric{Id: "PollCount", Type: pb.MetricType_METRIC_TYPE_COUNTER, Delta: &delta}}
+
+	_, err := client.Update(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	wrong, err := pb.Sign("wrong", req)
+	require.NoError(t, err)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), pb.SignatureHeader, wrong)
+	_, err = client.Update(ctx, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	signature, err := pb.Sign(key, req)
+	require.NoError(t, err)
+	ctx = metadata.AppendToOutgoingContext(context.Background(), pb.SignatureHeader, signature)
+	var header metadata.MD
+	resp, err := client.Update(ctx, req, grpc.Header(&header))
+	require.NoError(t, err)
+
+	respSignature, err := pb.Sign(key, resp)
+	require.NoError(t, err)
+	assert.Equal(t, []string{respSignature}, header.Get(pb.SignatureHeader))
+
+	// the signature doesn't depend on the map order
+	req.Metric.Labels = map[string]string{"a": "1", "b": "2", "c": "3"}
+	first, err := pb.Sign(key, req)
+	require.NoError(t, err)
+	clone := proto.Clone(req).(*pb.UpdateRequest)
+	second, err := pb.Sign(key, clone)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+}
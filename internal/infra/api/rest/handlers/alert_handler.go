@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+
+	"metrics/internal/core/alert"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AlertHandler struct {
+	engine *alert.Engine
+}
+
+func NewAlertHandler(engine *alert.Engine) *AlertHandler {
+	return &AlertHandler{engine: engine}
+}
+
+// List alerts API handler
+// @Tags Alerting
+// @Summary List active alerts
+// @Description Pending and firing alerts sorted by rule name and labels
+// @ID ListAlertsHandler
+// @Produce json
+// @Success 200 {array} alert.Alert
+// @Router /alerts [GET]
+func (h *AlertHandler) ListHandler(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, h.engine.Alerts())
+}
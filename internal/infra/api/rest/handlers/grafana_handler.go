@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"metrics/internal/core/alert"
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+	"metrics/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GrafanaHandler implements Grafana JSON datasource protocol.
+// A target is a metric type and name `counter:PollCount`, the type could be omitted for gauges.
+// Every series of the metric selected by ad hoc filters is returned, labels in the target select a single series:
+// `gauge:HeapAlloc{host="a"}`.
+type GrafanaHandler struct {
+	metricService *service.MetricService
+	rangeService  *service.RangeService
+	alertEngine   *alert.Engine
+}
+
+func NewGrafanaHandler(
+	metricService *service.MetricService, rangeService *service.RangeService, alertEngine *alert.Engine,
+) *GrafanaHandler {
+	return &GrafanaHandler{metricService: metricService, rangeService: rangeService, alertEngine: alertEngine}
+}
+
+type GrafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type GrafanaSearchRequest struct {
+	Target string `json:"target"`
+}
+
+type GrafanaTarget struct {
+	Payload struct {
+		Fn model.RangeFunction `json:"fn"` // avg by default
+	} `json:"payload"`
+	Target string `json:"target"`
+	RefID  string `json:"refId"`
+}
+
+type GrafanaAdhocFilter struct {
+	Key      string `json:"key"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+type GrafanaQueryRequest struct {
+	Range         GrafanaRange          `json:"range"`
+	Targets       []*GrafanaTarget      `json:"targets"`
+	AdhocFilters  []*GrafanaAdhocFilter `json:"adhocFilters"`
+	IntervalMs    int64                 `json:"intervalMs"`
+	MaxDataPoints int64                 `json:"maxDataPoints"`
+}
+
+// GrafanaSeries contains datapoints as [value, unix milliseconds] pairs.
+type GrafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+type GrafanaAnnotationRequest struct {
+	Range      GrafanaRange   `json:"range"`
+	Annotation map[string]any `json:"annotation"`
+}
+
+type GrafanaAnnotation struct {
+	Annotation map[string]any `json:"annotation"`
+	Title      string         `json:"title"`
+	Text       string         `json:"text"`
+	Tags       []string       `json:"tags"`
+	Time       int64          `json:"time"`
+	TimeEnd    int64          `json:"timeEnd,omitempty"`
+}
+
+type GrafanaTag struct {
+	Type string `json:"type,omitempty"`
+	Text string `json:"text"`
+}
+
+type GrafanaTagValuesRequest struct {
+	Key string `json:"key"`
+}
+
+// Grafana datasource test handler
+// @Tags Grafana
+// @Summary Test connection
+// @Description Grafana checks the datasource is available
+// @ID GrafanaTestHandler
+// @Produce json
+// @Success 200 {string} string "OK"
+// @Router /grafana/ [GET]
+func (h *GrafanaHandler) TestHandler(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": true})
+}
+
+// Grafana search handler
+// @Tags Grafana
+// @Summary Search metrics
+// @Description Targets of metrics which names contain the requested string
+// @ID GrafanaSearchHandler
+// @Accept  json
+// @Produce json
+// @Param req body GrafanaSearchRequest true "Search request"
+// @Success 200 {array} string
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Inernal Server Error"
+// @Router /grafana/search [POST]
+func (h *GrafanaHandler) SearchHandler(ctx *gin.Context) {
+	req := &GrafanaSearchRequest{}
+	if !bindGrafanaRequest(ctx, req) {
+		return
+	}
+
+	targets := make([]string, 0)
+	seen := make(map[string]bool)
+	q := &model.MetricsQuery{Match: "*" + req.Target + "*"}
+	err := h.eachMetric(ctx, q, func(m *model.MetricsV2) {
+		target := m.MType.String() + ":" + m.ID
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	})
+	if err != nil {
+		abortGrafana(ctx, http.StatusInternalServerError, "Error searching metrics", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, targets)
+}
+
+// Grafana query handler
+// @Tags Grafana
+// @Summary Query time series
+// @Description Samples of the targets averaged by the request interval
+// @ID GrafanaQueryHandler
+// @Accept  json
+// @Produce json
+// @Param req body GrafanaQueryRequest true "Query request"
+// @Success 200 {array} GrafanaSeries
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Inernal Server Error"
+// @Router /grafana/query [POST]
+func (h *GrafanaHandler) QueryHandler(ctx *gin.Context) {
+	req := &GrafanaQueryRequest{}
+	if !bindGrafanaRequest(ctx, req) {
+		return
+	}
+	matchers, err := adhocMatchers(req.AdhocFilters)
+	if err != nil {
+		abortGrafana(ctx, http.StatusBadRequest, "Error parsing ad hoc filters", err)
+		return
+	}
+	step := grafanaStep(req)
+
+	result := make([]*GrafanaSeries, 0, len(req.Targets))
+	for _, target := range req.Targets {
+		mType, name, labels, err := parseGrafanaTarget(target.Target)
+		if err != nil {
+			abortGrafana(ctx, http.StatusBadRequest, "Error parsing target", err)
+			return
+		}
+		fn := target.Payload.Fn
+		if fn == "" {
+			fn = model.FnAvg
+		}
+		q := model.RangeQuery{ID: name, MType: mType, Fn: fn, Start: req.Range.From, End: req.Range.To, Step: step}
+		if err := q.Validate(); err != nil {
+			abortGrafana(ctx, http.StatusBadRequest, "Error parsing target", err)
+			return
+		}
+
+		series := []model.Labels{labels}
+		if labels == nil {
+			series, err = h.series(ctx, mType, name, matchers)
+			if err != nil {
+				abortGrafana(ctx, http.StatusInternalServerError, "Error listing series", err)
+				return
+			}
+		}
+
+		for _, labels := range series {
+			q.Labels = labels
+			res, err := h.rangeService.QueryRange(ctx, &q)
+			if err != nil {
+				abortGrafana(ctx, http.StatusInternalServerError, "Error querying range", err)
+				return
+			}
+			datapoints := make([][2]float64, 0, len(res.Points))
+			for _, p := range res.Points {
+				datapoints = append(datapoints, [2]float64{p.Value, float64(p.Timestamp.UnixMilli())})
+			}
+			result = append(result, &GrafanaSeries{Target: model.MetricKey(name, labels), Datapoints: datapoints})
+		}
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// Grafana annotations handler
+// @Tags Grafana
+// @Summary Alert annotations
+// @Description Active alerts of the range. The annotation query selects alerts by the rule name.
+// @ID GrafanaAnnotationsHandler
+// @Accept  json
+// @Produce json
+// @Param req body GrafanaAnnotationRequest true "Annotations request"
+// @Success 200 {array} GrafanaAnnotation
+// @Failure 400 {string} string "Bad request"
+// @Router /grafana/annotations [POST]
+func (h *GrafanaHandler) AnnotationsHandler(ctx *gin.Context) {
+	req := &GrafanaAnnotationRequest{}
+	if !bindGrafanaRequest(ctx, req) {
+		return
+	}
+	rule, _ := req.Annotation["query"].(string)
+
+	result := make([]*GrafanaAnnotation, 0)
+	for _, a := range h.alertEngine.Alerts() {
+		if rule != "" && a.Rule != rule {
+			continue
+		}
+		if a.ActiveAt.After(req.Range.To) || (a.ResolvedAt != nil && a.ResolvedAt.Before(req.Range.From)) {
+			continue
+		}
+		annotation := &GrafanaAnnotation{
+			Annotation: req.Annotation,
+			Title:      fmt.Sprintf("%s (%s)", a.Rule, a.State),
+			Text:       fmt.Sprintf("%s = %g", a.Metric, a.Value),
+			Tags:       []string{a.Rule, string(a.State)},
+			Time:       a.ActiveAt.UnixMilli(),
+		}
+		if a.ResolvedAt != nil {
+			annotation.TimeEnd = a.ResolvedAt.UnixMilli()
+		}
+		result = append(result, annotation)
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// Grafana tag keys handler
+// @Tags Grafana
+// @Summary Label names
+// @Description Label names of all metrics for ad hoc filters
+// @ID GrafanaTagKeysHandler
+// @Produce json
+// @Success 200 {array} GrafanaTag
+// @Failure 500 {string} string "Inernal Server Error"
+// @Router /grafana/tag-keys [POST]
+func (h *GrafanaHandler) TagKeysHandler(ctx *gin.Context) {
+	names := make(map[string]bool)
+	err := h.eachMetric(ctx, &model.MetricsQuery{}, func(m *model.MetricsV2) {
+		for name := range m.Labels {
+			names[name] = true
+		}
+	})
+	if err != nil {
+		abortGrafana(ctx, http.StatusInternalServerError, "Error listing metrics", err)
+		return
+	}
+
+	result := make([]*GrafanaTag, 0, len(names))
+	for _, name := range sortedSet(names) {
+		result = append(result, &GrafanaTag{Type: "string", Text: name})
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// Grafana tag values handler
+// @Tags Grafana
+// @Summary Label values
+// @Description Values of the label for ad hoc filters
+// @ID GrafanaTagValuesHandler
+// @Accept  json
+// @Produce json
+// @Param req body GrafanaTagValuesRequest true "Label name"
+// @Success 200 {array} GrafanaTag
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Inernal Server Error"
+// @Router /grafana/tag-values [POST]
+func (h *GrafanaHandler) TagValuesHandler(ctx *gin.Context) {
+	req := &GrafanaTagValuesRequest{}
+	if !bindGrafanaRequest(ctx, req) {
+		return
+	}
+
+	values := make(map[string]bool)
+	err := h.eachMetric(ctx, &model.MetricsQuery{}, func(m *model.MetricsV2) {
+		if value, ok := m.Labels[req.Key]; ok {
+			values[value] = true
+		}
+	})
+	if err != nil {
+		abortGrafana(ctx, http.StatusInternalServerError, "Error listing metrics", err)
+		return
+	}
+
+	result := make([]*GrafanaTag, 0, len(values))
+	for _, value := range sortedSet(values) {
+		result = append(result, &GrafanaTag{Text: value})
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// eachMetric calls fn for every metric selected by the query, metrics are read page by page.
+func (h *GrafanaHandler) eachMetric(ctx context.Context, q *model.MetricsQuery, fn func(m *model.MetricsV2)) error {
+	q.Sort = model.SortByName
+	q.Limit = model.MaxQueryLimit
+	for {
+		page, err := h.metricService.QueryMetrics(ctx, q)
+		if err != nil {
+			return err
+		}
+		for _, m := range page.Metrics {
+			fn(m)
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		q.After = model.NewMetricsCursor(page.Metrics[len(page.Metrics)-1])
+	}
+}
+
+// series returns label sets of the metric which satisfy the matchers.
+func (h *GrafanaHandler) series(
+	ctx context.Context, mType model.MetricType, name string, matchers []*model.LabelMatcher,
+) ([]model.Labels, error) {
+	series := make([]model.Labels, 0)
+	err := h.eachMetric(ctx, &model.MetricsQuery{MType: mType, Match: name}, func(m *model.MetricsV2) {
+		if m.ID == name && model.MatchLabels(m.Labels, matchers) {
+			series = append(series, m.Labels)
+		}
+	})
+	return series, err
+}
+
+// grafanaStep returns the request interval limited by MaxDataPoints and model.MaxRangePoints.
+func grafanaStep(req *GrafanaQueryRequest) time.Duration {
+	step := time.Duration(req.IntervalMs) * time.Millisecond
+	duration := req.Range.To.Sub(req.Range.From)
+	if req.MaxDataPoints > 0 {
+		step = max(step, duration/time.Duration(req.MaxDataPoints))
+	}
+	return max(step, duration/(model.MaxRangePoints-1), time.Second)
+}
+
+// parseGrafanaTarget parses `type:name{labels}` target, the type is gauge if the prefix is not a metric type.
+func parseGrafanaTarget(target string) (model.MetricType, string, model.Labels, error) {
+	mType := model.GaugeType
+	if prefix, rest, ok := strings.Cut(target, ":"); ok {
+		switch model.MetricType(prefix) {
+		case model.GaugeType, model.CounterType, model.HistogramType:
+			mType, target = model.MetricType(prefix), rest
+		}
+	}
+	name, labels, err := model.ParseMetricKey(target)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if name == "" {
+		return "", "", nil, fmt.Errorf("metric name is required: %q", target)
+	}
+	return mType, name, labels, nil
+}
+
+func adhocMatchers(filters []*GrafanaAdhocFilter) ([]*model.LabelMatcher, error) {
+	matchers := make([]*model.LabelMatcher, 0, len(filters))
+	for _, f := range filters {
+		m, err := model.NewLabelMatcher(f.Key, model.MatchType(f.Operator), f.Value)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+func sortedSet(set map[string]bool) []string {
+	items := make([]string, 0, len(set))
+	for item := range set {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	return items
+}
+
+func bindGrafanaRequest(ctx *gin.Context, req any) bool {
+	if err := ctx.ShouldBindBodyWithJSON(req); err != nil {
+		abortGrafana(ctx, http.StatusBadRequest, "Error binding body", err)
+		return false
+	}
+	return true
+}
+
+func abortGrafana(ctx *gin.Context, code int, message string, err error) {
+	logger.Log.Error(message, zap.Error(err))
+	ctx.AbortWithStatusJSON(code, gin.H{"status": false, "message": fmt.Sprintf("%s: %s", message, err)})
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"metrics/internal/core/alert"
+	"metrics/internal/core/config"
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+	"metrics/internal/infra/store/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrafanaHandler(t *testing.T) {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	store, err := memory.NewStore(
+		ctx,
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+			HistorySize:     10,
+		},
+	)
+	require.NoError(t, err)
+	for host, value := range map[string]float64{"a": 1, "b": 2} {
+		err = store.SetGauge(ctx, &model.Gauge{Name: "HeapAlloc", Labels: model.Labels{"host": host}, Value: value})
+		require.NoError(t, err)
+	}
+	err = store.SetCounter(ctx, &model.Counter{Name: "PollCount", Labels: model.Labels{"region": "eu"}, Value: 5})
+	require.NoError(t, err)
+
+	threshold := 1.5
+	rule := &alert.Rule{
+		Name:      "HighHeap",
+		Metric:    "HeapAlloc",
+		Type:      model.GaugeType,
+		Condition: alert.Greater,
+		Threshold: &threshold,
+	}
+	require.NoError(t, rule.Validate())
+	engine := alert.NewEngine(store, []*alert.Rule{rule}, time.Minute, nil)
+	require.NoError(t, engine.Evaluate(ctx))
+
+	handler := NewGrafanaHandler(service.NewMetricService(store), service.NewRangeService(store), engine)
+
+	from := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	to := time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+	rangeJSON := `"range": {"from": "` + from + `", "to": "` + to + `"}`
+
+	tests := []struct {
+		handler  gin.HandlerFunc
+		name     string
+		body     string
+		response string
+		series   string // target of the single series returned by the query
+		code     int
+	}{
+		{
+			name:     "search",
+			handler:  handler.SearchHandler,
+			body:     `{"target": "Heap"}`,
+			code:     http.StatusOK,
+			response: `["gauge:HeapAlloc"]`,
+		},
+		{
+			name:     "search all",
+			handler:  handler.SearchHandler,
+			body:     `{"target": ""}`,
+			code:     http.StatusOK,
+			response: `["gauge:HeapAlloc", "counter:PollCount"]`,
+		},
+		{
+			name:    "query with ad hoc filter",
+			handler: handler.QueryHandler,
+			body: `{` + rangeJSON + `, "intervalMs": 3600000, "targets": [{"target": "HeapAlloc", "refId": "A"}],
+				"adhocFilters": [{"key": "host", "operator": "=", "value": "b"}]}`,
+			code:   http.StatusOK,
+			series: `HeapAlloc{host="b"}`,
+		},
+		{
+			name:    "query series",
+			handler: handler.QueryHandler,
+			body: `{` + rangeJSON + `,
+				"targets": [{"target": "counter:PollCount{region=\"eu\"}", "payload": {"fn": "max"}}]}`,
+			code:   http.StatusOK,
+			series: `PollCount{region="eu"}`,
+		},
+		{
+			name:     "invalid target",
+			handler:  handler.QueryHandler,
+			body:     `{` + rangeJSON + `, "targets": [{"target": "counter:{region=\"eu\"}"}]}`,
+			code:     http.StatusBadRequest,
+			response: `{"status": false, "message": "Error parsing target: could not parse metric: \"{region=\\\"eu\\\"}\""}`,
+		},
+		{
+			name:     "invalid function",
+			handler:  handler.QueryHandler,
+			body:     `{` + rangeJSON + `, "targets": [{"target": "HeapAlloc", "payload": {"fn": "median"}}]}`,
+			code:     http.StatusBadRequest,
+			response: `{"status": false, "message": "Error parsing target: unknown range function: median"}`,
+		},
+		{
+			name:     "invalid filter",
+			handler:  handler.QueryHandler,
+			body:     `{` + rangeJSON + `, "targets": [], "adhocFilters": [{"key": "host", "operator": ">", "value": "b"}]}`,
+			code:     http.StatusBadRequest,
+			response: `{"status": false, "message": "Error parsing ad hoc filters: unknown label match type: >"}`,
+		},
+		{
+			name:     "tag keys",
+			handler:  handler.TagKeysHandler,
+			code:     http.StatusOK,
+			response: `[{"type": "string", "text": "host"}, {"type": "string", "text": "region"}]`,
+		},
+		{
+			name:     "tag values",
+			handler:  handler.TagValuesHandler,
+			body:     `{"key": "host"}`,
+			code:     http.StatusOK,
+			response: `[{"text": "a"}, {"text": "b"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/grafana", strings.NewReader(tt.body))
+
+			tt.handler(c)
+
+			assert.Equal(t, tt.code, w.Code)
+			if tt.series == "" {
+				assert.JSONEq(t, tt.response, w.Body.String())
+				return
+			}
+			// время точек зависит от момента записи, проверяем только серии
+			var series []*GrafanaSeries
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &series))
+			require.Len(t, series, 1)
+			assert.Equal(t, tt.series, series[0].Target)
+			assert.Len(t, series[0].Datapoints, 1)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(
+		http.MethodPost, "/grafana/annotations", strings.NewReader(`{`+rangeJSON+`, "annotation": {"query": "HighHeap"}}`),
+	)
+	handler.AnnotationsHandler(c)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"title":"HighHeap (firing)"`)
+	assert.Contains(t, w.Body.String(), `"text":"HeapAlloc{host=\"b\"} = 2"`)
+
+	// хранилище без истории не может выполнить запрос
+	noHistory, err := memory.NewStore(
+		ctx,
+		&wg,
+		&config.StorageConfig{StoreIntreval: 1000, FileStoragePath: "/tmp/storage_dump.json"},
+	)
+	require.NoError(t, err)
+	handler = NewGrafanaHandler(service.NewMetricService(noHistory), service.NewRangeService(noHistory), engine)
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	body := `{` + rangeJSON + `, "targets": [{"target": "HeapAlloc{host=\"a\"}"}]}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/grafana/query", strings.NewReader(body))
+	handler.QueryHandler(c)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -5,6 +5,7 @@ package handlers
 
 import (
 	"fmt"
+	"html"
 	"net/http"
 	"strconv"
 
@@ -34,7 +35,7 @@ func NewHandlerV1(metricService *service.MetricService) *HandlerV1 {
 // @ID UpdateHandler
 // @Param name path string true "Metric name"
 // @Param type path string true "Metric type"
-// @Param value path string true "Metric value "
+// @Param value path string true "Metric value. For histogram it is a single observation"
 // @Success 200
 // @Failure 400 {string} string "Bad request"
 // @Failure 500 {string} string "Inernal Server Error"
@@ -75,6 +76,7 @@ func (h *HandlerV1) UpdateHandler(ctx *gin.Context) {
 // @ID GetHandler
 // @Param name path string true "Metric name"
 // @Param type path string true "Metric type"
+// @Param match query []string false "Label matchers: host=a, host!=a, host=~a.*, host!~a.*" collectionFormat(multi)
 // @Success 200 {string} string "Ok"
 // @Failure 400 {string} string "Bad request"
 // @Failure 404 {string} string "Not found"
@@ -101,7 +103,14 @@ func (h *HandlerV1) GetHandler(ctx *gin.Context) {
 		return
 	}
 
-	metric, err := h.metricService.GetMetric(ctx, reqV2)
+	matchers, err := model.ParseLabelMatchers(ctx.QueryArray("match"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		log.Error("Error parsing label matchers", zap.Error(err))
+		return
+	}
+
+	metric, err := h.metricService.FindMetric(ctx, reqV2, matchers)
 	if err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
 		logger.Log.Error("Error getting metric", zap.Error(err))
@@ -113,9 +122,18 @@ func (h *HandlerV1) GetHandler(ctx *gin.Context) {
 		return
 	}
 
-	if metric.MType == model.CounterType {
+	switch metric.MType {
+	case model.CounterType:
 		ctx.String(http.StatusOK, strconv.FormatInt(*metric.Delta, 10))
-	} else {
+	case model.HistogramType:
+		histogram := model.Histogram{
+			Name:    metric.ID,
+			Buckets: metric.Histogram.Buckets,
+			Counts:  metric.Histogram.Counts,
+			Sum:     metric.Histogram.Sum,
+		}
+		ctx.String(http.StatusOK, histogram.StringValue())
+	default:
 		ctx.String(http.StatusOK, strconv.FormatFloat(*metric.Value, 'f', -1, 64))
 	}
 }
@@ -125,12 +143,19 @@ func (h *HandlerV1) GetHandler(ctx *gin.Context) {
 // @Summary List metrics
 // @Description Get metric all from storage
 // @ID ListHandler
+// @Param match query []string false "Label matchers: host=a, host!=a, host=~a.*, host!~a.*" collectionFormat(multi)
 // @Success 200 {string} string "Metrics list"
 // @Failure 400 {string} string "Bad request"
 // @Failure 500 {string} string "Inernal Server Error"
 // @Router / [GET]
 func (h *HandlerV1) ListHandler(ctx *gin.Context) {
-	metrics, err := h.metricService.ListMetrics(ctx)
+	matchers, err := model.ParseLabelMatchers(ctx.QueryArray("match"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	metrics, err := h.metricService.ListMetrics(ctx, matchers...)
 	if err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
 		return
@@ -139,7 +164,7 @@ func (h *HandlerV1) ListHandler(ctx *gin.Context) {
 	content := "<html><body><ul>%s</ul></body></html>"
 	var listItems string
 	for _, m := range metrics.Metrics {
-		listItems += "<li><strong>" + m.Name + "</strong>: " + m.Value + "</li>"
+		listItems += "<li><strong>" + html.EscapeString(model.MetricKey(m.Name, m.Labels)) + "</strong>: " + m.Value + "</li>"
 	}
 
 	ctx.Header("Content-Type", "text/html")
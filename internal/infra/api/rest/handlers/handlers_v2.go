@@ -6,6 +6,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"metrics/internal/core/model"
@@ -71,10 +73,11 @@ func (h *HandlerV2) UpdateHandler(ctx *gin.Context) {
 // Batch metrics update API handler
 // @Tags V2 API
 // @Summary Batch update
-// @Description
+// @Description The batch with Idempotency-Key header is applied once, a retry returns the result of the first request.
 // @ID BatchUpdateHandler
 // @Accept  json
 // @Produce json
+// @Param Idempotency-Key header string false "Batch ID"
 // @Param req body []model.MetricsV2 true "Metrics request"
 // @Success 200 {object} []model.MetricsV2
 // @Failure 400 {string} string "Bad request"
@@ -90,9 +93,10 @@ func (h *HandlerV2) BatchUpdateHandler(ctx *gin.Context) {
 		)
 		return
 	}
-	logger.Log.Debug("Getting update request")
+	key := ctx.GetHeader("Idempotency-Key")
+	logger.Log.Debug("Getting update request", zap.String("key", key))
 
-	metrics, err := h.metricService.BatchUpsertMetricValue(ctx, req)
+	metrics, err := h.metricService.IdempotentBatchUpsertMetricValue(ctx, key, req)
 	if err != nil {
 		logger.Log.Error("Batch update error", zap.Error(err))
 		ctx.AbortWithStatusJSON(
@@ -152,3 +156,116 @@ func (h *HandlerV2) GetHandler(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, metric)
 }
+
+// Metric history API handler
+// @Tags V2 API
+// @Summary Get metric history
+// @Description Metric values recorded between two timestamps ordered by time
+// @ID HistoryHandler
+// @Accept  json
+// @Produce json
+// @Param req body model.HistoryRequest true "History request"
+// @Success 200 {object} model.HistoryResponse
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Inernal Server Error"
+// @Router /history/ [POST]
+func (h *HandlerV2) HistoryHandler(ctx *gin.Context) {
+	req := &model.HistoryRequest{}
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		logger.Log.Error("Error binding body", zap.Error(err))
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			gin.H{"status": false, "message": fmt.Sprintf("Error binding body: %s", err)},
+		)
+		return
+	}
+	log := logger.Log.With(
+		zap.String("name", req.ID),
+		zap.String("type", req.MType.String()),
+		zap.Time("from", req.From),
+		zap.Time("to", req.To),
+	)
+	log.Debug("Getting history for metric")
+
+	history, err := h.metricService.GetHistory(ctx, req)
+	if err != nil {
+		log.Error("Error getting metric history", zap.Error(err))
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			gin.H{"status": false, "message": fmt.Sprintf("Error getting metric history: %s", err)},
+		)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, history)
+}
+
+// Metrics query API handler
+// @Tags V2 API
+// @Summary Query metrics
+// @Description Metrics filtered by type and name ordered by the sort key. Use next_cursor to get the next page.
+// @ID QueryHandler
+// @Produce json
+// @Param type query string false "Metric type: gauge, counter or histogram"
+// @Param match query string false "Metric name glob: Heap*, *Alloc, Gauge?"
+// @Param sort query string false "Sort key: name, type, -name or -type for descending order" default(name)
+// @Param limit query int false "Page size" default(100) maximum(1000)
+// @Param cursor query string false "Cursor of the previous page"
+// @Success 200 {object} model.MetricsPage
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Inernal Server Error"
+// @Router /api/v2/metrics [GET]
+func (h *HandlerV2) QueryHandler(ctx *gin.Context) {
+	q, err := parseMetricsQuery(ctx)
+	if err == nil {
+		err = q.Validate()
+	}
+	if err != nil {
+		logger.Log.Error("Error parsing metrics query", zap.Error(err))
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			gin.H{"status": false, "message": fmt.Sprintf("Error parsing query: %s", err)},
+		)
+		return
+	}
+
+	page, err := h.metricService.QueryMetrics(ctx, q)
+	if err != nil {
+		logger.Log.Error("Error querying metrics", zap.Error(err))
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			gin.H{"status": false, "message": fmt.Sprintf("Error querying metrics: %s", err)},
+		)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, page)
+}
+
+func parseMetricsQuery(ctx *gin.Context) (*model.MetricsQuery, error) {
+	q := &model.MetricsQuery{
+		MType: model.MetricType(ctx.Query("type")),
+		Match: ctx.Query("match"),
+		Sort:  model.MetricsSort(ctx.DefaultQuery("sort", string(model.SortByName))),
+		Limit: model.DefaultQueryLimit,
+	}
+	if strings.HasPrefix(string(q.Sort), "-") {
+		q.Sort = q.Sort[1:]
+		q.Desc = true
+	}
+	if limit, ok := ctx.GetQuery("limit"); ok {
+		var err error
+		q.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit: %q", limit)
+		}
+	}
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		var err error
+		q.After, err = model.DecodeMetricsCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
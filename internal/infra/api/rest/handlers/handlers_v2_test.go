@@ -157,6 +157,57 @@ func TestUpdateRequestsWithTheSameStore(t *testing.T) {
 	}
 }
 
+func TestBatchUpdateHandlerIdempotencyKey(t *testing.T) {
+	var wg sync.WaitGroup
+	store, err := memory.NewStore(
+		context.Background(),
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+		},
+	)
+	require.NoError(t, err)
+	handler := NewHandlerV2(service.NewMetricService(store))
+
+	tests := []struct {
+		name     string
+		key      string
+		response string
+		code     int
+	}{
+		{name: "first request", key: "batch-1", code: 200, response: `[{"delta":10, "id":"counter01", "type":"counter"}]`},
+		// повтор с тем же ключом не увеличивает счетчик
+		{name: "retry", key: "batch-1", code: 200, response: `[{"delta":10, "id":"counter01", "type":"counter"}]`},
+		{name: "next batch", key: "batch-2", code: 200, response: `[{"delta":20, "id":"counter01", "type":"counter"}]`},
+		{name: "without key", code: 200, response: `[{"delta":30, "id":"counter01", "type":"counter"}]`},
+		{
+			name:     "too long key",
+			key:      strings.Repeat("k", model.MaxIdempotencyKeyLength+1),
+			code:     400,
+			response: `{"status":false, "message":"Batch upsert error: idempotency key is longer than 255"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			body := `[{"id":"counter01", "type":"counter", "delta":10}]`
+			c.Request = httptest.NewRequest(http.MethodPost, "/updates", strings.NewReader(body))
+			if tt.key != "" {
+				c.Request.Header.Set("Idempotency-Key", tt.key)
+			}
+
+			handler.BatchUpdateHandler(c)
+
+			assert.Equal(t, tt.code, w.Code)
+			assert.JSONEq(t, tt.response, w.Body.String())
+		})
+	}
+}
+
 func TestGetHandler(t *testing.T) {
 	var ten int64 = 10
 
@@ -220,6 +271,59 @@ func TestGetHandler(t *testing.T) {
 	}
 }
 
+func TestGetHandlerWithLabels(t *testing.T) {
+	var wg sync.WaitGroup
+	store, err := memory.NewStore(
+		context.Background(),
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+		},
+	)
+	require.NoError(t, err)
+	for host, value := range map[string]int64{"a": 1, "b": 2} {
+		counter := &model.Counter{Name: "requests", Labels: model.Labels{"host": host}, Value: value}
+		err = store.SetCounter(context.Background(), counter)
+		require.NoError(t, err)
+	}
+	handler := NewHandlerV2(service.NewMetricService(store))
+
+	tests := []struct {
+		metric   model.MetricsV2
+		response string
+		code     int
+	}{
+		{
+			metric:   model.MetricsV2{ID: "requests", MType: model.CounterType, Labels: model.Labels{"host": "b"}},
+			code:     http.StatusOK,
+			response: `{"delta":2, "id":"requests", "type":"counter", "labels":{"host":"b"}}`,
+		},
+		{
+			metric:   model.MetricsV2{ID: "requests", MType: model.CounterType},
+			code:     http.StatusNotFound,
+			response: `{"status":false, "message":"Not found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.metric.Key(), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			body, err := json.Marshal(tt.metric)
+			require.NoError(t, err)
+			c.Request = httptest.NewRequest(http.MethodPost, "/value", strings.NewReader(string(body)))
+
+			handler.GetHandler(c)
+
+			assert.Equal(t, tt.code, w.Code)
+			assert.JSONEq(t, tt.response, w.Body.String())
+		})
+	}
+}
+
 func ExampleHandlerV2_UpdateHandler() {
 	var ten int64 = 10
 
@@ -257,3 +361,69 @@ func ExampleHandlerV2_UpdateHandler() {
 	// 200
 	// {"delta":10,"id":"counter","type":"counter"}
 }
+
+func TestQueryHandler(t *testing.T) {
+	var wg sync.WaitGroup
+	store, err := memory.NewStore(
+		context.Background(),
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+		},
+	)
+	require.NoError(t, err)
+	for name, value := range map[string]float64{"HeapAlloc": 1, "HeapIdle": 2, "HeapInuse": 3, "StackInuse": 4} {
+		err = store.SetGauge(context.Background(), &model.Gauge{Name: name, Value: value})
+		require.NoError(t, err)
+	}
+	err = store.SetCounter(context.Background(), &model.Counter{Name: "HeapObjects", Value: 5})
+	require.NoError(t, err)
+	handler := NewHandlerV2(service.NewMetricService(store))
+
+	query := func(url string) (int, *model.MetricsPage) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, url, nil)
+
+		handler.QueryHandler(c)
+
+		page := &model.MetricsPage{}
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), page))
+		}
+		return w.Code, page
+	}
+
+	names := make([]string, 0)
+	url := "/api/v2/metrics?type=gauge&match=Heap*&sort=-name&limit=2"
+	for i := 0; i < 3; i++ {
+		code, page := query(url)
+		require.Equal(t, http.StatusOK, code)
+		for _, m := range page.Metrics {
+			names = append(names, m.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		url = "/api/v2/metrics?type=gauge&match=Heap*&sort=-name&limit=2&cursor=" + page.NextCursor
+	}
+	assert.Equal(t, []string{"HeapInuse", "HeapIdle", "HeapAlloc"}, names)
+
+	code, page := query("/api/v2/metrics")
+	require.Equal(t, http.StatusOK, code)
+	assert.Len(t, page.Metrics, 5)
+	assert.Empty(t, page.NextCursor)
+
+	for _, url := range []string{
+		"/api/v2/metrics?type=summary",
+		"/api/v2/metrics?sort=value",
+		"/api/v2/metrics?limit=0",
+		"/api/v2/metrics?limit=ten",
+		"/api/v2/metrics?cursor=abc",
+	} {
+		code, _ := query(url)
+		assert.Equal(t, http.StatusBadRequest, code, url)
+	}
+}
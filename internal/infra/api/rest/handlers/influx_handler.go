@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"metrics/internal/core/service"
+	"metrics/internal/infra/influx"
+	"metrics/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type InfluxHandler struct {
+	metricService *service.MetricService
+}
+
+func NewInfluxHandler(metricService *service.MetricService) *InfluxHandler {
+	return &InfluxHandler{metricService: metricService}
+}
+
+// InfluxDB line protocol write handler
+// @Tags Influx
+// @Summary InfluxDB line protocol receiver
+// @Description Accepts InfluxDB line protocol (v1 /write and v2 /api/v2/write). Fields are stored as gauges
+// @Description named `measurement_field` with tags as labels. String fields are skipped. Query parameters are ignored.
+// @Accept plain
+// @Success 204
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Inernal Server Error"
+// @Router /write [POST]
+// @Router /api/v2/write [POST]
+func (h *InfluxHandler) WriteHandler(ctx *gin.Context) {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		logger.Log.Error("Error reading body", zap.Error(err))
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			gin.H{"status": false, "message": fmt.Sprintf("Error reading body: %s", err)},
+		)
+		return
+	}
+	metrics, unsupported, err := influx.Parse(string(body))
+	if err != nil {
+		logger.Log.Debug("Error parsing line protocol", zap.Error(err))
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			gin.H{"status": false, "message": fmt.Sprintf("Error parsing line protocol: %s", err)},
+		)
+		return
+	}
+	if len(unsupported) > 0 {
+		logger.Log.Debug("Line protocol request has unsupported fields", zap.Int("count", len(unsupported)))
+	}
+	if len(metrics) > 0 {
+		if _, err := h.metricService.BatchUpsertMetricValue(ctx, metrics); err != nil {
+			logger.Log.Error("Error updating metrics", zap.Error(err))
+			ctx.AbortWithStatusJSON(
+				http.StatusInternalServerError,
+				gin.H{"status": false, "message": fmt.Sprintf("Error updating metrics: %s", err)},
+			)
+			return
+		}
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
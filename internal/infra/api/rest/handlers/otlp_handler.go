@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"metrics/internal/core/service"
+	"metrics/internal/infra/otlp"
+	"metrics/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+type OTLPHandler struct {
+	metricService *service.MetricService
+	converter     *otlp.Converter
+}
+
+func NewOTLPHandler(metricService *service.MetricService) *OTLPHandler {
+	return &OTLPHandler{
+		metricService: metricService,
+		converter:     otlp.NewConverter(service.NewCumulativeTracker(service.DefaultCumulativeTTL)),
+	}
+}
+
+// OTLP/HTTP metrics handler
+// @Tags OpenTelemetry
+// @Summary OTLP/HTTP metrics receiver
+// @Description Accepts ExportMetricsServiceRequest encoded as protobuf or JSON, the response has the same encoding.
+// @Description Gauges, sums and explicit bucket histograms are stored, other data points are counted
+// @Description as rejected in the partial success of the response.
+// @ID OTLPMetricsHandler
+// @Accept application/x-protobuf,json
+// @Produce application/x-protobuf,json
+// @Success 200 {string} string "ExportMetricsServiceResponse"
+// @Failure 400 {string} string "Bad request"
+// @Failure 415 {string} string "Unsupported media type"
+// @Failure 500 {string} string "Inernal Server Error"
+// @Router /v1/metrics [POST]
+func (h *OTLPHandler) MetricsHandler(ctx *gin.Context) {
+	contentType, _, _ := strings.Cut(ctx.GetHeader("Content-Type"), ";")
+	contentType = strings.TrimSpace(contentType)
+	if contentType != contentTypeProtobuf && contentType != contentTypeJSON {
+		ctx.AbortWithStatusJSON(
+			http.StatusUnsupportedMediaType,
+			gin.H{"status": false, "message": fmt.Sprintf("Unsupported content type: %s", contentType)},
+		)
+		return
+	}
+
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		logger.Log.Error("Error reading body", zap.Error(err))
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			gin.H{"status": false, "message": fmt.Sprintf("Error reading body: %s", err)},
+		)
+		return
+	}
+	req := &colmetricspb.ExportMetricsServiceRequest{}
+	if contentType == contentTypeJSON {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, req)
+	} else {
+		err = proto.Unmarshal(body, req)
+	}
+	if err != nil {
+		logger.Log.Debug("Error decoding OTLP request", zap.Error(err))
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			gin.H{"status": false, "message": fmt.Sprintf("Error decoding request: %s", err)},
+		)
+		return
+	}
+
+	metrics, unsupported := h.converter.Convert(req)
+	if len(metrics) > 0 {
+		if _, err := h.metricService.BatchUpsertMetricValue(ctx, metrics); err != nil {
+			// приращения счетчиков будут отправлены с повторным запросом
+			h.converter.Rollback(metrics)
+			logger.Log.Error("Error updating metrics", zap.Error(err))
+			ctx.AbortWithStatusJSON(
+				http.StatusInternalServerError,
+				gin.H{"status": false, "message": fmt.Sprintf("Error updating metrics: %s", err)},
+			)
+			return
+		}
+	}
+
+	resp := &colmetricspb.ExportMetricsServiceResponse{}
+	if len(unsupported) > 0 {
+		logger.Log.Debug("OTLP request has unsupported data points", zap.Int("count", len(unsupported)))
+		resp.PartialSuccess = &colmetricspb.ExportMetricsPartialSuccess{
+			RejectedDataPoints: int64(len(unsupported)),
+			ErrorMessage:       fmt.Sprintf("%s: %s", unsupported[0].Series, unsupported[0].Reason),
+		}
+	}
+	var data []byte
+	if contentType == contentTypeJSON {
+		data, err = protojson.Marshal(resp)
+	} else {
+		data, err = proto.Marshal(resp)
+	}
+	if err != nil {
+		logger.Log.Error("Error encoding OTLP response", zap.Error(err))
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	ctx.Data(http.StatusOK, contentType, data)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"metrics/internal/core/config"
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+	"metrics/internal/infra/store/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOTLPHandler(t *testing.T) {
+	var wg sync.WaitGroup
+	store, err := memory.NewStore(
+		context.Background(),
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+		},
+	)
+	require.NoError(t, err)
+	metricService := service.NewMetricService(store)
+	handler := NewOTLPHandler(metricService)
+
+	post := func(contentType string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/metrics", bytes.NewReader(body))
+		c.Request.Header.Set("Content-Type", contentType)
+		handler.MetricsHandler(c)
+		return w
+	}
+
+	// protobuf
+	req := &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Metrics: []*metricspb.Metric{{
+					Name: "queue.size",
+					Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+						DataPoints: []*metricspb.NumberDataPoint{{Value: &metricspb.NumberDataPoint_AsInt{AsInt: 7}}},
+					}},
+				}},
+			}},
+		}},
+	}
+	body, err := proto.Marshal(req)
+	require.NoError(t, err)
+	w := post("application/x-protobuf", body)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+	var resp colmetricspb.ExportMetricsServiceResponse
+	require.NoError(t, proto.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Nil(t, resp.GetPartialSuccess())
+
+	gauge, err := metricService.GetMetric(context.Background(), &model.MetricsV2{ID: "queue.size", MType: model.GaugeType})
+	require.NoError(t, err)
+	require.NotNil(t, gauge)
+	assert.Equal(t, 7.0, *gauge.Value)
+
+	// JSON, int64 values are strings and enums are numbers in OTLP JSON
+	jsonBody := `{"resourceMetrics":[{
+		"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]},
+		"scopeMetrics":[{"scope":{"name":"test"},"metrics":[
+			{"name":"requests","sum":{"aggregationTemporality":2,"isMonotonic":true,
+				"dataPoints":[{"asInt":"12","timeUnixNano":"1717171717000000000"}]}},
+			{"name":"latency","summary":{"dataPoints":[{"count":"1","sum":1}]}}
+		]}]
+	}]}`
+	w = post("application/json", []byte(jsonBody))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	resp.Reset()
+	require.NoError(t, protojson.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(1), resp.GetPartialSuccess().GetRejectedDataPoints())
+
+	counter, err := metricService.GetMetric(context.Background(), &model.MetricsV2{
+		ID:     "requests",
+		MType:  model.CounterType,
+		Labels: model.Labels{"service.name": "api"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, counter)
+	// the first cumulative value is the baseline
+	assert.Equal(t, int64(0), *counter.Delta)
+
+	assert.Equal(t, http.StatusBadRequest, post("application/json", []byte("{")).Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, post("text/plain", []byte("x")).Code)
+}
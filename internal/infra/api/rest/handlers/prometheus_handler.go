@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"metrics/internal/core/service"
+	"metrics/internal/infra/prometheus"
+	"metrics/internal/logger"
+	"metrics/internal/selfmetrics"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type PrometheusHandler struct {
+	metricService *service.MetricService
+	registry      *selfmetrics.Registry
+}
+
+func NewPrometheusHandler(metricService *service.MetricService, registry *selfmetrics.Registry) *PrometheusHandler {
+	return &PrometheusHandler{metricService: metricService, registry: registry}
+}
+
+// Prometheus scrape handler
+// @Tags Prometheus
+// @Summary Metrics in Prometheus text format
+// @Description All stored metrics and the server own metrics (with metrics_server_ prefix) in the text exposition format
+// @ID PrometheusMetricsHandler
+// @Produce plain
+// @Success 200 {string} string "Metrics"
+// @Failure 500 {string} string "Inernal Server Error"
+// @Router /metrics [GET]
+func (h *PrometheusHandler) MetricsHandler(ctx *gin.Context) {
+	list, err := h.metricService.ListMetrics(ctx)
+	if err != nil {
+		logger.Log.Error("Error getting list of metrics", zap.Error(err))
+		ctx.String(http.StatusInternalServerError, fmt.Sprintf("Error getting list of metrics: %s", err))
+		return
+	}
+
+	list.Metrics = append(list.Metrics, h.registry.Metrics()...)
+	var buf bytes.Buffer
+	if err := prometheus.WriteText(&buf, list.Metrics); err != nil {
+		logger.Log.Error("Error writing metrics", zap.Error(err))
+		ctx.String(http.StatusInternalServerError, fmt.Sprintf("Error writing metrics: %s", err))
+		return
+	}
+	ctx.Data(http.StatusOK, prometheus.ContentType, buf.Bytes())
+}
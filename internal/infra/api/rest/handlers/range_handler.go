@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+	"metrics/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// DefaultRangeStep is used when the range query has no step.
+const DefaultRangeStep = time.Minute
+
+type RangeHandler struct {
+	rangeService *service.RangeService
+}
+
+func NewRangeHandler(rangeService *service.RangeService) *RangeHandler {
+	return &RangeHandler{rangeService: rangeService}
+}
+
+// Range query API handler
+// @Tags V2 API
+// @Summary Query metric range
+// @Description Metric samples between start and end aggregated by steps. Steps without samples are skipped.
+// @ID QueryRangeHandler
+// @Produce json
+// @Param metric query string true "Metric name with optional labels: HeapAlloc or HeapAlloc{host=\"a\"}"
+// @Param type query string false "Metric type: gauge, counter or histogram" default(gauge)
+// @Param start query string false "Range start, RFC3339 or unix seconds. An hour before the end by default"
+// @Param end query string false "Range end, RFC3339 or unix seconds. The current time by default"
+// @Param step query string false "Step duration: 30s, 5m or seconds" default(1m)
+// @Param fn query string false "Function: avg, min, max, sum, p95, rate or increase" default(avg)
+// @Success 200 {object} model.RangeResponse
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Inernal Server Error"
+// @Router /api/v2/query_range [GET]
+func (h *RangeHandler) QueryRangeHandler(ctx *gin.Context) {
+	q, err := parseRangeQuery(ctx)
+	if err == nil {
+		err = q.Validate()
+	}
+	if err != nil {
+		logger.Log.Error("Error parsing range query", zap.Error(err))
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			gin.H{"status": false, "message": fmt.Sprintf("Error parsing query: %s", err)},
+		)
+		return
+	}
+
+	res, err := h.rangeService.QueryRange(ctx, q)
+	if err != nil {
+		logger.Log.Error("Error querying range", zap.Error(err))
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			gin.H{"status": false, "message": fmt.Sprintf("Error querying range: %s", err)},
+		)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func parseRangeQuery(ctx *gin.Context) (*model.RangeQuery, error) {
+	id, labels, err := model.ParseMetricKey(ctx.Query("metric"))
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, errors.New("metric is required")
+	}
+	q := &model.RangeQuery{
+		ID:     id,
+		Labels: labels,
+		MType:  model.MetricType(ctx.DefaultQuery("type", string(model.GaugeType))),
+		Fn:     model.RangeFunction(ctx.DefaultQuery("fn", string(model.FnAvg))),
+		End:    time.Now(),
+		Step:   DefaultRangeStep,
+	}
+
+	if end := ctx.Query("end"); end != "" {
+		if q.End, err = parseTime(end); err != nil {
+			return nil, fmt.Errorf("invalid end: %w", err)
+		}
+	}
+	q.Start = q.End.Add(-service.DefaultHistoryPeriod)
+	if start := ctx.Query("start"); start != "" {
+		if q.Start, err = parseTime(start); err != nil {
+			return nil, fmt.Errorf("invalid start: %w", err)
+		}
+	}
+	if step := ctx.Query("step"); step != "" {
+		if q.Step, err = parseDuration(step); err != nil {
+			return nil, fmt.Errorf("invalid step: %w", err)
+		}
+	}
+	return q, nil
+}
+
+// parseTime parses RFC3339 time or unix timestamp in seconds with optional fraction.
+func parseTime(s string) (time.Time, error) {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, frac := math.Modf(seconds)
+		return time.Unix(int64(sec), int64(frac*float64(time.Second))), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// parseDuration parses Go duration (30s, 5m) or amount of seconds.
+func parseDuration(s string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return time.ParseDuration(s)
+}
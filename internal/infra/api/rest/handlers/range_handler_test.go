@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"metrics/internal/core/config"
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+	"metrics/internal/infra/store/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryRangeHandler(t *testing.T) {
+	var wg sync.WaitGroup
+	store, err := memory.NewStore(
+		context.Background(),
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+			HistorySize:     10,
+		},
+	)
+	require.NoError(t, err)
+	start := time.Now().Add(-time.Minute)
+	for _, value := range []float64{1, 5, 3} {
+		gauge := &model.Gauge{Name: "HeapAlloc", Labels: model.Labels{"host": "a"}, Value: value}
+		err = store.SetGauge(context.Background(), gauge)
+		require.NoError(t, err)
+	}
+	handler := NewRangeHandler(service.NewRangeService(store))
+
+	query := func(params url.Values) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v2/query_range?"+params.Encode(), nil)
+
+		handler.QueryRangeHandler(c)
+		return w
+	}
+
+	w := query(url.Values{
+		"metric": {`HeapAlloc{host="a"}`},
+		"start":  {strconv.FormatInt(start.Unix(), 10)},
+		"step":   {"1h"},
+		"fn":     {"max"},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	res := &model.RangeResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), res))
+	assert.Equal(t, model.Labels{"host": "a"}, res.Labels)
+	assert.Equal(t, 3600.0, res.Step)
+	require.Len(t, res.Points, 1)
+	assert.Equal(t, 5.0, res.Points[0].Value)
+
+	for _, params := range []url.Values{
+		{},
+		{"metric": {"HeapAlloc{host=a}"}},
+		{"metric": {"HeapAlloc"}, "start": {"yesterday"}},
+		{"metric": {"HeapAlloc"}, "step": {"often"}},
+		{"metric": {"HeapAlloc"}, "fn": {"rate"}},
+	} {
+		w = query(params)
+		assert.Equal(t, http.StatusBadRequest, w.Code, params.Encode())
+	}
+
+	// ошибка хранилища - не ошибка запроса
+	noHistory, err := memory.NewStore(
+		context.Background(),
+		&wg,
+		&config.StorageConfig{StoreIntreval: 1000, FileStoragePath: "/tmp/storage_dump.json"},
+	)
+	require.NoError(t, err)
+	handler = NewRangeHandler(service.NewRangeService(noHistory))
+	w = query(url.Values{"metric": {"HeapAlloc"}})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
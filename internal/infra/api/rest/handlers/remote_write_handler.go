@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+	"metrics/internal/infra/prometheus"
+	"metrics/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/snappy"
+	"go.uber.org/zap"
+)
+
+const (
+	maxRemoteWriteBody    = 16 << 20 // compressed request size
+	maxRemoteWriteDecoded = 64 << 20 // decompressed request size, it is declared in the snappy header
+)
+
+type RemoteWriteHandler struct {
+	metricService *service.MetricService
+	converter     *prometheus.Converter
+}
+
+func NewRemoteWriteHandler(metricService *service.MetricService) *RemoteWriteHandler {
+	return &RemoteWriteHandler{
+		metricService: metricService,
+		converter:     prometheus.NewConverter(service.NewCumulativeTracker(service.DefaultCumulativeTTL)),
+	}
+}
+
+// Prometheus remote write handler
+// @Tags Prometheus
+// @Summary Prometheus remote write receiver
+// @Description Accepts snappy-compressed protobuf WriteRequest. Samples are stored as gauges and counters,
+// @Description series which could not be stored (histograms, summaries) are listed in the response.
+// @ID RemoteWriteHandler
+// @Accept application/x-protobuf
+// @Produce json
+// @Success 200 {object} model.WriteResponse
+// @Failure 400 {string} string "Bad request"
+// @Failure 413 {string} string "Request Entity Too Large"
+// @Failure 500 {string} string "Inernal Server Error"
+// @Router /api/v1/write [POST]
+func (h *RemoteWriteHandler) WriteHandler(ctx *gin.Context) {
+	compressed, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxRemoteWriteBody))
+	if err != nil {
+		logger.Log.Error("Error reading body", zap.Error(err))
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		ctx.AbortWithStatusJSON(status, gin.H{"status": false, "message": fmt.Sprintf("Error reading body: %s", err)})
+		return
+	}
+	// размер из заголовка проверяется до выделения памяти под распакованные данные
+	size, err := snappy.DecodedLen(compressed)
+	if err == nil && size > maxRemoteWriteDecoded {
+		logger.Log.Error("Remote write request is too large", zap.Int("size", size))
+		message := fmt.Sprintf("Decompressed body is larger than %d bytes", maxRemoteWriteDecoded)
+		ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"status": false, "message": message})
+		return
+	}
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		logger.Log.Error("Error decompressing body", zap.Error(err))
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			gin.H{"status": false, "message": fmt.Sprintf("Error decompressing body: %s", err)},
+		)
+		return
+	}
+	req, err := prometheus.DecodeWriteRequest(data)
+	if err != nil {
+		logger.Log.Error("Error decoding write request", zap.Error(err))
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			gin.H{"status": false, "message": fmt.Sprintf("Error decoding write request: %s", err)},
+		)
+		return
+	}
+
+	metrics, unsupported := h.converter.Convert(req)
+	if len(unsupported) > 0 {
+		logger.Log.Debug("Remote write request has unsupported series", zap.Int("count", len(unsupported)))
+	}
+	if len(metrics) > 0 {
+		if _, err := h.metricService.BatchUpsertMetricValue(ctx, metrics); err != nil {
+			// приращения счетчиков будут отправлены с повторным запросом
+			h.converter.Rollback(metrics)
+			logger.Log.Error("Error updating metrics", zap.Error(err))
+			ctx.AbortWithStatusJSON(
+				http.StatusInternalServerError,
+				gin.H{"status": false, "message": fmt.Sprintf("Error updating metrics: %s", err)},
+			)
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, model.WriteResponse{Unsupported: unsupported, Written: len(metrics)})
+}
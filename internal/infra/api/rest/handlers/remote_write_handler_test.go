@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"metrics/internal/core/config"
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+	"metrics/internal/infra/prometheus"
+	"metrics/internal/infra/store/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteWriteHandler(t *testing.T) {
+	var wg sync.WaitGroup
+	store, err := memory.NewStore(
+		context.Background(),
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+		},
+	)
+	require.NoError(t, err)
+	metricService := service.NewMetricService(store)
+	handler := NewRemoteWriteHandler(metricService)
+
+	write := func(body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader(body))
+		c.Request.Header.Set("Content-Encoding", "snappy")
+		c.Request.Header.Set("Content-Type", "application/x-protobuf")
+		handler.WriteHandler(c)
+		return w
+	}
+	request := func(requests, temperature float64) []byte {
+		req := &prometheus.WriteRequest{
+			Timeseries: []*prometheus.TimeSeries{
+				{
+					Labels:  []prometheus.Label{{Name: "__name__", Value: "http_requests_total"}, {Name: "job", Value: "api"}},
+					Samples: []prometheus.Sample{{Value: requests, Timestamp: 1000}},
+				},
+				{
+					Labels:  []prometheus.Label{{Name: "__name__", Value: "temperature"}},
+					Samples: []prometheus.Sample{{Value: temperature, Timestamp: 1000}},
+				},
+				{
+					Labels:  []prometheus.Label{{Name: "__name__", Value: "latency_bucket"}, {Name: "le", Value: "+Inf"}},
+					Samples: []prometheus.Sample{{Value: 1, Timestamp: 1000}},
+				},
+			},
+		}
+		return snappy.Encode(nil, req.Marshal())
+	}
+
+	w := write(request(10, 21.5))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(
+		t,
+		`{"written": 2, "unsupported": [{"series": "latency_bucket{le=\"+Inf\"}", "reason": "histograms are not supported"}]}`,
+		w.Body.String(),
+	)
+
+	// cumulative counter value is converted to delta, the first value is the baseline
+	w = write(request(25, 22))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	ctx := context.Background()
+	counter, err := metricService.GetCounter(
+		ctx,
+		&model.MetricsV2{ID: "http_requests_total", MType: model.CounterType, Labels: model.Labels{"job": "api"}},
+	)
+	require.NoError(t, err)
+	require.NotNil(t, counter)
+	assert.Equal(t, int64(15), *counter.Delta)
+
+	gauge, err := metricService.GetGauge(ctx, &model.MetricsV2{ID: "temperature", MType: model.GaugeType})
+	require.NoError(t, err)
+	require.NotNil(t, gauge)
+	assert.Equal(t, 22.0, *gauge.Value)
+
+	w = write([]byte("not snappy"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// заголовок snappy с огромным размером не приводит к выделению памяти
+	w = write(binary.AppendUvarint(nil, 1<<32-1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = write(make([]byte, maxRemoteWriteBody+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"strconv"
+	"time"
+
+	"metrics/internal/core/model"
+	"metrics/internal/selfmetrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestMetrics counts API requests and observes their duration.
+// The route pattern is used as the path label, so URL parameters don't produce new series.
+func RequestMetrics(registry *selfmetrics.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		path := c.FullPath()
+		if path == "" {
+			path = "unmatched"
+		}
+		registry.Add(
+			"http_requests_total",
+			model.Labels{"method": c.Request.Method, "path": path, "code": strconv.Itoa(c.Writer.Status())},
+			1,
+		)
+		registry.Observe(
+			"http_request_duration_seconds",
+			model.Labels{"method": c.Request.Method, "path": path},
+			model.DefaultHistogramBuckets,
+			time.Since(start).Seconds(),
+		)
+	}
+}
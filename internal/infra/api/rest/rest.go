@@ -8,11 +8,13 @@ import (
 	"net/http"
 	"time"
 
+	"metrics/internal/core/alert"
 	"metrics/internal/core/config"
 	"metrics/internal/core/service"
 	"metrics/internal/infra/api/rest/handlers"
 	"metrics/internal/infra/api/rest/middlewares"
 	"metrics/internal/logger"
+	"metrics/internal/selfmetrics"
 
 	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
@@ -48,14 +50,25 @@ func NewAPI(
 	cfg *config.Config,
 	metricService *service.MetricService,
 	systemService *service.SystemService,
+	rangeService *service.RangeService,
+	alertEngine *alert.Engine,
+	registry *selfmetrics.Registry,
 	privateKey *rsa.PrivateKey,
 ) *API {
 	serviceHandler := handlers.NewSystemHandler(systemService)
 	handlerV1 := handlers.NewHandlerV1(metricService)
 	handlerV2 := handlers.NewHandlerV2(metricService)
+	rangeHandler := handlers.NewRangeHandler(rangeService)
+	alertHandler := handlers.NewAlertHandler(alertEngine)
+	prometheusHandler := handlers.NewPrometheusHandler(metricService, registry)
+	remoteWriteHandler := handlers.NewRemoteWriteHandler(metricService)
+	influxHandler := handlers.NewInfluxHandler(metricService)
+	otlpHandler := handlers.NewOTLPHandler(metricService)
+	grafanaHandler := handlers.NewGrafanaHandler(metricService, rangeService, alertEngine)
 
 	router := gin.Default()
 	router.Use(ZapLogger(logger.Log))
+	router.Use(middlewares.RequestMetrics(registry))
 	router.Use(gin.Recovery())
 	if privateKey != nil {
 		router.Use(middlewares.DecryptReqBody(privateKey))
@@ -77,6 +90,24 @@ func NewAPI(
 	router.POST("/value/", handlerV2.GetHandler)
 	router.POST("/update/", handlerV2.UpdateHandler)
 	router.POST("/updates/", handlerV2.BatchUpdateHandler)
+	router.POST("/history/", handlerV2.HistoryHandler)
+	router.GET("/api/v2/metrics", handlerV2.QueryHandler)
+	router.GET("/api/v2/query_range", rangeHandler.QueryRangeHandler)
+
+	router.GET("/alerts", alertHandler.ListHandler)
+	router.GET("/metrics", prometheusHandler.MetricsHandler)
+	router.POST("/api/v1/write", remoteWriteHandler.WriteHandler)
+	router.POST("/write", influxHandler.WriteHandler)
+	router.POST("/api/v2/write", influxHandler.WriteHandler)
+	router.POST("/v1/metrics", otlpHandler.MetricsHandler)
+
+	grafana := router.Group("/grafana")
+	grafana.GET("/", grafanaHandler.TestHandler)
+	grafana.POST("/search", grafanaHandler.SearchHandler)
+	grafana.POST("/query", grafanaHandler.QueryHandler)
+	grafana.POST("/annotations", grafanaHandler.AnnotationsHandler)
+	grafana.POST("/tag-keys", grafanaHandler.TagKeysHandler)
+	grafana.POST("/tag-values", grafanaHandler.TagValuesHandler)
 
 	pprof.Register(router)
 	srv := &http.Server{Handler: router}
@@ -1,18 +1,27 @@
 package rest
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"sync"
 	"testing"
+	"time"
 
+	"metrics/internal/core/alert"
 	"metrics/internal/core/config"
 	"metrics/internal/core/model"
 	"metrics/internal/core/service"
 	"metrics/internal/infra/store/memory"
 	"metrics/internal/mocks"
+	"metrics/internal/selfmetrics"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -309,7 +318,7 @@ func TestUpdateHandler(t *testing.T) {
 	systemService := service.NewSystemService(dbMockStore)
 
 	cfg := config.Config{HashKey: ""}
-	api := NewAPI(&cfg, metricService, systemService, nil)
+	api := NewAPI(&cfg, metricService, systemService, service.NewRangeService(store), alert.NewEngine(store, nil, time.Second, nil), selfmetrics.NewRegistry(), nil)
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -324,3 +333,117 @@ func TestUpdateHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestPrometheusMetrics(t *testing.T) {
+	var wg sync.WaitGroup
+	store, err := memory.NewStore(
+		context.Background(),
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+		},
+	)
+	require.NoError(t, err)
+	metricService := service.NewMetricService(store)
+	systemService := service.NewSystemService(mocks.NewMockPinger(gomock.NewController(t)))
+
+	cfg := config.Config{HashKey: ""}
+	api := NewAPI(&cfg, metricService, systemService, service.NewRangeService(store), alert.NewEngine(store, nil, time.Second, nil), selfmetrics.NewRegistry(), nil)
+
+	for _, url := range []string{"/update/gauge/Heap.Alloc/1.5/", "/update/counter/PollCount/3/"} {
+		w := httptest.NewRecorder()
+		api.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, url, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	api.srv.Handler.ServeHTTP(w, request)
+
+	res := w.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", res.Header.Get("Content-Type"))
+
+	gz, err := gzip.NewReader(res.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(gz)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), "# TYPE Heap_Alloc gauge\nHeap_Alloc 1.5\n")
+	assert.Contains(t, string(body), "# TYPE PollCount counter\nPollCount 3\n")
+	assert.Contains(t, string(body), "# TYPE metrics_server_http_requests_total counter\n")
+	assert.Contains(
+		t,
+		string(body),
+		`metrics_server_http_requests_total{code="200",method="POST",path="/update/:type/:name/:value/"} 2`,
+	)
+	assert.Contains(t, string(body), "# TYPE metrics_server_go_goroutines gauge\n")
+}
+
+func TestInfluxWrite(t *testing.T) {
+	var wg sync.WaitGroup
+	store, err := memory.NewStore(
+		context.Background(),
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+		},
+	)
+	require.NoError(t, err)
+	metricService := service.NewMetricService(store)
+	systemService := service.NewSystemService(mocks.NewMockPinger(gomock.NewController(t)))
+
+	cfg := config.Config{HashKey: "secret"}
+	api := NewAPI(&cfg, metricService, systemService, service.NewRangeService(store), alert.NewEngine(store, nil, time.Second, nil), selfmetrics.NewRegistry(), nil)
+
+	// Telegraf sends gzipped body, the signature is checked after decompression
+	data := []byte("cpu,host=a usage_idle=98.5,usage_user=1i\nmem,host=a value=10\n")
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err = gz.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	body := buf.Bytes()
+
+	h := hmac.New(sha256.New, []byte(cfg.HashKey))
+	h.Write(data)
+
+	for _, url := range []string{"/write?db=telegraf", "/api/v2/write?org=a&bucket=b"} {
+		request := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		request.Header.Set("Content-Encoding", "gzip")
+		request.Header.Set("HashSHA256", hex.EncodeToString(h.Sum(nil)))
+		w := httptest.NewRecorder()
+		api.srv.Handler.ServeHTTP(w, request)
+		require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	}
+
+	gauge, err := metricService.GetMetric(context.Background(), &model.MetricsV2{
+		ID:     "cpu_usage_idle",
+		MType:  model.GaugeType,
+		Labels: model.Labels{"host": "a"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, gauge)
+	assert.Equal(t, 98.5, *gauge.Value)
+
+	gauge, err = metricService.GetMetric(context.Background(), &model.MetricsV2{
+		ID:     "mem",
+		MType:  model.GaugeType,
+		Labels: model.Labels{"host": "a"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, gauge)
+	assert.Equal(t, 10.0, *gauge.Value)
+
+	request := httptest.NewRequest(http.MethodPost, "/write", bytes.NewReader([]byte("cpu value=abc")))
+	w := httptest.NewRecorder()
+	api.srv.Handler.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,181 @@
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+	"metrics/internal/logger"
+	"metrics/internal/selfmetrics"
+
+	"go.uber.org/zap"
+)
+
+// Listener receives Graphite lines by TCP and writes the last received value of every metric
+// each flush interval. Lines which could not be parsed are counted by graphite_parse_errors_total self metric.
+type Listener struct {
+	listener      net.Listener
+	metricService *service.MetricService
+	registry      *selfmetrics.Registry
+	metrics       map[string]*model.MetricsV2
+	conns         map[net.Conn]bool
+	mux           *sync.Mutex
+	handlers      *sync.WaitGroup
+	quit          chan bool
+	done          chan bool
+	interval      time.Duration
+	closed        bool
+}
+
+func Listen(
+	addr string,
+	interval time.Duration,
+	metricService *service.MetricService,
+	registry *selfmetrics.Registry,
+) (*Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen %s: %w", addr, err)
+	}
+	return &Listener{
+		listener:      listener,
+		metricService: metricService,
+		registry:      registry,
+		metrics:       make(map[string]*model.MetricsV2),
+		conns:         make(map[net.Conn]bool),
+		mux:           &sync.Mutex{},
+		handlers:      &sync.WaitGroup{},
+		quit:          make(chan bool),
+		done:          make(chan bool),
+		interval:      interval,
+	}, nil
+}
+
+// Addr returns the listener network address.
+func (l *Listener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+// Run accepts connections and flushes metrics until Close is called. Metrics received before Close are flushed.
+func (l *Listener) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer close(l.done)
+	logger.Log.Info("Run Graphite listener", zap.String("Addres", l.Addr().String()), zap.Duration("flush", l.interval))
+
+	go l.accept()
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.quit:
+			logger.Log.Info("Close Graphite listener cicle")
+			l.flush(context.Background())
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.flush(ctx)
+		}
+	}
+}
+
+// Close stops accepting connections, closes active ones and waits for the last flush.
+func (l *Listener) Close() {
+	if err := l.listener.Close(); err != nil {
+		logger.Log.Error("Closing Graphite listener error", zap.Error(err))
+	}
+	l.mux.Lock()
+	l.closed = true
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.mux.Unlock()
+	l.handlers.Wait()
+
+	close(l.quit)
+	<-l.done
+}
+
+func (l *Listener) accept() {
+	for {
+		conn, err := l.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			logger.Log.Error("Accepting Graphite connection error", zap.Error(err))
+			continue
+		}
+
+		l.mux.Lock()
+		if l.closed {
+			l.mux.Unlock()
+			conn.Close()
+			return
+		}
+		l.conns[conn] = true
+		l.handlers.Add(1)
+		l.mux.Unlock()
+		go l.handle(conn)
+	}
+}
+
+func (l *Listener) handle(conn net.Conn) {
+	defer l.handlers.Done()
+	defer func() {
+		l.mux.Lock()
+		delete(l.conns, conn)
+		l.mux.Unlock()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		l.registry.Add("graphite_lines_total", nil, 1)
+		metric, err := ParseLine(line)
+		if err != nil {
+			l.registry.Add("graphite_parse_errors_total", nil, 1)
+			continue
+		}
+		l.mux.Lock()
+		l.metrics[model.MetricKey(metric.ID, metric.Labels)] = metric
+		l.mux.Unlock()
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) {
+		logger.Log.Debug("Reading Graphite connection error", zap.Error(err))
+	}
+}
+
+// flush writes metrics in one batch. Metrics of the failed batch are dropped.
+func (l *Listener) flush(ctx context.Context) {
+	l.mux.Lock()
+	batch := make([]*model.MetricsV2, 0, len(l.metrics))
+	for _, metric := range l.metrics {
+		batch = append(batch, metric)
+	}
+	l.metrics = make(map[string]*model.MetricsV2)
+	l.mux.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+	if _, err := l.metricService.BatchUpsertMetricValue(ctx, batch); err != nil {
+		l.registry.Add("graphite_flush_errors_total", nil, 1)
+		logger.Log.Error("Writing Graphite metrics error", zap.Int("metrics", len(batch)), zap.Error(err))
+		return
+	}
+	logger.Log.Debug("Graphite metrics flushed", zap.Int("metrics", len(batch)))
+}
@@ -0,0 +1,71 @@
+package graphite
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"metrics/internal/core/config"
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+	"metrics/internal/infra/store/memory"
+	"metrics/internal/selfmetrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListener(t *testing.T) {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	store, err := memory.NewStore(
+		ctx,
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+		},
+	)
+	require.NoError(t, err)
+	metricService := service.NewMetricService(store)
+	registry := selfmetrics.NewRegistry()
+
+	listener, err := Listen("127.0.0.1:0", time.Hour, metricService, registry)
+	require.NoError(t, err)
+	wg.Add(1)
+	go listener.Run(ctx, &wg)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("cpu.idle;host=a 10 1717171717\ncpu.idle;host=a 12 1717171718\nbroken\n"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		listener.mux.Lock()
+		defer listener.mux.Unlock()
+		return len(listener.metrics) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// the open connection is closed and the last flush is done on close
+	listener.Close()
+
+	gauge, err := metricService.GetMetric(ctx, &model.MetricsV2{
+		ID:     "cpu.idle",
+		MType:  model.GaugeType,
+		Labels: model.Labels{"host": "a"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, gauge)
+	assert.Equal(t, 12.0, *gauge.Value)
+
+	var parseErrors string
+	for _, m := range registry.Metrics() {
+		if m.Name == selfmetrics.Namespace+"_graphite_parse_errors_total" {
+			parseErrors = m.Value
+		}
+	}
+	assert.Equal(t, "1", parseErrors)
+}
@@ -0,0 +1,49 @@
+// Package graphite implements Graphite plaintext protocol listener: `path value timestamp` lines over TCP.
+package graphite
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"metrics/internal/core/model"
+)
+
+// ParseLine parses a plaintext line to the gauge. The path is used as the metric name, tags of the
+// tagged path `path;tag=value;...` become labels. The timestamp is checked but not used,
+// metrics are stored with the current time.
+func ParseLine(line string) (*model.MetricsV2, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 && len(fields) != 3 {
+		return nil, errors.New("line must be `path value timestamp`")
+	}
+
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("invalid value: %q", fields[1])
+	}
+	if len(fields) == 3 {
+		if _, err := strconv.ParseFloat(fields[2], 64); err != nil {
+			return nil, fmt.Errorf("invalid timestamp: %q", fields[2])
+		}
+	}
+
+	parts := strings.Split(fields[0], ";")
+	if parts[0] == "" {
+		return nil, errors.New("metric path is required")
+	}
+	metric := &model.MetricsV2{ID: parts[0], MType: model.GaugeType, Value: &value}
+	for _, tag := range parts[1:] {
+		name, tagValue, ok := strings.Cut(tag, "=")
+		if !ok || name == "" || tagValue == "" {
+			return nil, fmt.Errorf("tag must be name=value, got %q", tag)
+		}
+		if metric.Labels == nil {
+			metric.Labels = model.Labels{}
+		}
+		metric.Labels[name] = tagValue
+	}
+	return metric, nil
+}
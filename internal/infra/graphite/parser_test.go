@@ -0,0 +1,29 @@
+package graphite
+
+import (
+	"testing"
+
+	"metrics/internal/core/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	metric, err := ParseLine("servers.a.cpu.idle 98.5 1717171717")
+	require.NoError(t, err)
+	assert.Equal(t, "servers.a.cpu.idle", metric.ID)
+	assert.Equal(t, model.GaugeType, metric.MType)
+	assert.Nil(t, metric.Labels)
+	assert.Equal(t, 98.5, *metric.Value)
+
+	metric, err = ParseLine("cpu.idle;host=a;dc=eu 1")
+	require.NoError(t, err)
+	assert.Equal(t, "cpu.idle", metric.ID)
+	assert.Equal(t, model.Labels{"host": "a", "dc": "eu"}, metric.Labels)
+
+	for _, line := range []string{"cpu", "cpu abc 1", "cpu 1 abc", "cpu 1 1 1", ";host=a 1", "cpu;host 1", "cpu NaN"} {
+		_, err := ParseLine(line)
+		assert.Error(t, err, line)
+	}
+}
@@ -0,0 +1,176 @@
+// Package influx parses InfluxDB line protocol: `measurement,tag=value field=1.5,other=2i 1465839830100400200`.
+package influx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"metrics/internal/core/model"
+)
+
+// ValueField is a field name which is not added to the metric name, like Telegraf does for Prometheus output.
+const ValueField = "value"
+
+// Parse converts lines to gauges. Tags become labels and the metric name is `measurement_field`.
+// Numeric and boolean fields are stored, string fields are returned as unsupported.
+// Timestamps are checked but not used, metrics are stored with the current time.
+func Parse(data string) ([]*model.MetricsV2, []*model.UnsupportedSeries, error) {
+	metrics := make([]*model.MetricsV2, 0)
+	unsupported := make([]*model.UnsupportedSeries, 0)
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m, u, err := parseLine(line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		metrics = append(metrics, m...)
+		unsupported = append(unsupported, u...)
+	}
+	return metrics, unsupported, nil
+}
+
+func parseLine(line string) ([]*model.MetricsV2, []*model.UnsupportedSeries, error) {
+	sections := split(line, ' ', true)
+	if len(sections) < 2 || len(sections) > 3 {
+		return nil, nil, errors.New("line must have measurement, fields and optional timestamp")
+	}
+	if len(sections) == 3 {
+		if _, err := strconv.ParseInt(sections[2], 10, 64); err != nil {
+			return nil, nil, fmt.Errorf("invalid timestamp: %q", sections[2])
+		}
+	}
+
+	series := split(sections[0], ',', false)
+	measurement := unescape(series[0])
+	if measurement == "" {
+		return nil, nil, errors.New("measurement is required")
+	}
+	var labels model.Labels
+	for _, tag := range series[1:] {
+		name, value, err := pair(tag)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid tag: %w", err)
+		}
+		if labels == nil {
+			labels = model.Labels{}
+		}
+		labels[name] = value
+	}
+
+	metrics := make([]*model.MetricsV2, 0)
+	unsupported := make([]*model.UnsupportedSeries, 0)
+	for _, field := range split(sections[1], ',', true) {
+		name, raw, err := pair(field)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid field: %w", err)
+		}
+		id := measurement
+		if name != ValueField {
+			id += "_" + name
+		}
+		if strings.HasPrefix(raw, `"`) {
+			unsupported = append(unsupported, &model.UnsupportedSeries{
+				Series: model.MetricKey(id, labels),
+				Reason: "string field",
+			})
+			continue
+		}
+		value, err := parseValue(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		metrics = append(metrics, &model.MetricsV2{ID: id, MType: model.GaugeType, Labels: labels, Value: &value})
+	}
+	if len(metrics)+len(unsupported) == 0 {
+		return nil, nil, errors.New("at least one field is required")
+	}
+	return metrics, unsupported, nil
+}
+
+func parseValue(raw string) (float64, error) {
+	switch raw {
+	case "t", "T", "true", "True", "TRUE":
+		return 1, nil
+	case "f", "F", "false", "False", "FALSE":
+		return 0, nil
+	}
+
+	var value float64
+	var err error
+	switch {
+	case strings.HasSuffix(raw, "i"):
+		var v int64
+		v, err = strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+		value = float64(v)
+	case strings.HasSuffix(raw, "u"):
+		var v uint64
+		v, err = strconv.ParseUint(raw[:len(raw)-1], 10, 64)
+		value = float64(v)
+	default:
+		value, err = strconv.ParseFloat(raw, 64)
+	}
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("invalid value: %q", raw)
+	}
+	return value, nil
+}
+
+// pair splits `name=value` by the first unescaped equal sign. The value of string field stays quoted.
+func pair(s string) (string, string, error) {
+	parts := split(s, '=', false)
+	if len(parts) < 2 {
+		return "", "", fmt.Errorf("name=value expected, got %q", s)
+	}
+	name := unescape(parts[0])
+	value := s[len(parts[0])+1:]
+	if name == "" || value == "" {
+		return "", "", fmt.Errorf("name=value expected, got %q", s)
+	}
+	if !strings.HasPrefix(value, `"`) {
+		value = unescape(value)
+	}
+	return name, value, nil
+}
+
+// split splits s by unescaped separator. If quotes is true, separators inside of double quotes are skipped.
+// Escape sequences are kept, they are removed by unescape.
+func split(s string, sep byte, quotes bool) []string {
+	var parts []string
+	start := 0
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '"' && quotes:
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// escaped are characters which could be escaped by backslash, other backslashes are kept as is.
+const escaped = `, ="\\`
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(escaped, s[i+1]) >= 0 {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,57 @@
+package influx
+
+import (
+	"testing"
+
+	"metrics/internal/core/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	data := `
+# comment
+cpu,host=a,region=eu-west usage_idle=98.5,usage_user=1i 1465839830100400200
+mem\ usage,host=b value=10u
+disk,path=/var\,log used=1.5e3,ok=true,name="sda 1"
+swap free=0
+`
+	metrics, unsupported, err := Parse(data)
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	for _, m := range metrics {
+		assert.Equal(t, model.GaugeType, m.MType)
+		values[model.MetricKey(m.ID, m.Labels)] = *m.Value
+	}
+	assert.Equal(t, map[string]float64{
+		`cpu_usage_idle{host="a",region="eu-west"}`: 98.5,
+		`cpu_usage_user{host="a",region="eu-west"}`: 1,
+		`mem usage{host="b"}`:                       10,
+		`disk_used{path="/var,log"}`:                1500,
+		`disk_ok{path="/var,log"}`:                  1,
+		`swap_free`:                                 0,
+	}, values)
+
+	require.Len(t, unsupported, 1)
+	assert.Equal(t, `disk_name{path="/var,log"}`, unsupported[0].Series)
+}
+
+func TestParseErrors(t *testing.T) {
+	lines := []string{
+		"cpu",
+		",host=a value=1",
+		"cpu,host value=1",
+		"cpu value=",
+		"cpu value=abc",
+		"cpu value=1 abc",
+		"cpu value=1 1 1",
+	}
+	for _, line := range lines {
+		t.Run(line, func(t *testing.T) {
+			_, _, err := Parse(line)
+			assert.Error(t, err)
+		})
+	}
+}
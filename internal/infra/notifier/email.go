@@ -0,0 +1,142 @@
+package notifier
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"metrics/internal/core/alert"
+)
+
+const defaultEmailTimeout = 10 * time.Second
+
+// Email sends notifications as plain text emails. STARTTLS is used if the server supports it.
+type Email struct {
+	cfg     *alert.EmailConfig
+	timeout time.Duration
+}
+
+func NewEmail(cfg *alert.EmailConfig) *Email {
+	timeout := time.Duration(cfg.Timeout)
+	if timeout <= 0 {
+		timeout = defaultEmailTimeout
+	}
+	return &Email{cfg: cfg, timeout: timeout}
+}
+
+// Notify sends the email, the whole SMTP session is limited by the timeout and the context.
+func (e *Email) Notify(ctx context.Context, n *alert.Notification) error {
+	host, _, err := net.SplitHostPort(e.cfg.Smarthost)
+	if err != nil {
+		return fmt.Errorf("invalid smarthost: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", e.cfg.Smarthost)
+	if err != nil {
+		return fmt.Errorf("error connecting to smarthost: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return fmt.Errorf("error setting deadline: %w", err)
+	}
+	// соединение закрывается при отмене контекста
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	defer c.Close()
+	if err := e.send(c, host, n); err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	return nil
+}
+
+// send is smtp.SendMail on the connected client.
+func (e *Email) send(c *smtp.Client, host string, n *alert.Notification) error {
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range e.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(e.message(n)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (e *Email) message(n *alert.Notification) []byte {
+	firing := 0
+	for _, a := range n.Alerts {
+		if a.State == alert.StateFiring {
+			firing++
+		}
+	}
+	subject := fmt.Sprintf("[%s:%d] %s", strings.ToUpper(string(n.Status)), firing, n.GroupLabels[alert.AlertNameLabel])
+	if len(n.GroupLabels) > 1 {
+		labels := n.GroupLabels.Copy()
+		delete(labels, alert.AlertNameLabel)
+		subject += " (" + labels.String() + ")"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("From: " + e.cfg.From + "\r\n")
+	sb.WriteString("To: " + strings.Join(e.cfg.To, ", ") + "\r\n")
+	sb.WriteString("Subject: " + subject + "\r\n")
+	sb.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	sb.WriteString("\r\n")
+
+	for _, a := range n.Alerts {
+		sb.WriteString(fmt.Sprintf("[%s] %s = %s\r\n", a.State, a.Metric, strconv.FormatFloat(a.Value, 'g', -1, 64)))
+		sb.WriteString("  active since: " + a.ActiveAt.Format(time.RFC3339) + "\r\n")
+		if a.ResolvedAt != nil {
+			sb.WriteString("  resolved at: " + a.ResolvedAt.Format(time.RFC3339) + "\r\n")
+		}
+		sb.WriteString("  labels: " + a.Labels.String() + "\r\n")
+
+		names := make([]string, 0, len(a.Annotations))
+		for name := range a.Annotations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			sb.WriteString("  " + name + ": " + a.Annotations[name] + "\r\n")
+		}
+		sb.WriteString("\r\n")
+	}
+	return []byte(sb.String())
+}
@@ -0,0 +1,125 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"metrics/internal/core/alert"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mail is a message received by fakeSMTP.
+type mail struct {
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTP accepts a single session and sends the received message to the channel.
+func fakeSMTP(t *testing.T) (string, <-chan *mail) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	ch := make(chan *mail, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		tp := textproto.NewConn(conn)
+		m := &mail{}
+		reply := func(line string) { _ = tp.PrintfLine("%s", line) }
+		reply("220 localhost fake SMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				m.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				m.to = append(m.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				data, err := tp.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				m.data = string(data)
+				reply("250 OK")
+				ch <- m
+			case cmd == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String(), ch
+}
+
+func TestEmail(t *testing.T) {
+	addr, received := fakeSMTP(t)
+
+	notifier := NewEmail(&alert.EmailConfig{
+		Smarthost: addr,
+		From:      "metrics@example.com",
+		To:        []string{"ops@example.com", "dev@example.com"},
+	})
+	n := testNotification()
+	n.GroupLabels["host"] = "a"
+	require.NoError(t, notifier.Notify(context.Background(), n))
+
+	m := <-received
+	assert.Equal(t, "metrics@example.com", m.from)
+	assert.Equal(t, []string{"ops@example.com", "dev@example.com"}, m.to)
+
+	headers, err := textproto.NewReader(bufio.NewReader(strings.NewReader(m.data))).ReadMIMEHeader()
+	require.NoError(t, err)
+	assert.Equal(t, `[FIRING:1] HighHeap (host="a")`, headers.Get("Subject"))
+	assert.Equal(t, "ops@example.com, dev@example.com", headers.Get("To"))
+	assert.Contains(t, m.data, `[firing] HeapAlloc{host="a"} = 2e+09`)
+	assert.Contains(t, m.data, `[resolved] HeapAlloc{host="b"} = 1e+09`)
+	assert.Contains(t, m.data, "summary: Heap is too big")
+}
+
+func TestEmailTimeout(t *testing.T) {
+	// сервер принимает соединение, но не отвечает
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	notifier := NewEmail(&alert.EmailConfig{
+		Smarthost: ln.Addr().String(),
+		From:      "metrics@example.com",
+		To:        []string{"ops@example.com"},
+		Timeout:   alert.Duration(100 * time.Millisecond),
+	})
+	start := time.Now()
+	assert.Error(t, notifier.Notify(context.Background(), testNotification()))
+	assert.Less(t, time.Since(start), time.Second)
+}
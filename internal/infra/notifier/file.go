@@ -0,0 +1,43 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"metrics/internal/core/alert"
+)
+
+// File appends notifications to the file as NDJSON: one Alertmanager webhook message per line.
+// The file is opened on every notification, so it could be rotated by external tools.
+type File struct {
+	mux  *sync.Mutex
+	path string
+}
+
+func NewFile(cfg *alert.FileConfig) *File {
+	return &File{mux: &sync.Mutex{}, path: cfg.Path}
+}
+
+func (f *File) Notify(_ context.Context, n *alert.Notification) error {
+	line, err := json.Marshal(NewMessage(n))
+	if err != nil {
+		return fmt.Errorf("error marshalling notification: %w", err)
+	}
+	line = append(line, '\n')
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening notification file: %w", err)
+	}
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing notification file: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,40 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"metrics/internal/core/alert"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.ndjson")
+	notifier := NewFile(&alert.FileConfig{Path: path})
+
+	ctx := context.Background()
+	require.NoError(t, notifier.Notify(ctx, testNotification()))
+	resolved := testNotification()
+	resolved.Status = alert.StateResolved
+	require.NoError(t, notifier.Notify(ctx, resolved))
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var statuses []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var msg Message
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &msg))
+		statuses = append(statuses, msg.Status)
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []string{"firing", "resolved"}, statuses)
+}
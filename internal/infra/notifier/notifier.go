@@ -0,0 +1,117 @@
+// Package notifier implements alert notification channels: webhook, file and email.
+package notifier
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"metrics/internal/core/alert"
+)
+
+// NewNotifiers creates notifiers for the receivers, the result is keyed by receiver name.
+func NewNotifiers(receivers []*alert.ReceiverConfig) (map[string]alert.Notifier, error) {
+	notifiers := make(map[string]alert.Notifier, len(receivers))
+	for _, cfg := range receivers {
+		switch {
+		case cfg.Webhook != nil:
+			notifiers[cfg.Name] = NewWebhook(cfg.Webhook)
+		case cfg.File != nil:
+			notifiers[cfg.Name] = NewFile(cfg.File)
+		case cfg.Email != nil:
+			notifiers[cfg.Name] = NewEmail(cfg.Email)
+		default:
+			return nil, fmt.Errorf("receiver %s has no notification channel", cfg.Name)
+		}
+	}
+	return notifiers, nil
+}
+
+// Message is a notification in the Alertmanager webhook format (version 4).
+type Message struct {
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []*Alert          `json:"alerts"`
+	TruncatedAlerts   int               `json:"truncatedAlerts"`
+}
+
+// Alert is an alert in the Alertmanager webhook format. EndsAt is zero for firing alerts.
+type Alert struct {
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	Status       string            `json:"status"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// NewMessage converts the notification to the Alertmanager webhook format.
+func NewMessage(n *alert.Notification) *Message {
+	msg := &Message{
+		GroupLabels:       copyMap(n.GroupLabels),
+		CommonLabels:      map[string]string{},
+		CommonAnnotations: map[string]string{},
+		Version:           "4",
+		GroupKey:          n.GroupKey,
+		Status:            string(n.Status),
+		Receiver:          n.Receiver,
+		Alerts:            make([]*Alert, 0, len(n.Alerts)),
+	}
+
+	for i, a := range n.Alerts {
+		item := &Alert{
+			StartsAt:    a.ActiveAt,
+			Labels:      copyMap(a.Labels),
+			Annotations: copyMap(a.Annotations),
+			Status:      string(alert.StateFiring),
+			Fingerprint: fingerprint(a),
+		}
+		if a.State == alert.StateResolved {
+			item.Status = string(alert.StateResolved)
+			if a.ResolvedAt != nil {
+				item.EndsAt = *a.ResolvedAt
+			}
+		}
+		msg.Alerts = append(msg.Alerts, item)
+
+		if i == 0 {
+			msg.CommonLabels = copyMap(a.Labels)
+			msg.CommonAnnotations = copyMap(a.Annotations)
+			continue
+		}
+		intersect(msg.CommonLabels, a.Labels)
+		intersect(msg.CommonAnnotations, a.Annotations)
+	}
+	return msg
+}
+
+func fingerprint(a *alert.Alert) string {
+	h := fnv.New64a()
+	h.Write([]byte(a.Labels.String()))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+func copyMap(m map[string]string) map[string]string {
+	res := make(map[string]string, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	return res
+}
+
+// intersect removes items which are absent or differ in other.
+func intersect(common, other map[string]string) {
+	for k, v := range common {
+		if other[k] != v {
+			delete(common, k)
+		}
+	}
+}
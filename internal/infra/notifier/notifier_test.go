@@ -0,0 +1,78 @@
+package notifier
+
+import (
+	"testing"
+	"time"
+
+	"metrics/internal/core/alert"
+	"metrics/internal/core/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testNotification() *alert.Notification {
+	activeAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	resolvedAt := activeAt.Add(time.Minute)
+	return &alert.Notification{
+		GroupLabels: model.Labels{alert.AlertNameLabel: "HighHeap"},
+		Receiver:    "ops",
+		GroupKey:    `{}:{alertname="HighHeap"}`,
+		Status:      alert.StateFiring,
+		Alerts: []*alert.Alert{
+			{
+				ActiveAt:    activeAt,
+				Labels:      model.Labels{alert.AlertNameLabel: "HighHeap", "host": "a", "severity": "critical"},
+				Annotations: map[string]string{"summary": "Heap is too big"},
+				Rule:        "HighHeap",
+				Metric:      `HeapAlloc{host="a"}`,
+				State:       alert.StateFiring,
+				Value:       2e9,
+			},
+			{
+				ActiveAt:    activeAt,
+				ResolvedAt:  &resolvedAt,
+				Labels:      model.Labels{alert.AlertNameLabel: "HighHeap", "host": "b", "severity": "critical"},
+				Annotations: map[string]string{"summary": "Heap is too big"},
+				Rule:        "HighHeap",
+				Metric:      `HeapAlloc{host="b"}`,
+				State:       alert.StateResolved,
+				Value:       1e9,
+			},
+		},
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	msg := NewMessage(testNotification())
+
+	assert.Equal(t, "4", msg.Version)
+	assert.Equal(t, "firing", msg.Status)
+	assert.Equal(t, "ops", msg.Receiver)
+	assert.Equal(t, `{}:{alertname="HighHeap"}`, msg.GroupKey)
+	assert.Equal(t, map[string]string{"alertname": "HighHeap"}, msg.GroupLabels)
+	assert.Equal(t, map[string]string{"alertname": "HighHeap", "severity": "critical"}, msg.CommonLabels)
+	assert.Equal(t, map[string]string{"summary": "Heap is too big"}, msg.CommonAnnotations)
+
+	require.Len(t, msg.Alerts, 2)
+	assert.Equal(t, "firing", msg.Alerts[0].Status)
+	assert.True(t, msg.Alerts[0].EndsAt.IsZero())
+	assert.Equal(t, "resolved", msg.Alerts[1].Status)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC), msg.Alerts[1].EndsAt)
+	assert.NotEqual(t, msg.Alerts[0].Fingerprint, msg.Alerts[1].Fingerprint)
+}
+
+func TestNewNotifiers(t *testing.T) {
+	notifiers, err := NewNotifiers([]*alert.ReceiverConfig{
+		{Name: "ops", Webhook: &alert.WebhookConfig{URL: "http://localhost/hook"}},
+		{Name: "audit", File: &alert.FileConfig{Path: "/tmp/alerts.ndjson"}},
+		{Name: "mail", Email: &alert.EmailConfig{Smarthost: "localhost:25", From: "a@b.c", To: []string{"d@e.f"}}},
+	})
+	require.NoError(t, err)
+	assert.IsType(t, &Webhook{}, notifiers["ops"])
+	assert.IsType(t, &File{}, notifiers["audit"])
+	assert.IsType(t, &Email{}, notifiers["mail"])
+
+	_, err = NewNotifiers([]*alert.ReceiverConfig{{Name: "empty"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"metrics/internal/core/alert"
+	"metrics/internal/logger"
+	"metrics/internal/retrier"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWebhookTimeout     = 10 * time.Second
+	defaultWebhookMaxAttempts = 3
+)
+
+// errRetryable marks errors after which the webhook request is repeated: network errors and 5xx or 429 responses.
+var errRetryable = errors.New("retryable webhook error")
+
+// Webhook posts notifications in the Alertmanager webhook format, so existing Alertmanager receivers could be used.
+type Webhook struct {
+	client  *http.Client
+	retrier *retrier.Retrier
+	url     string
+}
+
+func NewWebhook(cfg *alert.WebhookConfig) *Webhook {
+	timeout := time.Duration(cfg.Timeout)
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	attempts := cfg.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultWebhookMaxAttempts
+	}
+
+	ret := &retrier.Retrier{
+		Strategy: retrier.Backoff(
+			attempts,       // max attempts
+			1*time.Second,  // initial delay
+			2,              // multiplier
+			10*time.Second, // max delay
+		),
+		OnRetry: func(ctx context.Context, n int, err error) {
+			logger.Log.Debug(fmt.Sprintf("Retrying webhook %s. retry #%d: %v", cfg.URL, n, err))
+		},
+	}
+	return &Webhook{
+		client:  &http.Client{Timeout: timeout},
+		retrier: ret,
+		url:     cfg.URL,
+	}
+}
+
+func (w *Webhook) Notify(ctx context.Context, n *alert.Notification) error {
+	body, err := json.Marshal(NewMessage(n))
+	if err != nil {
+		return fmt.Errorf("error marshalling webhook message: %w", err)
+	}
+
+	fun := func() error {
+		return w.post(ctx, body)
+	}
+	return w.retrier.Do(ctx, fun, errRetryable)
+}
+
+func (w *Webhook) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errRetryable, err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		logger.Log.Debug("Error reading webhook response", zap.Error(err))
+	}
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: unexpected status code %d", errRetryable, resp.StatusCode)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
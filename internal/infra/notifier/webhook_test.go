@@ -0,0 +1,64 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"metrics/internal/core/alert"
+	"metrics/internal/retrier"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int32
+		wantErr  bool
+	}{
+		{name: "ok", statuses: []int{http.StatusOK}, attempts: 1},
+		{name: "retry server error", statuses: []int{http.StatusBadGateway, http.StatusOK}, attempts: 2},
+		{name: "retry too many requests", statuses: []int{http.StatusTooManyRequests, http.StatusOK}, attempts: 2},
+		{name: "no retry on bad request", statuses: []int{http.StatusBadRequest}, attempts: 1, wantErr: true},
+		{
+			name:     "attempts exceeded",
+			statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			attempts: 3,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			var msg Message
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := calls.Add(1)
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			webhook := NewWebhook(&alert.WebhookConfig{URL: srv.URL, MaxAttempts: 3})
+			webhook.retrier.Strategy = retrier.Backoff(3, time.Millisecond, 1, time.Millisecond)
+
+			err := webhook.Notify(context.Background(), testNotification())
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.attempts, calls.Load())
+			assert.Equal(t, "ops", msg.Receiver)
+			assert.Len(t, msg.Alerts, 2)
+		})
+	}
+}
@@ -0,0 +1,273 @@
+// Package otlp maps OpenTelemetry (OTLP) metrics to the model.
+package otlp
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// ResourceLabels are resource attributes added to labels of every metric of the resource,
+// so metrics of different services do not collide. Other resource attributes are dropped.
+var ResourceLabels = []string{"service.name", "service.namespace", "service.instance.id", "host.name"}
+
+const noRecordedValue = uint32(metricspb.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK)
+
+// Converter maps OTLP metrics to gauges, counters and histograms:
+//   - Gauge and non-monotonic cumulative Sum are gauges, the latest data point of a series is used;
+//   - monotonic Sum is a counter. Cumulative values are converted to deltas by the tracker,
+//     fractional parts of delta values are carried over by the tracker too;
+//   - Histogram with explicit buckets is a histogram. Cumulative bucket counts are converted to deltas too.
+//
+// Exponential histograms, summaries and non-monotonic delta sums are not supported.
+// Data points have the attributes of the point and ResourceLabels of the resource as labels.
+type Converter struct {
+	tracker *service.CumulativeTracker
+}
+
+func NewConverter(tracker *service.CumulativeTracker) *Converter {
+	return &Converter{tracker: tracker}
+}
+
+// Rollback returns deltas of metrics which were not stored to the tracker, they are added to the next request.
+func (c *Converter) Rollback(metrics []*model.MetricsV2) {
+	c.tracker.Rollback(metrics)
+}
+
+// Convert returns metrics to be upserted and data points which could not be stored.
+func (c *Converter) Convert(req *colmetricspb.ExportMetricsServiceRequest) ([]*model.MetricsV2, []*model.UnsupportedSeries) {
+	metrics := make([]*model.MetricsV2, 0)
+	unsupported := make([]*model.UnsupportedSeries, 0)
+	for _, rm := range req.GetResourceMetrics() {
+		resource := resourceLabels(rm.GetResource().GetAttributes())
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				res, u := c.convertMetric(m, resource)
+				metrics = append(metrics, res...)
+				unsupported = append(unsupported, u...)
+			}
+		}
+	}
+	return metrics, unsupported
+}
+
+func (c *Converter) convertMetric(m *metricspb.Metric, resource model.Labels) ([]*model.MetricsV2, []*model.UnsupportedSeries) {
+	name := m.GetName()
+	switch data := m.GetData().(type) {
+	case *metricspb.Metric_Gauge:
+		return c.gauges(name, resource, data.Gauge.GetDataPoints()), nil
+	case *metricspb.Metric_Sum:
+		sum := data.Sum
+		cumulative := sum.GetAggregationTemporality() == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+		switch {
+		case sum.GetIsMonotonic():
+			return c.counters(name, resource, sum.GetDataPoints(), cumulative), nil
+		case cumulative:
+			return c.gauges(name, resource, sum.GetDataPoints()), nil
+		default:
+			return nil, unsupportedPoints(name, resource, numberAttributes(sum.GetDataPoints()), "non-monotonic delta sums are not supported")
+		}
+	case *metricspb.Metric_Histogram:
+		h := data.Histogram
+		cumulative := h.GetAggregationTemporality() == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+		return c.histograms(name, resource, h.GetDataPoints(), cumulative)
+	case *metricspb.Metric_ExponentialHistogram:
+		attrs := make([][]*commonpb.KeyValue, 0, len(data.ExponentialHistogram.GetDataPoints()))
+		for _, p := range data.ExponentialHistogram.GetDataPoints() {
+			attrs = append(attrs, p.GetAttributes())
+		}
+		return nil, unsupportedPoints(name, resource, attrs, "exponential histograms are not supported")
+	case *metricspb.Metric_Summary:
+		attrs := make([][]*commonpb.KeyValue, 0, len(data.Summary.GetDataPoints()))
+		for _, p := range data.Summary.GetDataPoints() {
+			attrs = append(attrs, p.GetAttributes())
+		}
+		return nil, unsupportedPoints(name, resource, attrs, "summaries are not supported")
+	default:
+		return nil, []*model.UnsupportedSeries{{Series: name, Reason: "metric has no data"}}
+	}
+}
+
+func (c *Converter) gauges(name string, resource model.Labels, points []*metricspb.NumberDataPoint) []*model.MetricsV2 {
+	latest := make(map[string]*metricspb.NumberDataPoint)
+	order := make([]string, 0, len(points))
+	for _, p := range points {
+		if _, ok := numberValue(p); !ok {
+			continue
+		}
+		key := model.MetricKey(name, pointLabels(resource, p.GetAttributes()))
+		prev, ok := latest[key]
+		if !ok {
+			order = append(order, key)
+		}
+		if !ok || p.GetTimeUnixNano() >= prev.GetTimeUnixNano() {
+			latest[key] = p
+		}
+	}
+
+	res := make([]*model.MetricsV2, 0, len(order))
+	for _, key := range order {
+		p := latest[key]
+		value, _ := numberValue(p)
+		labels := pointLabels(resource, p.GetAttributes())
+		res = append(res, &model.MetricsV2{ID: name, MType: model.GaugeType, Labels: labels, Value: &value})
+	}
+	return res
+}
+
+func (c *Converter) counters(
+	name string, resource model.Labels, points []*metricspb.NumberDataPoint, cumulative bool,
+) []*model.MetricsV2 {
+	res := make([]*model.MetricsV2, 0, len(points))
+	for _, p := range points {
+		value, ok := numberValue(p)
+		if !ok {
+			continue
+		}
+		labels := pointLabels(resource, p.GetAttributes())
+
+		var delta int64
+		if cumulative {
+			delta, ok = c.tracker.Delta(model.MetricKey(name, labels), pointTime(p.GetTimeUnixNano()), value)
+			if !ok {
+				continue
+			}
+		} else {
+			delta = c.tracker.Add(model.MetricKey(name, labels), value)
+		}
+		res = append(res, &model.MetricsV2{ID: name, MType: model.CounterType, Labels: labels, Delta: &delta})
+	}
+	return res
+}
+
+func (c *Converter) histograms(
+	name string, resource model.Labels, points []*metricspb.HistogramDataPoint, cumulative bool,
+) ([]*model.MetricsV2, []*model.UnsupportedSeries) {
+	res := make([]*model.MetricsV2, 0, len(points))
+	unsupported := make([]*model.UnsupportedSeries, 0)
+	for _, p := range points {
+		if p.GetFlags()&noRecordedValue != 0 {
+			continue
+		}
+		labels := pointLabels(resource, p.GetAttributes())
+		key := model.MetricKey(name, labels)
+
+		value := &model.HistogramValue{Buckets: p.GetExplicitBounds(), Sum: p.GetSum()}
+		for _, count := range p.GetBucketCounts() {
+			value.Counts = append(value.Counts, int64(count))
+		}
+		if len(value.Counts) == 0 && len(value.Buckets) == 0 {
+			value.Counts = []int64{int64(p.GetCount())}
+		}
+		if err := value.Validate(); err != nil {
+			unsupported = append(unsupported, &model.UnsupportedSeries{Series: key, Reason: "invalid histogram: " + err.Error()})
+			continue
+		}
+		if math.IsNaN(value.Sum) || math.IsInf(value.Sum, 0) {
+			unsupported = append(unsupported, &model.UnsupportedSeries{
+				Series: key,
+				Reason: "invalid histogram: sum is not finite",
+			})
+			continue
+		}
+
+		if cumulative {
+			var ok bool
+			value, ok = c.tracker.HistogramDelta(key, pointTime(p.GetTimeUnixNano()), value)
+			if !ok {
+				continue
+			}
+		}
+		res = append(res, &model.MetricsV2{ID: name, MType: model.HistogramType, Labels: labels, Histogram: value})
+	}
+	return res, unsupported
+}
+
+func numberValue(p *metricspb.NumberDataPoint) (float64, bool) {
+	if p.GetFlags()&noRecordedValue != 0 {
+		return 0, false
+	}
+	switch v := p.GetValue().(type) {
+	case *metricspb.NumberDataPoint_AsDouble:
+		return v.AsDouble, !math.IsNaN(v.AsDouble) && !math.IsInf(v.AsDouble, 0)
+	case *metricspb.NumberDataPoint_AsInt:
+		return float64(v.AsInt), true
+	default:
+		return 0, false
+	}
+}
+
+func numberAttributes(points []*metricspb.NumberDataPoint) [][]*commonpb.KeyValue {
+	attrs := make([][]*commonpb.KeyValue, 0, len(points))
+	for _, p := range points {
+		attrs = append(attrs, p.GetAttributes())
+	}
+	return attrs
+}
+
+func unsupportedPoints(name string, resource model.Labels, attrs [][]*commonpb.KeyValue, reason string) []*model.UnsupportedSeries {
+	res := make([]*model.UnsupportedSeries, 0, len(attrs))
+	for _, a := range attrs {
+		res = append(res, &model.UnsupportedSeries{Series: model.MetricKey(name, pointLabels(resource, a)), Reason: reason})
+	}
+	return res
+}
+
+func pointTime(nanos uint64) time.Time {
+	return time.Unix(0, int64(nanos))
+}
+
+func resourceLabels(attrs []*commonpb.KeyValue) model.Labels {
+	labels := model.Labels{}
+	for _, attr := range attrs {
+		for _, name := range ResourceLabels {
+			if attr.GetKey() != name {
+				continue
+			}
+			if value, ok := attributeValue(attr.GetValue()); ok {
+				labels[name] = value
+			}
+		}
+	}
+	return labels
+}
+
+// pointLabels returns attributes of the point with resource labels. Resource labels take precedence.
+func pointLabels(resource model.Labels, attrs []*commonpb.KeyValue) model.Labels {
+	if len(resource) == 0 && len(attrs) == 0 {
+		return nil
+	}
+	labels := make(model.Labels, len(resource)+len(attrs))
+	for _, attr := range attrs {
+		if value, ok := attributeValue(attr.GetValue()); ok {
+			labels[attr.GetKey()] = value
+		}
+	}
+	for name, value := range resource {
+		labels[name] = value
+	}
+	return labels
+}
+
+// attributeValue formats scalar attribute values, arrays, maps and bytes are skipped.
+func attributeValue(v *commonpb.AnyValue) (string, bool) {
+	switch value := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return value.StringValue, true
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(value.BoolValue), true
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(value.IntValue, 10), true
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(value.DoubleValue, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,188 @@
+package otlp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+func stringAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func request(service string, requests float64, ts time.Time) *colmetricspb.ExportMetricsServiceRequest {
+	nanos := uint64(ts.UnixNano())
+	cumulative := metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+				stringAttr("service.name", service),
+				stringAttr("process.pid", "42"),
+			}},
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Metrics: []*metricspb.Metric{
+					{
+						Name: "http.server.requests",
+						Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+							IsMonotonic:            true,
+							AggregationTemporality: cumulative,
+							DataPoints: []*metricspb.NumberDataPoint{{
+								Attributes:   []*commonpb.KeyValue{stringAttr("method", "GET")},
+								TimeUnixNano: nanos,
+								Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: requests},
+							}},
+						}},
+					},
+					{
+						Name: "queue.size",
+						Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+							DataPoints: []*metricspb.NumberDataPoint{
+								{TimeUnixNano: nanos, Value: &metricspb.NumberDataPoint_AsInt{AsInt: 7}},
+								{TimeUnixNano: nanos - 1, Value: &metricspb.NumberDataPoint_AsInt{AsInt: 3}},
+							},
+						}},
+					},
+					{
+						Name: "http.server.duration",
+						Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+							AggregationTemporality: cumulative,
+							DataPoints: []*metricspb.HistogramDataPoint{{
+								TimeUnixNano:   nanos,
+								ExplicitBounds: []float64{0.1, 1},
+								BucketCounts:   []uint64{uint64(requests), 0, 0},
+								Sum:            &requests,
+							}},
+						}},
+					},
+					{
+						Name: "http.server.size",
+						Data: &metricspb.Metric_Summary{Summary: &metricspb.Summary{
+							DataPoints: []*metricspb.SummaryDataPoint{{TimeUnixNano: nanos}},
+						}},
+					},
+				},
+			}},
+		}},
+	}
+}
+
+func TestConvert(t *testing.T) {
+	converter := NewConverter(service.NewCumulativeTracker(time.Hour))
+	ts := time.Now()
+
+	metrics, unsupported := converter.Convert(request("api", 10, ts))
+	require.Len(t, unsupported, 1)
+	assert.Equal(t, `http.server.size{service.name="api"}`, unsupported[0].Series)
+	require.Len(t, metrics, 3)
+
+	assert.Equal(t, "http.server.requests", metrics[0].ID)
+	assert.Equal(t, model.CounterType, metrics[0].MType)
+	assert.Equal(t, model.Labels{"service.name": "api", "method": "GET"}, metrics[0].Labels)
+	// первые накопленные значения - точка отсчета
+	assert.Equal(t, int64(0), *metrics[0].Delta)
+
+	assert.Equal(t, "queue.size", metrics[1].ID)
+	assert.Equal(t, model.GaugeType, metrics[1].MType)
+	assert.Equal(t, model.Labels{"service.name": "api"}, metrics[1].Labels)
+	assert.Equal(t, 7.0, *metrics[1].Value)
+
+	assert.Equal(t, model.HistogramType, metrics[2].MType)
+	assert.Equal(t, []int64{0, 0, 0}, metrics[2].Histogram.Counts)
+
+	// cumulative values are converted to deltas per service
+	metrics, _ = converter.Convert(request("api", 15, ts.Add(time.Second)))
+	assert.Equal(t, int64(5), *metrics[0].Delta)
+	assert.Equal(t, []int64{5, 0, 0}, metrics[2].Histogram.Counts)
+	assert.Equal(t, 5.0, metrics[2].Histogram.Sum)
+
+	metrics, _ = converter.Convert(request("worker", 15, ts.Add(time.Second)))
+	assert.Equal(t, int64(0), *metrics[0].Delta)
+	assert.Equal(t, model.Labels{"service.name": "worker", "method": "GET"}, metrics[0].Labels)
+}
+
+func TestConvertDeltaSum(t *testing.T) {
+	converter := NewConverter(service.NewCumulativeTracker(time.Hour))
+	req := &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Metrics: []*metricspb.Metric{{
+					Name: "cpu.time",
+					Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+						IsMonotonic:            true,
+						AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+						DataPoints: []*metricspb.NumberDataPoint{
+							{Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 0.4}},
+						},
+					}},
+				}},
+			}},
+		}},
+	}
+
+	var total int64
+	for range 5 {
+		metrics, _ := converter.Convert(req)
+		require.Len(t, metrics, 1)
+		total += *metrics[0].Delta
+	}
+	// дробные приращения не теряются
+	assert.Equal(t, int64(2), total)
+
+	// приращение, которое не удалось сохранить, добавляется к следующему запросу
+	metrics, _ := converter.Convert(req)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, int64(0), *metrics[0].Delta)
+	metrics, _ = converter.Convert(req)
+	require.Equal(t, int64(1), *metrics[0].Delta)
+	converter.Rollback(metrics)
+	metrics, _ = converter.Convert(req)
+	assert.Equal(t, int64(1), *metrics[0].Delta)
+}
+
+func TestConvertNotFinite(t *testing.T) {
+	converter := NewConverter(service.NewCumulativeTracker(time.Hour))
+	sum := math.NaN()
+	req := func(value float64) *colmetricspb.ExportMetricsServiceRequest {
+		point := []*metricspb.NumberDataPoint{{Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: value}}}
+		return &colmetricspb.ExportMetricsServiceRequest{
+			ResourceMetrics: []*metricspb.ResourceMetrics{{
+				ScopeMetrics: []*metricspb.ScopeMetrics{{
+					Metrics: []*metricspb.Metric{
+						{Name: "cpu.time", Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+							IsMonotonic:            true,
+							AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+							DataPoints:             point,
+						}}},
+						{Name: "load", Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: point}}},
+						{Name: "latency", Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+							AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+							DataPoints:             []*metricspb.HistogramDataPoint{{Count: 1, Sum: &sum}},
+						}}},
+					},
+				}},
+			}},
+		}
+	}
+
+	metrics, unsupported := converter.Convert(req(math.Inf(1)))
+	assert.Empty(t, metrics)
+	assert.Equal(t, []*model.UnsupportedSeries{
+		{Series: "latency", Reason: "invalid histogram: sum is not finite"},
+	}, unsupported)
+
+	// бесконечность не ломает следующие приращения
+	metrics, _ = converter.Convert(req(3))
+	require.Len(t, metrics, 2)
+	assert.Equal(t, int64(3), *metrics[0].Delta)
+	assert.Equal(t, 3.0, *metrics[1].Value)
+}
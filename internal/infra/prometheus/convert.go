@@ -0,0 +1,166 @@
+package prometheus
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+)
+
+// staleNaN is the Prometheus staleness marker, such samples are skipped.
+const staleNaN uint64 = 0x7ff0000000000002
+
+// Converter maps remote write series to gauges and counters.
+// Type of a series is taken from the request metadata, without metadata series with `_total` suffix are counters
+// and the others are gauges. Cumulative counter values are converted to deltas by the tracker.
+type Converter struct {
+	tracker *service.CumulativeTracker
+}
+
+func NewConverter(tracker *service.CumulativeTracker) *Converter {
+	return &Converter{tracker: tracker}
+}
+
+// Rollback returns deltas of counters which were not stored to the tracker, they are added to the next request.
+func (c *Converter) Rollback(metrics []*model.MetricsV2) {
+	c.tracker.Rollback(metrics)
+}
+
+// Convert returns metrics to be upserted and series which could not be stored.
+// NaN and infinite samples are skipped and their series are reported as unsupported,
+// stale markers are skipped silently.
+func (c *Converter) Convert(req *WriteRequest) ([]*model.MetricsV2, []*model.UnsupportedSeries) {
+	types := make(map[string]MetricMetadataType, len(req.Metadata))
+	for _, md := range req.Metadata {
+		types[md.FamilyName] = md.Type
+	}
+
+	metrics := make([]*model.MetricsV2, 0, len(req.Timeseries))
+	unsupported := make([]*model.UnsupportedSeries, 0)
+	for _, ts := range req.Timeseries {
+		name, labels := splitLabels(ts.Labels)
+		if name == "" {
+			unsupported = append(unsupported, &model.UnsupportedSeries{
+				Series: "{" + labels.String() + "}",
+				Reason: "series has no __name__ label",
+			})
+			continue
+		}
+		key := model.MetricKey(name, labels)
+		if ts.Histograms > 0 {
+			unsupported = append(unsupported, &model.UnsupportedSeries{
+				Series: key,
+				Reason: "native histograms are not supported",
+			})
+			continue
+		}
+		mType, reason := seriesType(name, labels, types)
+		if reason != "" {
+			unsupported = append(unsupported, &model.UnsupportedSeries{Series: key, Reason: reason})
+			continue
+		}
+
+		samples := make([]Sample, 0, len(ts.Samples))
+		invalid := false
+		for _, s := range ts.Samples {
+			switch {
+			case math.Float64bits(s.Value) == staleNaN:
+			case math.IsNaN(s.Value) || math.IsInf(s.Value, 0):
+				invalid = true
+			default:
+				samples = append(samples, s)
+			}
+		}
+		if invalid {
+			unsupported = append(unsupported, &model.UnsupportedSeries{
+				Series: key,
+				Reason: "NaN and infinite values are not supported",
+			})
+		}
+		if len(samples) == 0 {
+			continue
+		}
+		sort.SliceStable(samples, func(i, j int) bool { return samples[i].Timestamp < samples[j].Timestamp })
+
+		switch mType {
+		case model.GaugeType:
+			value := samples[len(samples)-1].Value
+			metrics = append(metrics, &model.MetricsV2{ID: name, MType: model.GaugeType, Labels: labels, Value: &value})
+		case model.CounterType:
+			var delta int64
+			accepted := false
+			for _, s := range samples {
+				d, ok := c.tracker.Delta(key, time.UnixMilli(s.Timestamp), s.Value)
+				if ok {
+					delta += d
+					accepted = true
+				}
+			}
+			if accepted {
+				metrics = append(metrics, &model.MetricsV2{ID: name, MType: model.CounterType, Labels: labels, Delta: &delta})
+			}
+		}
+	}
+	return metrics, unsupported
+}
+
+func splitLabels(items []Label) (string, model.Labels) {
+	var name string
+	var labels model.Labels
+	for _, l := range items {
+		if l.Name == "__name__" {
+			name = l.Value
+			continue
+		}
+		if labels == nil {
+			labels = model.Labels{}
+		}
+		labels[l.Name] = l.Value
+	}
+	return name, labels
+}
+
+// seriesType returns the metric type of the series or the reason why it is not supported.
+func seriesType(name string, labels model.Labels, types map[string]MetricMetadataType) (model.MetricType, string) {
+	if t, ok := types[name]; ok {
+		switch t {
+		case MetadataCounter:
+			return model.CounterType, ""
+		case MetadataSummary:
+			return "", "summaries are not supported"
+		case MetadataHistogram, MetadataGaugeHistogram:
+			return "", "histograms are not supported"
+		default:
+			return model.GaugeType, ""
+		}
+	}
+	for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+		family, found := strings.CutSuffix(name, suffix)
+		if !found {
+			continue
+		}
+		switch types[family] {
+		case MetadataSummary:
+			return "", "summaries are not supported"
+		case MetadataHistogram, MetadataGaugeHistogram:
+			return "", "histograms are not supported"
+		}
+	}
+
+	// there is no metadata, guess by naming conventions
+	_, hasLe := labels["le"]
+	_, hasQuantile := labels["quantile"]
+	switch {
+	case strings.HasSuffix(name, "_bucket") && hasLe:
+		return "", "histograms are not supported"
+	case hasQuantile:
+		return "", "summaries are not supported"
+	case strings.HasSuffix(name, "_total"):
+		return model.CounterType, ""
+	default:
+		return model.GaugeType, ""
+	}
+}
@@ -0,0 +1,165 @@
+package prometheus
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"metrics/internal/core/model"
+)
+
+// TextSample is a sample of the text exposition format. Values of counters are totals.
+type TextSample struct {
+	Labels model.Labels
+	Name   string
+	Type   model.MetricType
+	Value  float64
+}
+
+var textTypes = map[string]MetricMetadataType{
+	"counter":   MetadataCounter,
+	"gauge":     MetadataGauge,
+	"summary":   MetadataSummary,
+	"histogram": MetadataHistogram,
+	"untyped":   MetadataUnknown,
+}
+
+// ParseText parses the text exposition format. Types are taken from `# TYPE` lines like the remote write metadata,
+// samples of histograms and summaries are skipped, timestamps are ignored.
+// Invalid lines are returned as the error together with the valid samples.
+func ParseText(r io.Reader) ([]*TextSample, error) {
+	types := make(map[string]MetricMetadataType)
+	samples := make([]*TextSample, 0)
+	var errs []error
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if comment, ok := strings.CutPrefix(line, "#"); ok {
+			fields := strings.Fields(comment)
+			if len(fields) < 3 || fields[0] != "TYPE" {
+				continue
+			}
+			t, ok := textTypes[fields[2]]
+			if !ok {
+				errs = append(errs, fmt.Errorf("line %d: unknown type %s", n, fields[2]))
+				continue
+			}
+			types[fields[1]] = t
+			continue
+		}
+
+		s, err := parseSample(line)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", n, err))
+			continue
+		}
+		samples = append(samples, s)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("error reading metrics: %w", err))
+	}
+
+	res := samples[:0]
+	for _, s := range samples {
+		mType, reason := seriesType(s.Name, s.Labels, types)
+		if reason != "" {
+			continue
+		}
+		s.Type = mType
+		res = append(res, s)
+	}
+	return res, errors.Join(errs...)
+}
+
+// parseSample parses lines like `name{label="value"} 1.5 1718000000000`.
+func parseSample(line string) (*TextSample, error) {
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return nil, errors.New("sample has no value")
+	}
+	s := &TextSample{Name: line[:end]}
+	if !validName(s.Name, true) {
+		return nil, fmt.Errorf("invalid metric name %q", s.Name)
+	}
+
+	rest := line[end:]
+	if strings.HasPrefix(rest, "{") {
+		var err error
+		if s.Labels, rest, err = parseLabels(rest[1:]); err != nil {
+			return nil, fmt.Errorf("metric %s: %w", s.Name, err)
+		}
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("metric %s: invalid value", s.Name)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("metric %s: invalid value %q", s.Name, fields[0])
+	}
+	s.Value = value
+	return s, nil
+}
+
+// parseLabels parses labels after the opening brace and returns the rest of the line after the closing one.
+func parseLabels(s string) (model.Labels, string, error) {
+	var labels model.Labels
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if rest, ok := strings.CutPrefix(s, "}"); ok {
+			return labels, rest, nil
+		}
+		name, rest, ok := strings.Cut(s, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !validName(name, false) {
+			return nil, "", fmt.Errorf("invalid label name %q", name)
+		}
+		rest = strings.TrimLeft(rest, " \t")
+		if !strings.HasPrefix(rest, `"`) {
+			return nil, "", fmt.Errorf("label %s: value is not quoted", name)
+		}
+
+		var value strings.Builder
+		i := 1
+		for ; i < len(rest) && rest[i] != '"'; i++ {
+			if rest[i] != '\\' || i+1 == len(rest) {
+				value.WriteByte(rest[i])
+				continue
+			}
+			i++
+			switch rest[i] {
+			case 'n':
+				value.WriteByte('\n')
+			case '\\', '"':
+				value.WriteByte(rest[i])
+			default:
+				value.WriteByte('\\')
+				value.WriteByte(rest[i])
+			}
+		}
+		if i == len(rest) {
+			return nil, "", fmt.Errorf("label %s: value is not terminated", name)
+		}
+		if labels == nil {
+			labels = model.Labels{}
+		}
+		labels[name] = value.String()
+
+		s = strings.TrimLeft(rest[i+1:], " \t")
+		s = strings.TrimPrefix(s, ",")
+	}
+}
+
+// validName checks the name is [a-zA-Z_:][a-zA-Z0-9_:]* for metrics and [a-zA-Z_][a-zA-Z0-9_]* for labels.
+func validName(name string, allowColon bool) bool {
+	return name != "" && sanitize(name, allowColon) == name
+}
@@ -0,0 +1,63 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+
+	"metrics/internal/core/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseText(t *testing.T) {
+	text := `# HELP jobs_total Processed jobs.
+# TYPE jobs_total counter
+jobs_total{queue="mail",path="a\"b\\c\nd"} 15 1718000000000
+jobs_total{queue="sms",} 3
+# TYPE temperature gauge
+temperature 21.5
+
+# TYPE latency histogram
+latency_bucket{le="+Inf"} 3
+latency_sum 2.6
+latency_count 3
+# TYPE rpc summary
+rpc{quantile="0.5"} 0.1
+rpc_count 7
+backups_total 2
+free_bytes 1e3
+`
+	samples, err := ParseText(strings.NewReader(text))
+	require.NoError(t, err)
+	assert.Equal(t, []*TextSample{
+		{Name: "jobs_total", Labels: model.Labels{"queue": "mail", "path": "a\"b\\c\nd"}, Type: model.CounterType, Value: 15},
+		{Name: "jobs_total", Labels: model.Labels{"queue": "sms"}, Type: model.CounterType, Value: 3},
+		{Name: "temperature", Type: model.GaugeType, Value: 21.5},
+		{Name: "backups_total", Type: model.CounterType, Value: 2},
+		{Name: "free_bytes", Type: model.GaugeType, Value: 1000},
+	}, samples)
+}
+
+func TestParseTextInvalidLines(t *testing.T) {
+	text := `temperature 21.5
+temperature
+1temperature 1
+temperature{room=kitchen} 1
+temperature{room="kitchen} 1
+temperature{room-name="kitchen"} 1
+temperature abc
+temperature 1 2 3
+# TYPE temperature meter
+pressure 760
+`
+	samples, err := ParseText(strings.NewReader(text))
+	require.Error(t, err)
+	for _, line := range []string{"line 2:", "line 3:", "line 4:", "line 5:", "line 6:", "line 7:", "line 8:", "line 9:"} {
+		assert.Contains(t, err.Error(), line)
+	}
+	assert.Equal(t, []*TextSample{
+		{Name: "temperature", Type: model.GaugeType, Value: 21.5},
+		{Name: "pressure", Type: model.GaugeType, Value: 760},
+	}, samples)
+}
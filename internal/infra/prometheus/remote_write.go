@@ -0,0 +1,218 @@
+package prometheus
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Types of prometheus.WriteRequest (prompb) which are needed by the remote write receiver.
+// They are decoded with protowire, unknown fields are skipped.
+
+// MetricMetadataType is prompb.MetricMetadata.MetricType.
+type MetricMetadataType int32
+
+const (
+	MetadataUnknown        MetricMetadataType = 0
+	MetadataCounter        MetricMetadataType = 1
+	MetadataGauge          MetricMetadataType = 2
+	MetadataSummary        MetricMetadataType = 3
+	MetadataHistogram      MetricMetadataType = 4
+	MetadataGaugeHistogram MetricMetadataType = 5
+	MetadataInfo           MetricMetadataType = 6
+	MetadataStateset       MetricMetadataType = 7
+)
+
+type WriteRequest struct {
+	Timeseries []*TimeSeries
+	Metadata   []*MetricMetadata
+}
+
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+	// Histograms is amount of native histogram samples, they are not supported and not decoded.
+	Histograms int
+}
+
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a value with timestamp in milliseconds.
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+type MetricMetadata struct {
+	FamilyName string
+	Type       MetricMetadataType
+}
+
+// DecodeWriteRequest decodes protobuf WriteRequest (already uncompressed).
+func DecodeWriteRequest(data []byte) (*WriteRequest, error) {
+	req := &WriteRequest{}
+	err := decodeMessage(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			ts, err := decodeTimeSeries(value)
+			if err != nil {
+				return fmt.Errorf("timeseries: %w", err)
+			}
+			req.Timeseries = append(req.Timeseries, ts)
+		case num == 3 && typ == protowire.BytesType:
+			md, err := decodeMetadata(value)
+			if err != nil {
+				return fmt.Errorf("metadata: %w", err)
+			}
+			req.Metadata = append(req.Metadata, md)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not decode write request: %w", err)
+	}
+	return req, nil
+}
+
+func decodeTimeSeries(data []byte) (*TimeSeries, error) {
+	ts := &TimeSeries{}
+	err := decodeMessage(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			label, err := decodeLabel(value)
+			if err != nil {
+				return fmt.Errorf("label: %w", err)
+			}
+			ts.Labels = append(ts.Labels, label)
+		case num == 2 && typ == protowire.BytesType:
+			sample, err := decodeSample(value)
+			if err != nil {
+				return fmt.Errorf("sample: %w", err)
+			}
+			ts.Samples = append(ts.Samples, sample)
+		case num == 4 && typ == protowire.BytesType:
+			ts.Histograms++
+		}
+		return nil
+	})
+	return ts, err
+}
+
+func decodeLabel(data []byte) (Label, error) {
+	var label Label
+	err := decodeMessage(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			label.Name = string(value)
+		case num == 2 && typ == protowire.BytesType:
+			label.Value = string(value)
+		}
+		return nil
+	})
+	return label, err
+}
+
+func decodeSample(data []byte) (Sample, error) {
+	var sample Sample
+	err := decodeMessage(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch {
+		case num == 1 && typ == protowire.Fixed64Type:
+			v, _ := protowire.ConsumeFixed64(value)
+			sample.Value = math.Float64frombits(v)
+		case num == 2 && typ == protowire.VarintType:
+			v, _ := protowire.ConsumeVarint(value)
+			sample.Timestamp = int64(v)
+		}
+		return nil
+	})
+	return sample, err
+}
+
+func decodeMetadata(data []byte) (*MetricMetadata, error) {
+	md := &MetricMetadata{}
+	err := decodeMessage(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			v, _ := protowire.ConsumeVarint(value)
+			md.Type = MetricMetadataType(v)
+		case num == 2 && typ == protowire.BytesType:
+			md.FamilyName = string(value)
+		}
+		return nil
+	})
+	return md, err
+}
+
+// decodeMessage calls fn for every field of the message. For length-delimited fields value is the field content,
+// for other types it is the raw encoded value.
+func decodeMessage(data []byte, fn func(num protowire.Number, typ protowire.Type, value []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		var value []byte
+		if typ == protowire.BytesType {
+			v, m := protowire.ConsumeBytes(data)
+			if m < 0 {
+				return protowire.ParseError(m)
+			}
+			value, n = v, m
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			value = data[:n]
+		}
+		if err := fn(num, typ, value); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// Marshal encodes the request to protobuf. Native histograms are not encoded.
+func (req *WriteRequest) Marshal() []byte {
+	var b []byte
+	for _, ts := range req.Timeseries {
+		var tsb []byte
+		for _, l := range ts.Labels {
+			var lb []byte
+			lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.Name)
+			lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.Value)
+			tsb = protowire.AppendTag(tsb, 1, protowire.BytesType)
+			tsb = protowire.AppendBytes(tsb, lb)
+		}
+		for _, s := range ts.Samples {
+			var sb []byte
+			sb = protowire.AppendTag(sb, 1, protowire.Fixed64Type)
+			sb = protowire.AppendFixed64(sb, math.Float64bits(s.Value))
+			sb = protowire.AppendTag(sb, 2, protowire.VarintType)
+			sb = protowire.AppendVarint(sb, uint64(s.Timestamp))
+			tsb = protowire.AppendTag(tsb, 2, protowire.BytesType)
+			tsb = protowire.AppendBytes(tsb, sb)
+		}
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, tsb)
+	}
+	for _, md := range req.Metadata {
+		var mb []byte
+		mb = protowire.AppendTag(mb, 1, protowire.VarintType)
+		mb = protowire.AppendVarint(mb, uint64(md.Type))
+		mb = protowire.AppendTag(mb, 2, protowire.BytesType)
+		mb = protowire.AppendString(mb, md.FamilyName)
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, mb)
+	}
+	return b
+}
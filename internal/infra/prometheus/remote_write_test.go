@@ -0,0 +1,148 @@
+package prometheus
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func series(name string, labels []Label, samples ...Sample) *TimeSeries {
+	return &TimeSeries{Labels: append([]Label{{Name: "__name__", Value: name}}, labels...), Samples: samples}
+}
+
+func TestDecodeWriteRequest(t *testing.T) {
+	req := &WriteRequest{
+		Timeseries: []*TimeSeries{
+			series("up", []Label{{Name: "job", Value: "api"}}, Sample{Value: 1, Timestamp: 1000}, Sample{Value: 0, Timestamp: 2000}),
+			series("http_requests_total", nil, Sample{Value: 10.5, Timestamp: -1}),
+		},
+		Metadata: []*MetricMetadata{{FamilyName: "up", Type: MetadataGauge}},
+	}
+	data := req.Marshal()
+
+	// unknown fields are skipped: reserved field 2 and an exemplar (timeseries field 3)
+	data = protowire.AppendTag(data, 2, protowire.VarintType)
+	data = protowire.AppendVarint(data, 1)
+	var ts []byte
+	ts = protowire.AppendTag(ts, 3, protowire.BytesType)
+	ts = protowire.AppendBytes(ts, []byte{})
+	ts = protowire.AppendTag(ts, 4, protowire.BytesType)
+	ts = protowire.AppendBytes(ts, []byte{})
+	data = protowire.AppendTag(data, 1, protowire.BytesType)
+	data = protowire.AppendBytes(data, ts)
+
+	decoded, err := DecodeWriteRequest(data)
+	require.NoError(t, err)
+	require.Len(t, decoded.Timeseries, 3)
+	assert.Equal(t, req.Timeseries[0], decoded.Timeseries[0])
+	assert.Equal(t, req.Timeseries[1], decoded.Timeseries[1])
+	assert.Equal(t, 1, decoded.Timeseries[2].Histograms)
+	assert.Equal(t, req.Metadata, decoded.Metadata)
+
+	_, err = DecodeWriteRequest([]byte{0x0a, 0x05, 0x01})
+	assert.Error(t, err)
+}
+
+func TestConvert(t *testing.T) {
+	converter := NewConverter(service.NewCumulativeTracker(time.Hour))
+	host := []Label{{Name: "host", Value: "a"}}
+
+	req := &WriteRequest{
+		Timeseries: []*TimeSeries{
+			// gauge by metadata, the latest sample wins
+			series("temperature", host, Sample{Value: 25, Timestamp: 2000}, Sample{Value: 20, Timestamp: 1000}),
+			// counter by metadata
+			series("requests", host, Sample{Value: 10, Timestamp: 1000}, Sample{Value: 15, Timestamp: 2000}),
+			// counter by name, no metadata
+			series("errors_total", nil, Sample{Value: 2, Timestamp: 1000}),
+			// gauge by default
+			series("queue", nil, Sample{Value: 3, Timestamp: 1000}),
+			// stale marker only
+			series("gone", nil, Sample{Value: math.Float64frombits(staleNaN), Timestamp: 1000}),
+			series("latency_bucket", []Label{{Name: "le", Value: "0.5"}}, Sample{Value: 1, Timestamp: 1000}),
+			series("latency_sum", nil, Sample{Value: 1, Timestamp: 1000}),
+			series("rpc", []Label{{Name: "quantile", Value: "0.9"}}, Sample{Value: 1, Timestamp: 1000}),
+			{Labels: host, Samples: []Sample{{Value: 1, Timestamp: 1000}}},
+			{Labels: []Label{{Name: "__name__", Value: "native"}}, Histograms: 1},
+		},
+		Metadata: []*MetricMetadata{
+			{FamilyName: "temperature", Type: MetadataGauge},
+			{FamilyName: "requests", Type: MetadataCounter},
+			{FamilyName: "latency", Type: MetadataHistogram},
+		},
+	}
+
+	metrics, unsupported := converter.Convert(req)
+
+	// первое значение счетчика - точка отсчета
+	temperature, delta, errorsDelta, queue := 25.0, int64(5), int64(0), 3.0
+	labels := model.Labels{"host": "a"}
+	assert.Equal(t, []*model.MetricsV2{
+		{ID: "temperature", MType: model.GaugeType, Labels: labels, Value: &temperature},
+		{ID: "requests", MType: model.CounterType, Labels: labels, Delta: &delta},
+		{ID: "errors_total", MType: model.CounterType, Delta: &errorsDelta},
+		{ID: "queue", MType: model.GaugeType, Value: &queue},
+	}, metrics)
+
+	assert.Equal(t, []*model.UnsupportedSeries{
+		{Series: `latency_bucket{le="0.5"}`, Reason: "histograms are not supported"},
+		{Series: "latency_sum", Reason: "histograms are not supported"},
+		{Series: `rpc{quantile="0.9"}`, Reason: "summaries are not supported"},
+		{Series: `{host="a"}`, Reason: "series has no __name__ label"},
+		{Series: "native", Reason: "native histograms are not supported"},
+	}, unsupported)
+
+	// the next request contains cumulative value, only the increase is returned
+	metrics, _ = converter.Convert(&WriteRequest{
+		Timeseries: []*TimeSeries{series("requests", host, Sample{Value: 18, Timestamp: 3000})},
+		Metadata:   []*MetricMetadata{{FamilyName: "requests", Type: MetadataCounter}},
+	})
+	require.Len(t, metrics, 1)
+	assert.Equal(t, int64(3), *metrics[0].Delta)
+
+	// приращение, которое не удалось сохранить, возвращается повторным запросом
+	retry := &WriteRequest{
+		Timeseries: []*TimeSeries{series("requests", host, Sample{Value: 20, Timestamp: 4000})},
+		Metadata:   []*MetricMetadata{{FamilyName: "requests", Type: MetadataCounter}},
+	}
+	metrics, _ = converter.Convert(retry)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, int64(2), *metrics[0].Delta)
+	converter.Rollback(metrics)
+	metrics, _ = converter.Convert(retry)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, int64(2), *metrics[0].Delta)
+
+	// NaN и бесконечность не сохраняются и не ломают последующие значения
+	nan := &WriteRequest{
+		Timeseries: []*TimeSeries{
+			series("requests", host, Sample{Value: math.NaN(), Timestamp: 5000}, Sample{Value: 21, Timestamp: 6000}),
+			series("temperature", host, Sample{Value: math.Inf(1), Timestamp: 5000}),
+		},
+		Metadata: []*MetricMetadata{
+			{FamilyName: "temperature", Type: MetadataGauge},
+			{FamilyName: "requests", Type: MetadataCounter},
+		},
+	}
+	metrics, unsupported = converter.Convert(nan)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, int64(1), *metrics[0].Delta)
+	assert.Equal(t, []*model.UnsupportedSeries{
+		{Series: `requests{host="a"}`, Reason: "NaN and infinite values are not supported"},
+		{Series: `temperature{host="a"}`, Reason: "NaN and infinite values are not supported"},
+	}, unsupported)
+
+	metrics, _ = converter.Convert(&WriteRequest{
+		Timeseries: []*TimeSeries{series("requests", host, Sample{Value: 25, Timestamp: 7000})},
+		Metadata:   []*MetricMetadata{{FamilyName: "requests", Type: MetadataCounter}},
+	})
+	require.Len(t, metrics, 1)
+	assert.Equal(t, int64(4), *metrics[0].Delta)
+}
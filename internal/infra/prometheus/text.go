@@ -0,0 +1,245 @@
+// Package prometheus renders metrics in the Prometheus text exposition format (version 0.0.4).
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"metrics/internal/core/model"
+	"metrics/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// ContentType of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// family is metrics with the same name.
+type family struct {
+	name   string
+	mType  model.MetricType
+	series map[string]*textSeries // by sanitized labels
+}
+
+// textSeries is a metric with sanitized labels.
+type textSeries struct {
+	labels model.Labels
+	metric *model.MetricResponse
+}
+
+// WriteText writes metrics grouped by name with `# TYPE` lines. Names and labels are sanitized.
+// If sanitized names of metrics with different types collide, including names of histogram samples
+// (_bucket, _sum and _count), or sanitized labels of metrics collide, the metric which comes later is skipped.
+func WriteText(w io.Writer, metrics []*model.MetricResponse) error {
+	families := make(map[string]*family)
+	owners := make(map[string]string) // sample name to the family name
+	for _, m := range metrics {
+		name := SanitizeName(m.Name)
+		f, ok := families[name]
+		if !ok {
+			if owner, taken := familyOwner(owners, name, m.Type); taken {
+				logger.Log.Warn(
+					"Metric name collides with samples of another metric, skip it",
+					zap.String("name", m.Name),
+					zap.String("family", owner),
+				)
+				continue
+			}
+			f = &family{name: name, mType: m.Type, series: make(map[string]*textSeries)}
+			families[name] = f
+			for _, sample := range sampleNames(name, m.Type) {
+				owners[sample] = name
+			}
+		}
+		if f.mType != m.Type {
+			logger.Log.Warn(
+				"Metric name collides with a metric of another type, skip it",
+				zap.String("name", m.Name),
+				zap.String("type", m.Type.String()),
+			)
+			continue
+		}
+
+		labels := sanitizeLabels(m.Labels)
+		if m.Type == model.HistogramType {
+			delete(labels, "le")
+		}
+		key := labels.String()
+		if _, ok := f.series[key]; ok {
+			logger.Log.Warn(
+				"Metric labels collide with another metric after sanitizing, skip it",
+				zap.String("name", m.Name),
+				zap.String("labels", m.Labels.String()),
+			)
+			continue
+		}
+		f.series[key] = &textSeries{labels: labels, metric: m}
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		if err := writeFamily(bw, families[name]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// sampleNames returns names of samples written for the family.
+func sampleNames(name string, mType model.MetricType) []string {
+	if mType == model.HistogramType {
+		return []string{name, name + "_bucket", name + "_sum", name + "_count"}
+	}
+	return []string{name}
+}
+
+// familyOwner returns the family which already writes samples with names of the new family.
+func familyOwner(owners map[string]string, name string, mType model.MetricType) (string, bool) {
+	for _, sample := range sampleNames(name, mType) {
+		if owner, ok := owners[sample]; ok {
+			return owner, true
+		}
+	}
+	return "", false
+}
+
+func writeFamily(w *bufio.Writer, f *family) error {
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if _, err := fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.mType); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		m, labels := f.series[key].metric, f.series[key].labels
+		switch m.Type {
+		case model.GaugeType, model.CounterType:
+			value, err := strconv.ParseFloat(m.Value, 64)
+			if err != nil {
+				return fmt.Errorf("metric %s has invalid value %q: %w", m.Name, m.Value, err)
+			}
+			writeSample(w, f.name, labels, "", "", value)
+		case model.HistogramType:
+			if m.Histogram == nil {
+				return fmt.Errorf("histogram %s has no buckets", m.Name)
+			}
+			var cumulative int64
+			for i, c := range m.Histogram.Counts {
+				cumulative += c
+				le := math.Inf(1)
+				if i < len(m.Histogram.Buckets) {
+					le = m.Histogram.Buckets[i]
+				}
+				writeSample(w, f.name+"_bucket", labels, "le", formatFloat(le), float64(cumulative))
+			}
+			writeSample(w, f.name+"_sum", labels, "", "", m.Histogram.Sum)
+			writeSample(w, f.name+"_count", labels, "", "", float64(cumulative))
+		default:
+			return fmt.Errorf("unsupported metric type: %s", m.Type)
+		}
+	}
+	return nil
+}
+
+// writeSample writes a sample line. extraName label is added after the metric labels if it is not empty.
+func writeSample(w *bufio.Writer, name string, labels model.Labels, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, labelName := range labels.Names() {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, labelName, labels[labelName])
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func writeLabel(w *bufio.Writer, name, value string) {
+	w.WriteString(name)
+	w.WriteString(`="`)
+	w.WriteString(labelValueEscaper.Replace(value))
+	w.WriteByte('"')
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+func sanitizeLabels(labels model.Labels) model.Labels {
+	res := make(model.Labels, len(labels))
+	for name, value := range labels {
+		res[SanitizeLabelName(name)] = value
+	}
+	return res
+}
+
+// SanitizeName replaces characters which are not allowed in a metric name by underscores.
+func SanitizeName(name string) string {
+	return sanitize(name, true)
+}
+
+// SanitizeLabelName replaces characters which are not allowed in a label name by underscores.
+func SanitizeLabelName(name string) string {
+	return sanitize(name, false)
+}
+
+// sanitize makes a valid name: [a-zA-Z_:][a-zA-Z0-9_:]* for metrics and [a-zA-Z_][a-zA-Z0-9_]* for labels.
+func sanitize(name string, allowColon bool) string {
+	if name == "" {
+		return "_"
+	}
+	var sb strings.Builder
+	for i, r := range name {
+		valid := r == '_' ||
+			(r >= 'a' && r <= 'z') ||
+			(r >= 'A' && r <= 'Z') ||
+			(r == ':' && allowColon) ||
+			(r >= '0' && r <= '9' && i > 0)
+		switch {
+		case valid:
+			sb.WriteRune(r)
+		case i == 0 && r >= '0' && r <= '9':
+			sb.WriteByte('_')
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,139 @@
+package prometheus
+
+import (
+	"bytes"
+	"testing"
+
+	"metrics/internal/core/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteText(t *testing.T) {
+	metrics := []*model.MetricResponse{
+		{Name: "PollCount", Type: model.CounterType, Value: "5", Labels: model.Labels{"host": "b"}},
+		{Name: "PollCount", Type: model.CounterType, Value: "3", Labels: model.Labels{"host": "a"}},
+		{Name: "Heap.Alloc", Type: model.GaugeType, Value: "1.5"},
+		{Name: "label", Type: model.GaugeType, Value: "1", Labels: model.Labels{"1-path": "a\"b\\c\nd"}},
+		{
+			Name:  "latency",
+			Type:  model.HistogramType,
+			Value: "count=3 sum=2.6 buckets=[0.5:1 1:1 +Inf:1]",
+			Histogram: &model.HistogramValue{
+				Buckets: []float64{0.5, 1},
+				Counts:  []int64{1, 1, 1},
+				Sum:     2.6,
+			},
+			Labels: model.Labels{"host": "a"},
+		},
+		{Name: "PollCount", Type: model.GaugeType, Value: "1"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteText(&buf, metrics))
+	assert.Equal(t, `# TYPE Heap_Alloc gauge
+Heap_Alloc 1.5
+# TYPE PollCount counter
+PollCount{host="a"} 3
+PollCount{host="b"} 5
+# TYPE label gauge
+label{_1_path="a\"b\\c\nd"} 1
+# TYPE latency histogram
+latency_bucket{host="a",le="0.5"} 1
+latency_bucket{host="a",le="1"} 2
+latency_bucket{host="a",le="+Inf"} 3
+latency_sum{host="a"} 2.6
+latency_count{host="a"} 3
+`, buf.String())
+}
+
+func TestWriteTextCollisions(t *testing.T) {
+	histogram := func(name string) *model.MetricResponse {
+		return &model.MetricResponse{
+			Name:      name,
+			Type:      model.HistogramType,
+			Histogram: &model.HistogramValue{Counts: []int64{1}, Sum: 1},
+		}
+	}
+	tests := []struct {
+		name    string
+		metrics []*model.MetricResponse
+		want    string
+	}{
+		{
+			name: "same type names",
+			metrics: []*model.MetricResponse{
+				{Name: "Heap.Alloc", Type: model.GaugeType, Value: "1"},
+				{Name: "Heap_Alloc", Type: model.GaugeType, Value: "2"},
+			},
+			want: "# TYPE Heap_Alloc gauge\nHeap_Alloc 1\n",
+		},
+		{
+			name: "labels",
+			metrics: []*model.MetricResponse{
+				{Name: "Alloc", Type: model.GaugeType, Value: "1", Labels: model.Labels{"host.name": "a"}},
+				{Name: "Alloc", Type: model.GaugeType, Value: "2", Labels: model.Labels{"host_name": "a"}},
+			},
+			want: "# TYPE Alloc gauge\nAlloc{host_name=\"a\"} 1\n",
+		},
+		{
+			name: "gauge before histogram",
+			metrics: []*model.MetricResponse{
+				{Name: "latency_count", Type: model.GaugeType, Value: "5"},
+				histogram("latency"),
+			},
+			want: "# TYPE latency_count gauge\nlatency_count 5\n",
+		},
+		{
+			name: "histogram before gauge",
+			metrics: []*model.MetricResponse{
+				histogram("latency"),
+				{Name: "latency_sum", Type: model.GaugeType, Value: "5"},
+			},
+			want: `# TYPE latency histogram
+latency_bucket{le="+Inf"} 1
+latency_sum 1
+latency_count 1
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, WriteText(&buf, tt.metrics))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestWriteTextInvalidValue(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteText(&buf, []*model.MetricResponse{{Name: "a", Type: model.GaugeType, Value: "abc"}})
+	assert.Error(t, err)
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name  string
+		label bool
+		want  string
+	}{
+		{name: "HeapAlloc", want: "HeapAlloc"},
+		{name: "http:requests", want: "http:requests"},
+		{name: "http:requests", label: true, want: "http_requests"},
+		{name: "cpu.usage-1", want: "cpu_usage_1"},
+		{name: "1st", want: "_1st"},
+		{name: "", want: "_"},
+		{name: "память", want: "______"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.label {
+				assert.Equal(t, tt.want, SanitizeLabelName(tt.name))
+				return
+			}
+			assert.Equal(t, tt.want, SanitizeName(tt.name))
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package statsd
+
+import (
+	"math"
+
+	"metrics/internal/core/model"
+)
+
+type counter struct {
+	labels model.Labels
+	name   string
+	value  float64
+}
+
+type gauge struct {
+	labels  model.Labels
+	name    string
+	value   float64
+	changed bool
+}
+
+// Aggregator accumulates lines between flushes, it is not safe for concurrent use.
+//   - counters are summed, the value is divided by the sample rate. The fractional part is kept for the next flush;
+//   - gauges keep the last value between flushes, so deltas are applied to it like StatsD does.
+//     Only changed gauges are flushed;
+//   - timers and histograms are collected to histograms with model.DefaultHistogramBuckets.
+type Aggregator struct {
+	counters   map[string]*counter
+	gauges     map[string]*gauge
+	histograms map[string]*model.Histogram
+}
+
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		counters:   make(map[string]*counter),
+		gauges:     make(map[string]*gauge),
+		histograms: make(map[string]*model.Histogram),
+	}
+}
+
+func (a *Aggregator) Add(line *Line) {
+	key := model.MetricKey(line.Name, line.Labels)
+	switch line.Type {
+	case TypeCounter:
+		c, ok := a.counters[key]
+		if !ok {
+			c = &counter{name: line.Name, labels: line.Labels}
+			a.counters[key] = c
+		}
+		c.value += line.Value / line.Rate
+	case TypeGauge:
+		g, ok := a.gauges[key]
+		if !ok {
+			g = &gauge{name: line.Name, labels: line.Labels}
+			a.gauges[key] = g
+		}
+		if line.Delta {
+			g.value += line.Value
+		} else {
+			g.value = line.Value
+		}
+		g.changed = true
+	case TypeTimer, TypeHistogram:
+		h, ok := a.histograms[key]
+		if !ok {
+			h = model.NewHistogram(line.Name, line.Labels, nil)
+			a.histograms[key] = h
+		}
+		value := line.Value
+		if line.Type == TypeTimer {
+			value /= 1000
+		}
+		weight := int(math.Max(1, math.Round(1/line.Rate)))
+		for i := 0; i < weight; i++ {
+			h.Observe(value)
+		}
+	}
+}
+
+// Flush returns metrics accumulated since the previous flush.
+func (a *Aggregator) Flush() []*model.MetricsV2 {
+	res := make([]*model.MetricsV2, 0, len(a.counters)+len(a.histograms))
+	for key, c := range a.counters {
+		delta := int64(math.Round(c.value))
+		c.value -= float64(delta)
+		if c.value == 0 {
+			delete(a.counters, key)
+		}
+		if delta != 0 {
+			res = append(res, &model.MetricsV2{ID: c.name, MType: model.CounterType, Labels: c.labels, Delta: &delta})
+		}
+	}
+	for _, g := range a.gauges {
+		if !g.changed {
+			continue
+		}
+		value := g.value
+		g.changed = false
+		res = append(res, &model.MetricsV2{ID: g.name, MType: model.GaugeType, Labels: g.labels, Value: &value})
+	}
+	for key, h := range a.histograms {
+		res = append(res, &model.MetricsV2{ID: h.Name, MType: model.HistogramType, Labels: h.Labels, Histogram: h.Payload()})
+		delete(a.histograms, key)
+	}
+	return res
+}
@@ -0,0 +1,145 @@
+package statsd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"metrics/internal/core/service"
+	"metrics/internal/logger"
+	"metrics/internal/selfmetrics"
+
+	"go.uber.org/zap"
+)
+
+// maxPacketSize is the max size of UDP payload.
+const maxPacketSize = 65535
+
+// Listener receives StatsD lines by UDP and writes aggregated metrics every flush interval.
+// Lines which could not be parsed are counted by statsd_parse_errors_total self metric instead of logging.
+type Listener struct {
+	conn          net.PacketConn
+	metricService *service.MetricService
+	registry      *selfmetrics.Registry
+	aggregator    *Aggregator
+	mux           *sync.Mutex
+	quit          chan bool
+	done          chan bool
+	interval      time.Duration
+}
+
+func Listen(
+	addr string,
+	interval time.Duration,
+	metricService *service.MetricService,
+	registry *selfmetrics.Registry,
+) (*Listener, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen %s: %w", addr, err)
+	}
+	return &Listener{
+		conn:          conn,
+		metricService: metricService,
+		registry:      registry,
+		aggregator:    NewAggregator(),
+		mux:           &sync.Mutex{},
+		quit:          make(chan bool),
+		done:          make(chan bool),
+		interval:      interval,
+	}, nil
+}
+
+// Addr returns the listener network address.
+func (l *Listener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// Run receives packets and flushes metrics until Close is called. Metrics received before Close are flushed.
+func (l *Listener) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer close(l.done)
+	logger.Log.Info("Run StatsD listener", zap.String("Addres", l.Addr().String()), zap.Duration("flush", l.interval))
+
+	go l.receive()
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.quit:
+			logger.Log.Info("Close StatsD listener cicle")
+			l.flush(context.Background())
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.flush(ctx)
+		}
+	}
+}
+
+// Close stops receiving packets and waits for the last flush.
+func (l *Listener) Close() {
+	close(l.quit)
+	if err := l.conn.Close(); err != nil {
+		logger.Log.Error("Closing StatsD listener error", zap.Error(err))
+	}
+	<-l.done
+}
+
+func (l *Listener) receive() {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, _, err := l.conn.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			logger.Log.Error("Reading StatsD packet error", zap.Error(err))
+			continue
+		}
+		l.handlePacket(string(buf[:n]))
+	}
+}
+
+func (l *Listener) handlePacket(packet string) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	for _, s := range strings.Split(packet, "\n") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		l.registry.Add("statsd_lines_total", nil, 1)
+		line, err := ParseLine(s)
+		if err != nil {
+			l.registry.Add("statsd_parse_errors_total", nil, 1)
+			continue
+		}
+		l.aggregator.Add(line)
+	}
+}
+
+// flush writes metrics in one batch. Metrics of the failed batch are dropped.
+func (l *Listener) flush(ctx context.Context) {
+	l.mux.Lock()
+	batch := l.aggregator.Flush()
+	l.mux.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+	if _, err := l.metricService.BatchUpsertMetricValue(ctx, batch); err != nil {
+		l.registry.Add("statsd_flush_errors_total", nil, 1)
+		logger.Log.Error("Writing StatsD metrics error", zap.Int("metrics", len(batch)), zap.Error(err))
+		return
+	}
+	logger.Log.Debug("StatsD metrics flushed", zap.Int("metrics", len(batch)))
+}
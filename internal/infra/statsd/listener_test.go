@@ -0,0 +1,81 @@
+package statsd
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"metrics/internal/core/config"
+	"metrics/internal/core/model"
+	"metrics/internal/core/service"
+	"metrics/internal/infra/store/memory"
+	"metrics/internal/selfmetrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListener(t *testing.T) {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	store, err := memory.NewStore(
+		ctx,
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+		},
+	)
+	require.NoError(t, err)
+	metricService := service.NewMetricService(store)
+	registry := selfmetrics.NewRegistry()
+
+	listener, err := Listen("127.0.0.1:0", time.Hour, metricService, registry)
+	require.NoError(t, err)
+	wg.Add(1)
+	go listener.Run(ctx, &wg)
+
+	conn, err := net.Dial("udp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("requests:1|c\nrequests:2|c\ntemperature:12.5|g\nbroken\n"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("requests:x|c"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		listener.mux.Lock()
+		defer listener.mux.Unlock()
+		return len(listener.aggregator.counters) == 1 && len(listener.aggregator.gauges) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return statsdCounter(registry, "statsd_lines_total") == "5"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "2", statsdCounter(registry, "statsd_parse_errors_total"))
+
+	// the last flush is done on close
+	listener.Close()
+
+	counter, err := metricService.GetMetric(ctx, &model.MetricsV2{ID: "requests", MType: model.CounterType})
+	require.NoError(t, err)
+	require.NotNil(t, counter)
+	assert.Equal(t, int64(3), *counter.Delta)
+
+	gauge, err := metricService.GetMetric(ctx, &model.MetricsV2{ID: "temperature", MType: model.GaugeType})
+	require.NoError(t, err)
+	require.NotNil(t, gauge)
+	assert.Equal(t, 12.5, *gauge.Value)
+}
+
+func statsdCounter(registry *selfmetrics.Registry, name string) string {
+	for _, m := range registry.Metrics() {
+		if m.Name == selfmetrics.Namespace+"_"+name {
+			return m.Value
+		}
+	}
+	return ""
+}
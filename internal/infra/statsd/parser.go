@@ -0,0 +1,91 @@
+// Package statsd implements StatsD UDP listener. Received lines are aggregated in memory
+// and written to the store every flush interval.
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"metrics/internal/core/model"
+)
+
+// Types of StatsD metrics. Timers and histograms are stored as histograms, timer values are converted to seconds.
+const (
+	TypeCounter   = "c"
+	TypeGauge     = "g"
+	TypeTimer     = "ms"
+	TypeHistogram = "h"
+)
+
+// Line is a parsed StatsD line: `name:value|type[|@rate][|#tag:value,...]`.
+type Line struct {
+	Labels model.Labels // DogStatsD tags
+	Name   string
+	Type   string
+	Value  float64
+	Rate   float64 // sample rate, 1 if not set
+	Delta  bool    // gauge value with a sign changes the current value
+}
+
+// ParseLine parses a single StatsD line.
+func ParseLine(s string) (*Line, error) {
+	colon := strings.IndexByte(s, ':')
+	if colon <= 0 {
+		return nil, errors.New("metric name is required")
+	}
+	line := &Line{Name: s[:colon], Rate: 1}
+
+	parts := strings.Split(s[colon+1:], "|")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("metric %s: type is required", line.Name)
+	}
+	line.Type = parts[1]
+	switch line.Type {
+	case TypeCounter, TypeTimer, TypeHistogram:
+	case TypeGauge:
+		line.Delta = strings.HasPrefix(parts[0], "+") || strings.HasPrefix(parts[0], "-")
+	default:
+		return nil, fmt.Errorf("metric %s: unsupported type: %q", line.Name, line.Type)
+	}
+
+	value, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("metric %s: invalid value: %q", line.Name, parts[0])
+	}
+	line.Value = value
+
+	for _, part := range parts[2:] {
+		switch {
+		case strings.HasPrefix(part, "@"):
+			rate, err := strconv.ParseFloat(part[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return nil, fmt.Errorf("metric %s: invalid sample rate: %q", line.Name, part)
+			}
+			line.Rate = rate
+		case strings.HasPrefix(part, "#"):
+			labels, err := parseTags(part[1:])
+			if err != nil {
+				return nil, fmt.Errorf("metric %s: %w", line.Name, err)
+			}
+			line.Labels = labels
+		default:
+			return nil, fmt.Errorf("metric %s: unknown field: %q", line.Name, part)
+		}
+	}
+	return line, nil
+}
+
+func parseTags(s string) (model.Labels, error) {
+	labels := model.Labels{}
+	for _, tag := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(tag, ":")
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("tag must be name:value, got %q", tag)
+		}
+		labels[name] = value
+	}
+	return labels, nil
+}
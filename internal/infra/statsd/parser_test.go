@@ -0,0 +1,128 @@
+package statsd
+
+import (
+	"testing"
+
+	"metrics/internal/core/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		want *Line
+		name string
+		line string
+	}{
+		{
+			name: "counter",
+			line: "requests:1|c",
+			want: &Line{Name: "requests", Type: TypeCounter, Value: 1, Rate: 1},
+		},
+		{
+			name: "counter with sample rate",
+			line: "requests:2|c|@0.1",
+			want: &Line{Name: "requests", Type: TypeCounter, Value: 2, Rate: 0.1},
+		},
+		{
+			name: "gauge",
+			line: "temperature:12.5|g",
+			want: &Line{Name: "temperature", Type: TypeGauge, Value: 12.5, Rate: 1},
+		},
+		{
+			name: "gauge increment",
+			line: "temperature:+3|g",
+			want: &Line{Name: "temperature", Type: TypeGauge, Value: 3, Rate: 1, Delta: true},
+		},
+		{
+			name: "gauge decrement",
+			line: "temperature:-3|g",
+			want: &Line{Name: "temperature", Type: TypeGauge, Value: -3, Rate: 1, Delta: true},
+		},
+		{
+			name: "timer with tags",
+			line: "latency:320|ms|@0.5|#host:a,env:prod",
+			want: &Line{
+				Name: "latency", Type: TypeTimer, Value: 320, Rate: 0.5,
+				Labels: model.Labels{"host": "a", "env": "prod"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := ParseLine(tt.line)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, line)
+		})
+	}
+}
+
+func TestParseLineErrors(t *testing.T) {
+	lines := []string{
+		"requests",
+		":1|c",
+		"requests:1",
+		"requests:abc|c",
+		"requests:NaN|g",
+		"requests:1|s",
+		"requests:1|c|@0",
+		"requests:1|c|@2",
+		"requests:1|c|#host",
+		"requests:1|c|unknown",
+	}
+	for _, line := range lines {
+		t.Run(line, func(t *testing.T) {
+			_, err := ParseLine(line)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	aggregator := NewAggregator()
+	for _, s := range []string{
+		"requests:1|c",
+		"requests:1|c|@0.5",
+		"requests:1|c|#host:a",
+		"rare:1|c|@0.4",
+		"temperature:10|g",
+		"temperature:+5|g",
+		"temperature:-2|g",
+		"latency:100|ms|@0.5",
+	} {
+		line, err := ParseLine(s)
+		require.NoError(t, err)
+		aggregator.Add(line)
+	}
+
+	metrics := make(map[string]*model.MetricsV2)
+	for _, m := range aggregator.Flush() {
+		metrics[model.MetricKey(m.ID, m.Labels)] = m
+	}
+	require.Len(t, metrics, 5)
+	assert.Equal(t, int64(3), *metrics["requests"].Delta)
+	assert.Equal(t, int64(1), *metrics[`requests{host="a"}`].Delta)
+	assert.Equal(t, int64(3), *metrics["rare"].Delta) // 2.5 is rounded, -0.5 is kept for the next flush
+	assert.Equal(t, 13.0, *metrics["temperature"].Value)
+	assert.Equal(t, model.HistogramType, metrics["latency"].MType)
+	assert.Equal(t, 0.2, metrics["latency"].Histogram.Sum)
+
+	// gauges keep the value for deltas, but are not flushed without changes
+	line, err := ParseLine("rare:1|c|@0.4")
+	require.NoError(t, err)
+	aggregator.Add(line)
+	line, err = ParseLine("temperature:+1|g")
+	require.NoError(t, err)
+	aggregator.Add(line)
+
+	metrics = make(map[string]*model.MetricsV2)
+	for _, m := range aggregator.Flush() {
+		metrics[model.MetricKey(m.ID, m.Labels)] = m
+	}
+	require.Len(t, metrics, 2)
+	assert.Equal(t, int64(2), *metrics["rare"].Delta)
+	assert.Equal(t, 14.0, *metrics["temperature"].Value)
+
+	assert.Empty(t, aggregator.Flush())
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -74,10 +76,19 @@ func (s *Store) Ping(ctx context.Context) error {
 }
 
 func (s *Store) BatchUpsertMetrics(ctx context.Context, metrics []*model.MetricsV2) ([]*model.MetricsV2, error) {
+	return s.IdempotentBatchUpsertMetrics(ctx, "", metrics)
+}
+
+// IdempotentBatchUpsertMetrics applies the batch once: the key is saved with the result in the same transaction,
+// so the batch with the applied key returns the saved result. Concurrent batches with the same key wait
+// for the first one. Keys are kept for model.IdempotencyKeyTTL, the empty key is not saved.
+func (s *Store) IdempotentBatchUpsertMetrics(
+	ctx context.Context, key string, metrics []*model.MetricsV2,
+) ([]*model.MetricsV2, error) {
 	results := []*model.MetricsV2{}
 	fun := func() error {
 		var err error
-		results, err = s.doBatchUpsertMetrics(ctx, metrics)
+		results, err = s.doBatchUpsertMetrics(ctx, key, metrics)
 		return err
 	}
 
@@ -85,15 +96,90 @@ func (s *Store) BatchUpsertMetrics(ctx context.Context, metrics []*model.Metrics
 	return results, err
 }
 
-func (s *Store) doBatchUpsertMetrics(ctx context.Context, metrics []*model.MetricsV2) ([]*model.MetricsV2, error) {
-	results := make([]*model.MetricsV2, 0, len(metrics))
+func (s *Store) doBatchUpsertMetrics(
+	ctx context.Context, key string, metrics []*model.MetricsV2,
+) ([]*model.MetricsV2, error) {
 	tx, err := s.db.Begin()
 	if err != nil {
 		return nil, fmt.Errorf("error starting transaction: %w", err)
 	}
+	if key != "" {
+		results, applied, err := s.lockBatchKeyTx(ctx, tx, key)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if applied {
+			logger.Log.Debug("Batch is already applied", zap.String("key", key))
+			tx.Rollback()
+			return results, nil
+		}
+	}
+
+	results, err := s.upsertMetricsTx(ctx, tx, metrics)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if key != "" {
+		data, err := json.Marshal(results)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("error encoding batch result: %w", err)
+		}
+		if _, err = tx.ExecContext(ctx, "UPDATE batch_key SET result=$1 WHERE key=$2", data, key); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("error saving batch result: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("batch transaction commit error: %w", err)
+	}
+	return results, nil
+}
+
+// lockBatchKeyTx inserts the batch key, the row is locked until the transaction ends.
+// If the key exists the batch is applied already and its result is returned.
+func (s *Store) lockBatchKeyTx(ctx context.Context, tx *sql.Tx, key string) ([]*model.MetricsV2, bool, error) {
+	_, err := tx.ExecContext(
+		ctx, "DELETE FROM batch_key WHERE created_at < $1", time.Now().Add(-model.IdempotencyKeyTTL),
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("error deleting expired batch keys: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO batch_key(key) VALUES($1) ON CONFLICT (key) DO NOTHING", key)
+	if err != nil {
+		return nil, false, fmt.Errorf("error saving batch key: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, fmt.Errorf("error getting RowsAffected for batch key: %w", err)
+	}
+	if count == 1 {
+		return nil, false, nil
+	}
+
+	var data []byte
+	if err = tx.QueryRowContext(ctx, "SELECT result FROM batch_key WHERE key=$1", key).Scan(&data); err != nil {
+		return nil, false, fmt.Errorf("error reading batch result: %w", err)
+	}
+	var results []*model.MetricsV2
+	if err = json.Unmarshal(data, &results); err != nil {
+		return nil, false, fmt.Errorf("error decoding batch result: %w", err)
+	}
+	return results, true, nil
+}
+
+func (s *Store) upsertMetricsTx(
+	ctx context.Context, tx *sql.Tx, metrics []*model.MetricsV2,
+) ([]*model.MetricsV2, error) {
+	results := make([]*model.MetricsV2, 0, len(metrics))
 	for _, m := range metrics {
 		logger.Log.Debug("BatchUpsertMetrics input",
 			zap.String("metric", m.ID),
+			zap.String("labels", m.Labels.String()),
 			zap.String("type", m.MType.String()),
 			zap.Float64p("value", m.Value),
 			zap.Int64p("delta", m.Delta),
@@ -101,44 +187,48 @@ func (s *Store) doBatchUpsertMetrics(ctx context.Context, metrics []*model.Metri
 		switch m.MType {
 		case model.GaugeType:
 			if m.Value == nil {
-				tx.Rollback()
 				return nil, fmt.Errorf("gauge Value clould not be nil: %v", m)
 			}
-			res := model.MetricsV2{ID: m.ID, MType: m.MType}
+			res := model.MetricsV2{ID: m.ID, Labels: m.Labels, MType: m.MType}
 			row := tx.QueryRowContext(
 				ctx,
-				`INSERT INTO gauge(id, value) values($1, $2) ON conflict(id) 
-				 DO UPDATE SET value = excluded.value
-				 RETURNING value`,
-				m.ID, m.Value,
+				`WITH upsert AS (
+				   INSERT INTO gauge(id, labels, value) values($1, $2, $3) ON conflict(id, labels)
+				   DO UPDATE SET value = excluded.value
+				   RETURNING id, labels, value
+				 )
+				 INSERT INTO sample(type, id, labels, value) SELECT 'gauge', id, labels, value FROM upsert
+				 RETURNING (SELECT value FROM upsert)`,
+				m.ID, m.Labels.String(), m.Value,
 			)
-			err = row.Scan(&res.Value)
+			err := row.Scan(&res.Value)
 			logger.Log.Debug("BatchUpsertMetrics returning",
 				zap.String("metric", res.ID),
 				zap.String("type", res.MType.String()),
 				zap.Float64p("value", res.Value),
 			)
 			if err != nil {
-				tx.Rollback()
 				return nil, fmt.Errorf("error upserting gauge: %w", err)
 			}
 			results = append(results, &res)
 		case model.CounterType:
 			if m.Delta == nil {
-				tx.Rollback()
 				return nil, fmt.Errorf("counter Delta clould not be nil: %v", m)
 			}
-			res := model.MetricsV2{ID: m.ID, MType: m.MType}
+			res := model.MetricsV2{ID: m.ID, Labels: m.Labels, MType: m.MType}
 			row := tx.QueryRowContext(
 				ctx,
-				`INSERT INTO counter(id, value) values($1, $2) ON conflict(id) 
-				 DO UPDATE SET value = counter.value + excluded.value
-				 RETURNING value`,
-				m.ID, m.Delta,
+				`WITH upsert AS (
+				   INSERT INTO counter(id, labels, value) values($1, $2, $3) ON conflict(id, labels)
+				   DO UPDATE SET value = counter.value + excluded.value
+				   RETURNING id, labels, value
+				 )
+				 INSERT INTO sample(type, id, labels, value) SELECT 'counter', id, labels, value FROM upsert
+				 RETURNING (SELECT value FROM upsert)`,
+				m.ID, m.Labels.String(), m.Delta,
 			)
-			err = row.Scan(&res.Delta)
+			err := row.Scan(&res.Delta)
 			if err != nil {
-				tx.Rollback()
 				return nil, fmt.Errorf("error upserting counter: %w", err)
 			}
 			logger.Log.Debug("BatchUpsertMetrics returning",
@@ -147,14 +237,16 @@ func (s *Store) doBatchUpsertMetrics(ctx context.Context, metrics []*model.Metri
 				zap.Int64p("delta", res.Delta),
 			)
 			results = append(results, &res)
+		case model.HistogramType:
+			res, err := s.upsertHistogramTx(ctx, tx, m)
+			if err != nil {
+				return nil, fmt.Errorf("error upserting histogram: %w", err)
+			}
+			results = append(results, res)
 		default:
-			tx.Rollback()
 			return nil, fmt.Errorf("unknown metric type: %s", m.MType.String())
 		}
 	}
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("batch transaction commit error: %w", err)
-	}
 	return results, nil
 }
 
@@ -162,13 +254,19 @@ func (s *Store) GetGauge(ctx context.Context, req *model.MetricsV2) (*model.Gaug
 	gauge := &model.Gauge{}
 
 	fun := func() error {
-		var err error
-		row := s.db.QueryRowContext(ctx, "SELECT id, value FROM gauge WHERE id=$1", req.ID)
-		err = row.Scan(&gauge.Name, &gauge.Value)
+		var labels string
+		row := s.db.QueryRowContext(
+			ctx, "SELECT id, labels, value FROM gauge WHERE id=$1 AND labels=$2", req.ID, req.Labels.String(),
+		)
+		err := row.Scan(&gauge.Name, &labels, &gauge.Value)
 		if errors.Is(err, sql.ErrNoRows) {
 			gauge = nil
 			return nil
 		}
+		if err != nil {
+			return err
+		}
+		gauge.Labels, err = model.ParseLabels(labels)
 		return err
 	}
 	err := s.retrier.Do(ctx, fun, recoverableErrors...)
@@ -182,8 +280,13 @@ func (s *Store) SetGauge(ctx context.Context, gauge *model.Gauge) error {
 	fun := func() error {
 		result, err := s.db.ExecContext(
 			ctx,
-			"INSERT INTO gauge(id, value) values($1, $2) ON conflict(id) DO UPDATE SET value = excluded.value",
-			gauge.Name, gauge.Value,
+			`WITH upsert AS (
+			   INSERT INTO gauge(id, labels, value) values($1, $2, $3) ON conflict(id, labels)
+			   DO UPDATE SET value = excluded.value
+			   RETURNING id, labels, value
+			 )
+			 INSERT INTO sample(type, id, labels, value) SELECT 'gauge', id, labels, value FROM upsert`,
+			gauge.Name, gauge.Labels.String(), gauge.Value,
 		)
 		if err != nil {
 			return fmt.Errorf("error ExecContext for gauge: %w", err)
@@ -220,7 +323,7 @@ func (s *Store) ListGauge(ctx context.Context) ([]*model.Gauge, error) {
 func (s *Store) doListGauge(ctx context.Context) ([]*model.Gauge, error) {
 	gauges := make([]*model.Gauge, 0, 10)
 
-	rows, err := s.db.QueryContext(ctx, "SELECT id, value FROM gauge")
+	rows, err := s.db.QueryContext(ctx, "SELECT id, labels, value FROM gauge")
 	if err != nil {
 		return nil, fmt.Errorf("error reading gauge: %w", err)
 	}
@@ -229,10 +332,15 @@ func (s *Store) doListGauge(ctx context.Context) ([]*model.Gauge, error) {
 
 	for rows.Next() {
 		var g model.Gauge
-		err = rows.Scan(&g.Name, &g.Value)
+		var labels string
+		err = rows.Scan(&g.Name, &labels, &g.Value)
 		if err != nil {
 			return nil, fmt.Errorf("error reading gauge row: %w", err)
 		}
+		g.Labels, err = model.ParseLabels(labels)
+		if err != nil {
+			return nil, fmt.Errorf("error reading gauge labels: %w", err)
+		}
 
 		gauges = append(gauges, &g)
 	}
@@ -249,13 +357,19 @@ func (s *Store) GetCounter(ctx context.Context, req *model.MetricsV2) (*model.Co
 	counter := &model.Counter{}
 
 	fun := func() error {
-		var err error
-		row := s.db.QueryRowContext(ctx, "SELECT id, value FROM counter WHERE id=$1", req.ID)
-		err = row.Scan(&counter.Name, &counter.Value)
+		var labels string
+		row := s.db.QueryRowContext(
+			ctx, "SELECT id, labels, value FROM counter WHERE id=$1 AND labels=$2", req.ID, req.Labels.String(),
+		)
+		err := row.Scan(&counter.Name, &labels, &counter.Value)
 		if errors.Is(err, sql.ErrNoRows) {
 			counter = nil
 			return nil
 		}
+		if err != nil {
+			return err
+		}
+		counter.Labels, err = model.ParseLabels(labels)
 		return err
 	}
 	err := s.retrier.Do(ctx, fun, recoverableErrors...)
@@ -269,8 +383,13 @@ func (s *Store) SetCounter(ctx context.Context, counter *model.Counter) error {
 	fun := func() error {
 		result, err := s.db.ExecContext(
 			ctx,
-			"INSERT INTO counter(id, value) values($1, $2) ON conflict(id) DO UPDATE SET value = excluded.value",
-			counter.Name, counter.Value,
+			`WITH upsert AS (
+			   INSERT INTO counter(id, labels, value) values($1, $2, $3) ON conflict(id, labels)
+			   DO UPDATE SET value = excluded.value
+			   RETURNING id, labels, value
+			 )
+			 INSERT INTO sample(type, id, labels, value) SELECT 'counter', id, labels, value FROM upsert`,
+			counter.Name, counter.Labels.String(), counter.Value,
 		)
 		if err != nil {
 			return fmt.Errorf("error ExecContext for counter: %w", err)
@@ -307,7 +426,7 @@ func (s *Store) ListCounter(ctx context.Context) ([]*model.Counter, error) {
 func (s *Store) doListCounter(ctx context.Context) ([]*model.Counter, error) {
 	counters := make([]*model.Counter, 0, 10)
 
-	rows, err := s.db.QueryContext(ctx, "SELECT id, value FROM counter")
+	rows, err := s.db.QueryContext(ctx, "SELECT id, labels, value FROM counter")
 	if err != nil {
 		return nil, fmt.Errorf("error reading counters: %w", err)
 	}
@@ -315,10 +434,15 @@ func (s *Store) doListCounter(ctx context.Context) ([]*model.Counter, error) {
 
 	for rows.Next() {
 		var c model.Counter
-		err = rows.Scan(&c.Name, &c.Value)
+		var labels string
+		err = rows.Scan(&c.Name, &labels, &c.Value)
 		if err != nil {
 			return nil, fmt.Errorf("error reading counter row: %w", err)
 		}
+		c.Labels, err = model.ParseLabels(labels)
+		if err != nil {
+			return nil, fmt.Errorf("error reading counter labels: %w", err)
+		}
 
 		counters = append(counters, &c)
 	}
@@ -330,3 +454,387 @@ func (s *Store) doListCounter(ctx context.Context) ([]*model.Counter, error) {
 
 	return counters, nil
 }
+
+// upsertHistogramTx merges request into the stored histogram inside the transaction.
+// The row is locked until the transaction ends, so concurrent batches do not lose bucket counts.
+func (s *Store) upsertHistogramTx(ctx context.Context, tx *sql.Tx, m *model.MetricsV2) (*model.MetricsV2, error) {
+	row := tx.QueryRowContext(
+		ctx,
+		"SELECT id, labels, buckets, counts, sum FROM histogram WHERE id=$1 AND labels=$2 FOR UPDATE",
+		m.ID, m.Labels.String(),
+	)
+	histogram, err := scanHistogram(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		histogram = model.NewHistogramFromRequest(m)
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading histogram: %w", err)
+	}
+
+	if err = histogram.Apply(m); err != nil {
+		return nil, fmt.Errorf("failed to update histogram (%s): %w", m.ID, err)
+	}
+
+	buckets, counts, err := marshalHistogram(histogram)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		`WITH upsert AS (
+		   INSERT INTO histogram(id, labels, buckets, counts, sum) values($1, $2, $3, $4, $5) ON conflict(id, labels)
+		   DO UPDATE SET buckets = excluded.buckets, counts = excluded.counts, sum = excluded.sum
+		   RETURNING id, labels
+		 )
+		 INSERT INTO sample(type, id, labels, value) SELECT 'histogram', id, labels, $6::DOUBLE PRECISION FROM upsert`,
+		histogram.Name, histogram.Labels.String(), buckets, counts, histogram.Sum, float64(histogram.Count()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error writing histogram: %w", err)
+	}
+	logger.Log.Debug("BatchUpsertMetrics returning",
+		zap.String("metric", histogram.Name),
+		zap.String("type", m.MType.String()),
+		zap.Int64("count", histogram.Count()),
+	)
+	return &model.MetricsV2{ID: m.ID, Labels: histogram.Labels, MType: m.MType, Histogram: histogram.Payload()}, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanHistogram(row rowScanner) (*model.Histogram, error) {
+	var labels string
+	var buckets, counts []byte
+	histogram := &model.Histogram{}
+	if err := row.Scan(&histogram.Name, &labels, &buckets, &counts, &histogram.Sum); err != nil {
+		return nil, err
+	}
+	var err error
+	histogram.Labels, err = model.ParseLabels(labels)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding histogram labels: %w", err)
+	}
+	if err := json.Unmarshal(buckets, &histogram.Buckets); err != nil {
+		return nil, fmt.Errorf("error decoding histogram buckets: %w", err)
+	}
+	if err := json.Unmarshal(counts, &histogram.Counts); err != nil {
+		return nil, fmt.Errorf("error decoding histogram counts: %w", err)
+	}
+	return histogram, nil
+}
+
+func marshalHistogram(histogram *model.Histogram) (buckets []byte, counts []byte, err error) {
+	buckets, err = json.Marshal(histogram.Buckets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error encoding histogram buckets: %w", err)
+	}
+	counts, err = json.Marshal(histogram.Counts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error encoding histogram counts: %w", err)
+	}
+	return buckets, counts, nil
+}
+
+func (s *Store) GetHistogram(ctx context.Context, req *model.MetricsV2) (*model.Histogram, error) {
+	var histogram *model.Histogram
+
+	fun := func() error {
+		var err error
+		row := s.db.QueryRowContext(
+			ctx,
+			"SELECT id, labels, buckets, counts, sum FROM histogram WHERE id=$1 AND labels=$2",
+			req.ID, req.Labels.String(),
+		)
+		histogram, err = scanHistogram(row)
+		if errors.Is(err, sql.ErrNoRows) {
+			histogram = nil
+			return nil
+		}
+		return err
+	}
+	err := s.retrier.Do(ctx, fun, recoverableErrors...)
+	if err != nil {
+		return nil, fmt.Errorf("error reading histogram: %w", err)
+	}
+	return histogram, nil
+}
+
+func (s *Store) SetHistogram(ctx context.Context, histogram *model.Histogram) error {
+	buckets, counts, err := marshalHistogram(histogram)
+	if err != nil {
+		return err
+	}
+
+	fun := func() error {
+		result, err := s.db.ExecContext(
+			ctx,
+			`WITH upsert AS (
+			   INSERT INTO histogram(id, labels, buckets, counts, sum) values($1, $2, $3, $4, $5) ON conflict(id, labels)
+			   DO UPDATE SET buckets = excluded.buckets, counts = excluded.counts, sum = excluded.sum
+			   RETURNING id, labels
+			 )
+			 INSERT INTO sample(type, id, labels, value) SELECT 'histogram', id, labels, $6::DOUBLE PRECISION FROM upsert`,
+			histogram.Name, histogram.Labels.String(), buckets, counts, histogram.Sum, float64(histogram.Count()),
+		)
+		if err != nil {
+			return fmt.Errorf("error ExecContext for histogram: %w", err)
+		}
+
+		count, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error getting RowsAffected for histogram: %w", err)
+		}
+		if count != 1 {
+			return fmt.Errorf("incorrect rows affected: %d", count)
+		}
+		return nil
+	}
+	err = s.retrier.Do(ctx, fun, recoverableErrors...)
+	if err != nil {
+		return fmt.Errorf("error writing histogram: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) ListHistogram(ctx context.Context) ([]*model.Histogram, error) {
+	results := []*model.Histogram{}
+	fun := func() error {
+		var err error
+		results, err = s.doListHistogram(ctx)
+		return err
+	}
+
+	err := s.retrier.Do(ctx, fun, recoverableErrors...)
+	return results, err
+}
+
+func (s *Store) doListHistogram(ctx context.Context) ([]*model.Histogram, error) {
+	histograms := make([]*model.Histogram, 0, 10)
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, labels, buckets, counts, sum FROM histogram")
+	if err != nil {
+		return nil, fmt.Errorf("error reading histograms: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		h, err := scanHistogram(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading histogram row: %w", err)
+		}
+
+		histograms = append(histograms, h)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error scaning histograms: %w", err)
+	}
+
+	return histograms, nil
+}
+
+// ListSamples returns values of the metric written between from and to ordered by time.
+func (s *Store) ListSamples(ctx context.Context, req *model.MetricsV2, from, to time.Time) ([]*model.Sample, error) {
+	results := []*model.Sample{}
+	fun := func() error {
+		var err error
+		results, err = s.doListSamples(ctx, req, from, to)
+		return err
+	}
+
+	err := s.retrier.Do(ctx, fun, recoverableErrors...)
+	return results, err
+}
+
+func (s *Store) doListSamples(ctx context.Context, req *model.MetricsV2, from, to time.Time) ([]*model.Sample, error) {
+	samples := make([]*model.Sample, 0, 10)
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT ts, value FROM sample
+		 WHERE type=$1 AND id=$2 AND labels=$3 AND ts >= $4 AND ts <= $5
+		 ORDER BY ts`,
+		req.MType.String(), req.ID, req.Labels.String(), from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error reading samples: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sample model.Sample
+		err = rows.Scan(&sample.Timestamp, &sample.Value)
+		if err != nil {
+			return nil, fmt.Errorf("error reading sample row: %w", err)
+		}
+
+		samples = append(samples, &sample)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error scaning samples: %w", err)
+	}
+
+	return samples, nil
+}
+
+// Подзапросы по таблицам метрик с общим набором колонок для UNION ALL.
+var metricTableQueries = map[model.MetricType]string{
+	model.GaugeType: `SELECT 'gauge'::TEXT AS type, id, labels, value, NULL::BIGINT AS delta,
+	   NULL::JSONB AS buckets, NULL::JSONB AS counts, NULL::DOUBLE PRECISION AS sum FROM gauge`,
+	model.CounterType: `SELECT 'counter'::TEXT AS type, id, labels, NULL::DOUBLE PRECISION AS value, value AS delta,
+	   NULL::JSONB AS buckets, NULL::JSONB AS counts, NULL::DOUBLE PRECISION AS sum FROM counter`,
+	model.HistogramType: `SELECT 'histogram'::TEXT AS type, id, labels, NULL::DOUBLE PRECISION AS value, NULL::BIGINT AS delta,
+	   buckets, counts, sum FROM histogram`,
+}
+
+// QueryMetrics returns metrics satisfying the query filters ordered by the query sort key.
+// Filtering, ordering and the limit are done by the database.
+func (s *Store) QueryMetrics(ctx context.Context, q *model.MetricsQuery) ([]*model.MetricsV2, error) {
+	results := []*model.MetricsV2{}
+	fun := func() error {
+		var err error
+		results, err = s.doQueryMetrics(ctx, q)
+		return err
+	}
+
+	err := s.retrier.Do(ctx, fun, recoverableErrors...)
+	return results, err
+}
+
+func (s *Store) doQueryMetrics(ctx context.Context, q *model.MetricsQuery) ([]*model.MetricsV2, error) {
+	query, args := buildMetricsQuery(q)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying metrics: %w", err)
+	}
+	defer rows.Close()
+
+	metrics := make([]*model.MetricsV2, 0, q.Limit)
+	for rows.Next() {
+		m, err := scanMetric(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading metric row: %w", err)
+		}
+		metrics = append(metrics, m)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error scaning metrics: %w", err)
+	}
+
+	return metrics, nil
+}
+
+// buildMetricsQuery returns SQL of the metrics query and its arguments.
+// The cursor is compared as a row value, so the next page is read by the index like the first one.
+func buildMetricsQuery(q *model.MetricsQuery) (string, []any) {
+	tables := make([]string, 0, len(metricTableQueries))
+	for _, mType := range []model.MetricType{model.GaugeType, model.CounterType, model.HistogramType} {
+		if q.MType == "" || q.MType == mType {
+			tables = append(tables, metricTableQueries[mType])
+		}
+	}
+
+	columns := []string{"id", "labels", "type"}
+	if q.Sort == model.SortByType {
+		columns = []string{"type", "id", "labels"}
+	}
+	order, op := " ASC", ">"
+	if q.Desc {
+		order, op = " DESC", "<"
+	}
+
+	var where []string
+	var args []any
+	if q.Match != "" {
+		args = append(args, globToLike(q.Match))
+		where = append(where, fmt.Sprintf("id LIKE $%d", len(args)))
+	}
+	if q.After != nil {
+		values := map[string]any{"id": q.After.ID, "labels": q.After.Labels, "type": q.After.MType.String()}
+		params := make([]string, 0, len(columns))
+		for _, column := range columns {
+			args = append(args, values[column])
+			params = append(params, fmt.Sprintf("$%d", len(args)))
+		}
+		where = append(
+			where,
+			fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), op, strings.Join(params, ", ")),
+		)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT type, id, labels, value, delta, buckets, counts, sum FROM (")
+	sb.WriteString(strings.Join(tables, " UNION ALL "))
+	sb.WriteString(") AS metrics")
+	if len(where) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(where, " AND "))
+	}
+	sb.WriteString(" ORDER BY ")
+	sb.WriteString(strings.Join(columns, order+", ") + order)
+	if q.Limit > 0 {
+		args = append(args, q.Limit)
+		sb.WriteString(fmt.Sprintf(" LIMIT $%d", len(args)))
+	}
+	return sb.String(), args
+}
+
+// globToLike converts the name glob to LIKE pattern: * to %, ? to _, LIKE special characters are escaped.
+func globToLike(glob string) string {
+	var sb strings.Builder
+	for _, r := range glob {
+		switch r {
+		case '*':
+			sb.WriteByte('%')
+		case '?':
+			sb.WriteByte('_')
+		case '%', '_', '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func scanMetric(row rowScanner) (*model.MetricsV2, error) {
+	var mType, labels string
+	var value, sum sql.NullFloat64
+	var delta sql.NullInt64
+	var buckets, counts []byte
+	m := &model.MetricsV2{}
+	if err := row.Scan(&mType, &m.ID, &labels, &value, &delta, &buckets, &counts, &sum); err != nil {
+		return nil, err
+	}
+	var err error
+	m.Labels, err = model.ParseLabels(labels)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding metric labels: %w", err)
+	}
+
+	m.MType = model.MetricType(mType)
+	switch m.MType {
+	case model.GaugeType:
+		m.Value = &value.Float64
+	case model.CounterType:
+		m.Delta = &delta.Int64
+	case model.HistogramType:
+		m.Histogram = &model.HistogramValue{Sum: sum.Float64}
+		if err := json.Unmarshal(buckets, &m.Histogram.Buckets); err != nil {
+			return nil, fmt.Errorf("error decoding histogram buckets: %w", err)
+		}
+		if err := json.Unmarshal(counts, &m.Histogram.Counts); err != nil {
+			return nil, fmt.Errorf("error decoding histogram counts: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown metric type: %s", mType)
+	}
+	return m, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"metrics/internal/core/model"
 
@@ -112,22 +113,22 @@ func TestBatchUpsertMetrics(t *testing.T) {
 		switch m.MType {
 		case model.GaugeType:
 			mock.ExpectQuery(
-				`INSERT INTO gauge\(id, value\) values\(\$1, \$2\) ON conflict\(id\) 
-					DO UPDATE SET value \= excluded.value
-					RETURNING value`,
+				`INSERT INTO gauge\(id, labels, value\) values\(\$1, \$2, \$3\) ON conflict\(id, labels\)
+					DO UPDATE SET value \= excluded.value RETURNING id, labels, value \)
+					INSERT INTO sample\(type, id, labels, value\) SELECT 'gauge', id, labels, value FROM upsert`,
 			).
-				WithArgs(m.ID, m.Value).
+				WithArgs(m.ID, "", m.Value).
 				WillReturnRows(
 					sqlmock.NewRows([]string{"current"}).AddRow(m.Value),
 				)
 		case model.CounterType:
 
 			mock.ExpectQuery(
-				`INSERT INTO counter\(id, value\) values\(\$1, \$2\) ON conflict\(id\) 
-				DO UPDATE SET value \= counter.value \+ excluded.value 
-				RETURNING value`,
+				`INSERT INTO counter\(id, labels, value\) values\(\$1, \$2, \$3\) ON conflict\(id, labels\)
+					DO UPDATE SET value \= counter.value \+ excluded.value RETURNING id, labels, value \)
+					INSERT INTO sample\(type, id, labels, value\) SELECT 'counter', id, labels, value FROM upsert`,
 			).
-				WithArgs(m.ID, m.Delta).
+				WithArgs(m.ID, "", m.Delta).
 				WillReturnRows(
 					sqlmock.NewRows([]string{"current"}).AddRow(total + *m.Delta),
 				)
@@ -192,11 +193,11 @@ func TestRollbackBatchUpsertMetrics(t *testing.T) {
 						mock.ExpectRollback()
 					} else {
 						mock.ExpectQuery(
-							`INSERT INTO gauge\(id, value\) values\(\$1, \$2\) ON conflict\(id\) 
-								DO UPDATE SET value \= excluded.value
-								RETURNING value`,
+							`INSERT INTO gauge\(id, labels, value\) values\(\$1, \$2, \$3\) ON conflict\(id, labels\)
+								DO UPDATE SET value \= excluded.value RETURNING id, labels, value \)
+								INSERT INTO sample\(type, id, labels, value\) SELECT 'gauge', id, labels, value FROM upsert`,
 						).
-							WithArgs(m.ID, m.Value).
+							WithArgs(m.ID, "", m.Value).
 							WillReturnRows(
 								sqlmock.NewRows([]string{"current"}).AddRow(m.Value),
 							)
@@ -206,11 +207,11 @@ func TestRollbackBatchUpsertMetrics(t *testing.T) {
 						mock.ExpectRollback()
 					} else {
 						mock.ExpectQuery(
-							`INSERT INTO counter\(id, value\) values\(\$1, \$2\) ON conflict\(id\) 
-							DO UPDATE SET value \= counter.value \+ excluded.value 
-							RETURNING value`,
+							`INSERT INTO counter\(id, labels, value\) values\(\$1, \$2, \$3\) ON conflict\(id, labels\)
+								DO UPDATE SET value \= counter.value \+ excluded.value RETURNING id, labels, value \)
+								INSERT INTO sample\(type, id, labels, value\) SELECT 'counter', id, labels, value FROM upsert`,
 						).
-							WithArgs(m.ID, m.Delta).
+							WithArgs(m.ID, "", m.Delta).
 							WillReturnRows(
 								sqlmock.NewRows([]string{"current"}).AddRow(*m.Delta),
 							)
@@ -228,6 +229,61 @@ func TestRollbackBatchUpsertMetrics(t *testing.T) {
 	}
 }
 
+func TestIdempotentBatchUpsertMetrics(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	var delta int64 = 5
+	batch := []*model.MetricsV2{{ID: "counter_01", MType: model.CounterType, Delta: &delta}}
+	var current int64 = 15
+	expected := []*model.MetricsV2{{ID: "counter_01", MType: model.CounterType, Delta: &current}}
+	result := []byte(`[{"delta":15,"id":"counter_01","type":"counter"}]`)
+
+	store := newStore(db)
+	ctx := context.Background()
+
+	// новый ключ: батч применяется и результат сохраняется вместе с ключом
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM batch_key WHERE created_at < \$1`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO batch_key\(key\) VALUES\(\$1\) ON CONFLICT \(key\) DO NOTHING`).
+		WithArgs("batch-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO counter\(id, labels, value\) values\(\$1, \$2, \$3\)`).
+		WithArgs("counter_01", "", &delta).
+		WillReturnRows(sqlmock.NewRows([]string{"current"}).AddRow(current))
+	mock.ExpectExec(`UPDATE batch_key SET result=\$1 WHERE key=\$2`).
+		WithArgs(result, "batch-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	actual, err := store.IdempotentBatchUpsertMetrics(ctx, "batch-1", batch)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	// повтор: счетчик не обновляется, возвращается сохраненный результат
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM batch_key WHERE created_at < \$1`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO batch_key\(key\) VALUES\(\$1\) ON CONFLICT \(key\) DO NOTHING`).
+		WithArgs("batch-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT result FROM batch_key WHERE key=\$1`).
+		WithArgs("batch-1").
+		WillReturnRows(sqlmock.NewRows([]string{"result"}).AddRow(result))
+	mock.ExpectRollback()
+
+	actual, err = store.IdempotentBatchUpsertMetrics(ctx, "batch-1", batch)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetGauge(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -268,17 +324,17 @@ func TestGetGauge(t *testing.T) {
 		t.Run(tt.testName, func(t *testing.T) {
 			if tt.want != nil {
 				mock.ExpectQuery(
-					`SELECT id, value FROM gauge WHERE id=\$1`,
+					`SELECT id, labels, value FROM gauge WHERE id=\$1 AND labels=\$2`,
 				).
-					WithArgs(tt.metric.ID).
+					WithArgs(tt.metric.ID, "").
 					WillReturnRows(
-						sqlmock.NewRows([]string{"id", "value"}).AddRow(tt.want.Name, tt.want.Value),
+						sqlmock.NewRows([]string{"id", "labels", "value"}).AddRow(tt.want.Name, "", tt.want.Value),
 					)
 			} else {
 				mock.ExpectQuery(
-					`SELECT id, value FROM gauge WHERE id=\$1`,
+					`SELECT id, labels, value FROM gauge WHERE id=\$1 AND labels=\$2`,
 				).
-					WithArgs(tt.metric.ID).
+					WithArgs(tt.metric.ID, "").
 					WillReturnError(sql.ErrNoRows)
 			}
 
@@ -331,17 +387,17 @@ func TestGetCounter(t *testing.T) {
 		t.Run(tt.testName, func(t *testing.T) {
 			if tt.want != nil {
 				mock.ExpectQuery(
-					`SELECT id, value FROM counter WHERE id=\$1`,
+					`SELECT id, labels, value FROM counter WHERE id=\$1 AND labels=\$2`,
 				).
-					WithArgs(tt.metric.ID).
+					WithArgs(tt.metric.ID, "").
 					WillReturnRows(
-						sqlmock.NewRows([]string{"id", "value"}).AddRow(tt.want.Name, tt.want.Value),
+						sqlmock.NewRows([]string{"id", "labels", "value"}).AddRow(tt.want.Name, "", tt.want.Value),
 					)
 			} else {
 				mock.ExpectQuery(
-					`SELECT id, value FROM counter WHERE id=\$1`,
+					`SELECT id, labels, value FROM counter WHERE id=\$1 AND labels=\$2`,
 				).
-					WithArgs(tt.metric.ID).
+					WithArgs(tt.metric.ID, "").
 					WillReturnError(sql.ErrNoRows)
 			}
 
@@ -380,9 +436,9 @@ func TestSetGauge(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.testName, func(t *testing.T) {
 			mock.ExpectExec(
-				`INSERT INTO gauge\(id, value\) values\(\$1, \$2\) ON conflict\(id\) DO UPDATE SET value \= excluded\.value`,
+				`INSERT INTO gauge\(id, labels, value\) values\(\$1, \$2, \$3\) ON conflict\(id, labels\) DO UPDATE SET value \= excluded\.value RETURNING id, labels, value \) INSERT INTO sample`,
 			).
-				WithArgs(tt.metric.Name, tt.metric.Value).
+				WithArgs(tt.metric.Name, tt.metric.Labels.String(), tt.metric.Value).
 				WillReturnResult(sqlmock.NewResult(1, 1))
 
 			err := store.SetGauge(ctx, tt.metric)
@@ -418,9 +474,9 @@ func TestSetCounter(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.testName, func(t *testing.T) {
 			mock.ExpectExec(
-				`INSERT INTO counter\(id, value\) values\(\$1, \$2\) ON conflict\(id\) DO UPDATE SET value \= excluded\.value`,
+				`INSERT INTO counter\(id, labels, value\) values\(\$1, \$2, \$3\) ON conflict\(id, labels\) DO UPDATE SET value \= excluded\.value RETURNING id, labels, value \) INSERT INTO sample`,
 			).
-				WithArgs(tt.metric.Name, tt.metric.Value).
+				WithArgs(tt.metric.Name, tt.metric.Labels.String(), tt.metric.Value).
 				WillReturnResult(sqlmock.NewResult(1, 1))
 
 			err := store.SetCounter(ctx, tt.metric)
@@ -446,16 +502,17 @@ func TestListGauge(t *testing.T) {
 			Value: 123.0,
 		},
 		{
-			Name:  "gauge_02",
-			Value: 1.01,
+			Name:   "gauge_02",
+			Labels: model.Labels{"host": "a"},
+			Value:  1.01,
 		},
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "value"}).
-		AddRow("gauge_01", 123.0).
-		AddRow("gauge_02", 1.01)
+	rows := sqlmock.NewRows([]string{"id", "labels", "value"}).
+		AddRow("gauge_01", "", 123.0).
+		AddRow("gauge_02", `host="a"`, 1.01)
 
-	mock.ExpectQuery(`SELECT id, value FROM gauge`).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT id, labels, value FROM gauge`).WillReturnRows(rows)
 
 	actual, err := store.ListGauge(ctx)
 	require.NoError(t, err)
@@ -484,14 +541,187 @@ func TestListCounter(t *testing.T) {
 		},
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "value"}).
-		AddRow("counter_01", 123).
-		AddRow("counter_02", 1)
+	rows := sqlmock.NewRows([]string{"id", "labels", "value"}).
+		AddRow("counter_01", "", 123).
+		AddRow("counter_02", "", 1)
 
-	mock.ExpectQuery(`SELECT id, value FROM counter`).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT id, labels, value FROM counter`).WillReturnRows(rows)
 
 	actual, err := store.ListCounter(ctx)
 	require.NoError(t, err)
 	require.NoError(t, mock.ExpectationsWereMet())
 	assert.Equal(t, metrics, actual)
 }
+
+func TestBatchUpsertHistogram(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	value := 0.3
+	batch := []*model.MetricsV2{
+		{
+			ID:    "histogram_01",
+			MType: model.HistogramType,
+			Value: &value,
+		},
+	}
+
+	store := newStore(db)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, labels, buckets, counts, sum FROM histogram WHERE id=\$1 AND labels=\$2 FOR UPDATE`).
+		WithArgs("histogram_01", "").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "labels", "buckets", "counts", "sum"}).
+				AddRow("histogram_01", "", []byte("[0.1,1]"), []byte("[1,2,0]"), 1.1),
+		)
+	mock.ExpectExec(`INSERT INTO histogram\(id, labels, buckets, counts, sum\) values\(\$1, \$2, \$3, \$4, \$5\)`).
+		WithArgs("histogram_01", "", []byte("[0.1,1]"), []byte("[1,3,0]"), 1.4000000000000001, 4.0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	actual, err := store.BatchUpsertMetrics(context.Background(), batch)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	expected := []*model.MetricsV2{
+		{
+			ID:        "histogram_01",
+			MType:     model.HistogramType,
+			Histogram: &model.HistogramValue{Buckets: []float64{0.1, 1}, Counts: []int64{1, 3, 0}, Sum: 1.4000000000000001},
+		},
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestGetHistogram(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := newStore(db)
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT id, labels, buckets, counts, sum FROM histogram WHERE id=\$1 AND labels=\$2`).
+		WithArgs("histogram_01", `host="a"`).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "labels", "buckets", "counts", "sum"}).
+				AddRow("histogram_01", `host="a"`, []byte("[1,5]"), []byte("[1,0,2]"), 21.5),
+		)
+	mock.ExpectQuery(`SELECT id, labels, buckets, counts, sum FROM histogram WHERE id=\$1 AND labels=\$2`).
+		WithArgs("histogram_02", "").
+		WillReturnError(sql.ErrNoRows)
+
+	req := &model.MetricsV2{ID: "histogram_01", MType: model.HistogramType, Labels: model.Labels{"host": "a"}}
+	actual, err := store.GetHistogram(ctx, req)
+	require.NoError(t, err)
+	expected := &model.Histogram{
+		Name:    "histogram_01",
+		Labels:  model.Labels{"host": "a"},
+		Buckets: []float64{1, 5},
+		Counts:  []int64{1, 0, 2},
+		Sum:     21.5,
+	}
+	assert.Equal(t, expected, actual)
+
+	actual, err = store.GetHistogram(ctx, &model.MetricsV2{ID: "histogram_02", MType: model.HistogramType})
+	require.NoError(t, err)
+	assert.Nil(t, actual)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListSamples(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := newStore(db)
+	ctx := context.Background()
+
+	to := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	from := to.Add(-time.Hour)
+	expected := []*model.Sample{
+		{Timestamp: from.Add(time.Minute), Value: 10},
+		{Timestamp: from.Add(2 * time.Minute), Value: 12.5},
+	}
+
+	rows := sqlmock.NewRows([]string{"ts", "value"})
+	for _, sample := range expected {
+		rows.AddRow(sample.Timestamp, sample.Value)
+	}
+	mock.ExpectQuery(`SELECT ts, value FROM sample WHERE type=\$1 AND id=\$2 AND labels=\$3 AND ts >= \$4 AND ts <= \$5 ORDER BY ts`).
+		WithArgs("gauge", "HeapAlloc", `host="a"`, from, to).
+		WillReturnRows(rows)
+
+	req := &model.MetricsV2{ID: "HeapAlloc", MType: model.GaugeType, Labels: model.Labels{"host": "a"}}
+	actual, err := store.ListSamples(ctx, req, from, to)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, expected, actual)
+}
+
+func TestQueryMetrics(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := newStore(db)
+	ctx := context.Background()
+
+	value := 1.5
+	var delta int64 = 3
+	expected := []*model.MetricsV2{
+		{ID: "Heap_Alloc", MType: model.GaugeType, Labels: model.Labels{"host": "b"}, Value: &value},
+		{ID: "Heap_Alloc", MType: model.CounterType, Delta: &delta},
+		{
+			ID:        "Heap_Alloc",
+			MType:     model.HistogramType,
+			Histogram: &model.HistogramValue{Buckets: []float64{1}, Counts: []int64{1, 2}, Sum: 4.5},
+		},
+	}
+
+	rows := sqlmock.NewRows([]string{"type", "id", "labels", "value", "delta", "buckets", "counts", "sum"}).
+		AddRow("gauge", "Heap_Alloc", `host="b"`, value, nil, nil, nil, nil).
+		AddRow("counter", "Heap_Alloc", "", nil, delta, nil, nil, nil).
+		AddRow("histogram", "Heap_Alloc", "", nil, nil, []byte("[1]"), []byte("[1,2]"), 4.5)
+	mock.ExpectQuery(
+		`SELECT type, id, labels, value, delta, buckets, counts, sum FROM \(.* FROM gauge UNION ALL .* FROM counter `+
+			`UNION ALL .* FROM histogram\) AS metrics WHERE id LIKE \$1 AND \(id, labels, type\) < \(\$2, \$3, \$4\) `+
+			`ORDER BY id DESC, labels DESC, type DESC LIMIT \$5`,
+	).
+		WithArgs(`Heap\_%`, "Heap_Alloc", `host="c"`, "gauge", 10).
+		WillReturnRows(rows)
+
+	q := &model.MetricsQuery{
+		Match: "Heap_*",
+		Sort:  model.SortByName,
+		Desc:  true,
+		Limit: 10,
+		After: &model.MetricsCursor{ID: "Heap_Alloc", Labels: `host="c"`, MType: model.GaugeType},
+	}
+	actual, err := store.QueryMetrics(ctx, q)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, expected, actual)
+}
+
+func TestBuildMetricsQuery(t *testing.T) {
+	query, args := buildMetricsQuery(&model.MetricsQuery{MType: model.CounterType, Sort: model.SortByType})
+	assert.NotContains(t, query, "FROM gauge")
+	assert.NotContains(t, query, "FROM histogram")
+	assert.Contains(t, query, "FROM counter")
+	assert.NotContains(t, query, "WHERE")
+	assert.NotContains(t, query, "LIMIT")
+	assert.Contains(t, query, "ORDER BY type ASC, id ASC, labels ASC")
+	assert.Empty(t, args)
+
+	assert.Equal(t, `100\%\_done\\%_`, globToLike(`100%_done\*?`))
+}
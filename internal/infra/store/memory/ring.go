@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"time"
+
+	"metrics/internal/core/model"
+)
+
+// ring is a fixed-size buffer with the latest samples of a metric.
+// When the buffer is full a new sample overwrites the oldest one, so memory usage is bounded.
+type ring struct {
+	samples []model.Sample
+	start   int
+	size    int
+}
+
+func newRing(capacity int) *ring {
+	return &ring{samples: make([]model.Sample, capacity)}
+}
+
+func (r *ring) push(sample model.Sample) {
+	capacity := len(r.samples)
+	if capacity == 0 {
+		return
+	}
+	if r.size < capacity {
+		r.samples[(r.start+r.size)%capacity] = sample
+		r.size++
+		return
+	}
+	r.samples[r.start] = sample
+	r.start = (r.start + 1) % capacity
+}
+
+// all returns samples from the oldest to the newest.
+func (r *ring) all() []model.Sample {
+	res := make([]model.Sample, 0, r.size)
+	for i := 0; i < r.size; i++ {
+		res = append(res, r.samples[(r.start+i)%len(r.samples)])
+	}
+	return res
+}
+
+// between returns samples written between from and to (inclusive) ordered by time.
+func (r *ring) between(from, to time.Time) []*model.Sample {
+	res := make([]*model.Sample, 0, r.size)
+	for _, sample := range r.all() {
+		if sample.Timestamp.Before(from) || sample.Timestamp.After(to) {
+			continue
+		}
+		s := sample
+		res = append(res, &s)
+	}
+	return res
+}
@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"metrics/internal/core/model"
+)
+
+func TestRing(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	r := newRing(3)
+
+	for i := 0; i < 5; i++ {
+		r.push(model.Sample{Timestamp: start.Add(time.Duration(i) * time.Minute), Value: float64(i)})
+	}
+
+	expected := []model.Sample{
+		{Timestamp: start.Add(2 * time.Minute), Value: 2},
+		{Timestamp: start.Add(3 * time.Minute), Value: 3},
+		{Timestamp: start.Add(4 * time.Minute), Value: 4},
+	}
+	assert.Equal(t, expected, r.all())
+
+	between := r.between(start.Add(3*time.Minute), start.Add(10*time.Minute))
+	assert.Equal(t, []*model.Sample{&expected[1], &expected[2]}, between)
+
+	assert.Empty(t, newRing(0).all())
+}
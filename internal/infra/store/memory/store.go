@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -18,20 +19,43 @@ import (
 )
 
 type Store struct {
-	mux     *sync.RWMutex
-	quit    chan bool
-	config  *config.StorageConfig
-	gauge   map[string]*model.Gauge
-	counter map[string]*model.Counter
+	mux       *sync.RWMutex
+	quit      chan bool
+	config    *config.StorageConfig
+	gauge     map[string]*model.Gauge
+	counter   map[string]*model.Counter
+	histogram map[string]*model.Histogram
+	history   map[string]*ring
+	batchMux  *sync.Mutex
+	batches   map[string]*appliedBatch
+	batchKeys []string // keys of applied batches in order of applying, used to forget old keys
+}
+
+// appliedBatch is a result of the batch with the idempotency key.
+type appliedBatch struct {
+	createdAt time.Time
+	results   []*model.MetricsV2
+}
+
+// dumpData is a JSON file representation of the store.
+type dumpData struct {
+	Gauge     map[string]*model.Gauge     `json:"gauge"`
+	Counter   map[string]*model.Counter   `json:"counter"`
+	Histogram map[string]*model.Histogram `json:"histogram"`
+	History   map[string][]model.Sample   `json:"history,omitempty"`
 }
 
 func NewStore(ctx context.Context, wg *sync.WaitGroup, cfg *config.StorageConfig) (*Store, error) {
 	store := &Store{
-		mux:     &sync.RWMutex{},
-		quit:    make(chan bool),
-		config:  cfg,
-		gauge:   make(map[string]*model.Gauge),
-		counter: make(map[string]*model.Counter),
+		mux:       &sync.RWMutex{},
+		quit:      make(chan bool),
+		config:    cfg,
+		gauge:     make(map[string]*model.Gauge),
+		counter:   make(map[string]*model.Counter),
+		histogram: make(map[string]*model.Histogram),
+		history:   make(map[string]*ring),
+		batchMux:  &sync.Mutex{},
+		batches:   make(map[string]*appliedBatch),
 	}
 
 	if cfg.Restore && cfg.FileStoragePath != "" {
@@ -47,6 +71,8 @@ func NewStore(ctx context.Context, wg *sync.WaitGroup, cfg *config.StorageConfig
 		zap.String("FileStoragePath", cfg.FileStoragePath),
 		zap.Int64("StoreIntreval", cfg.StoreIntreval),
 		zap.Bool("Restore", cfg.Restore),
+		zap.Int("HistorySize", cfg.HistorySize),
+		zap.Int64("HistoryRetention", cfg.HistoryRetention),
 	)
 	return store, nil
 }
@@ -60,7 +86,7 @@ func (s *Store) GetGauge(_ context.Context, req *model.MetricsV2) (*model.Gauge,
 	s.mux.RLock()
 	defer s.mux.RUnlock()
 
-	res, ok := s.gauge[req.ID]
+	res, ok := s.gauge[req.Key()]
 	if !ok {
 		return nil, nil
 	}
@@ -71,7 +97,8 @@ func (s *Store) SetGauge(_ context.Context, gauge *model.Gauge) error {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
-	s.gauge[gauge.Name] = gauge
+	s.gauge[gauge.Key()] = gauge
+	s.record(gauge.Type(), gauge.Key(), gauge.Value)
 	if s.config.StoreIntreval == 0 && s.config.FileStoragePath != "" {
 		s.saveDump()
 	}
@@ -82,7 +109,7 @@ func (s *Store) GetCounter(_ context.Context, req *model.MetricsV2) (*model.Coun
 	s.mux.RLock()
 	defer s.mux.RUnlock()
 
-	res, ok := s.counter[req.ID]
+	res, ok := s.counter[req.Key()]
 	if !ok {
 		return nil, nil
 	}
@@ -93,7 +120,31 @@ func (s *Store) SetCounter(_ context.Context, counter *model.Counter) error {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
-	s.counter[counter.Name] = counter
+	s.counter[counter.Key()] = counter
+	s.record(counter.Type(), counter.Key(), float64(counter.Value))
+	if s.config.StoreIntreval == 0 && s.config.FileStoragePath != "" {
+		s.saveDump()
+	}
+	return nil
+}
+
+func (s *Store) GetHistogram(_ context.Context, req *model.MetricsV2) (*model.Histogram, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	res, ok := s.histogram[req.Key()]
+	if !ok {
+		return nil, nil
+	}
+	return res, nil
+}
+
+func (s *Store) SetHistogram(_ context.Context, histogram *model.Histogram) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.histogram[histogram.Key()] = histogram
+	s.record(histogram.Type(), histogram.Key(), float64(histogram.Count()))
 	if s.config.StoreIntreval == 0 && s.config.FileStoragePath != "" {
 		s.saveDump()
 	}
@@ -105,6 +156,7 @@ func (s *Store) BatchUpsertMetrics(ctx context.Context, metrics []*model.Metrics
 	for _, m := range metrics {
 		logger.Log.Debug("BatchUpsertMetrics input",
 			zap.String("metric", m.ID),
+			zap.String("labels", m.Labels.String()),
 			zap.String("type", m.MType.String()),
 			zap.Float64p("value", m.Value),
 			zap.Int64p("delta", m.Delta),
@@ -119,14 +171,14 @@ func (s *Store) BatchUpsertMetrics(ctx context.Context, metrics []*model.Metrics
 				return nil, fmt.Errorf("failed to fetch %s from the store: %w", m.MType.String(), err)
 			}
 			if gauge == nil {
-				gauge = model.NewGauge(m.ID)
+				gauge = model.NewGauge(m.ID, m.Labels)
 			}
 			gauge.Set(*m.Value)
 			if err = s.SetGauge(ctx, gauge); err != nil {
 				return nil, fmt.Errorf("failed to save gauge to store: %w", err)
 			}
 			v := gauge.Value
-			results = append(results, &model.MetricsV2{ID: m.ID, MType: m.MType, Value: &v})
+			results = append(results, &model.MetricsV2{ID: m.ID, Labels: gauge.Labels, MType: m.MType, Value: &v})
 		case model.CounterType:
 			if m.Delta == nil {
 				return nil, errors.New("incorrect value")
@@ -136,14 +188,32 @@ func (s *Store) BatchUpsertMetrics(ctx context.Context, metrics []*model.Metrics
 				return nil, fmt.Errorf("failed to fetch %s from the store: %w", m.MType.String(), err)
 			}
 			if counter == nil {
-				counter = model.NewCounter(m.ID)
+				counter = model.NewCounter(m.ID, m.Labels)
 			}
 			counter.Increment(*m.Delta)
 			if err = s.SetCounter(ctx, counter); err != nil {
 				return nil, fmt.Errorf("failed to save gauge to store: %w", err)
 			}
 			v := counter.Value
-			results = append(results, &model.MetricsV2{ID: m.ID, MType: m.MType, Delta: &v})
+			results = append(results, &model.MetricsV2{ID: m.ID, Labels: counter.Labels, MType: m.MType, Delta: &v})
+		case model.HistogramType:
+			histogram, err := s.GetHistogram(ctx, m)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch %s from the store: %w", m.MType.String(), err)
+			}
+			if histogram == nil {
+				histogram = model.NewHistogramFromRequest(m)
+			}
+			if err = histogram.Apply(m); err != nil {
+				return nil, fmt.Errorf("failed to update histogram (%s): %w", m.ID, err)
+			}
+			if err = s.SetHistogram(ctx, histogram); err != nil {
+				return nil, fmt.Errorf("failed to save histogram to store: %w", err)
+			}
+			results = append(
+				results,
+				&model.MetricsV2{ID: m.ID, Labels: histogram.Labels, MType: m.MType, Histogram: histogram.Payload()},
+			)
 		default:
 			return nil, fmt.Errorf("unknown metric type: %s", m.MType.String())
 		}
@@ -151,13 +221,44 @@ func (s *Store) BatchUpsertMetrics(ctx context.Context, metrics []*model.Metrics
 	return results, nil
 }
 
+// IdempotentBatchUpsertMetrics applies the batch once, the batch with the applied key returns the saved result.
+// Batches with keys are applied one by one. Keys are kept for model.IdempotencyKeyTTL and are not dumped to the file.
+func (s *Store) IdempotentBatchUpsertMetrics(
+	ctx context.Context, key string, metrics []*model.MetricsV2,
+) ([]*model.MetricsV2, error) {
+	if key == "" {
+		return s.BatchUpsertMetrics(ctx, metrics)
+	}
+
+	s.batchMux.Lock()
+	defer s.batchMux.Unlock()
+
+	now := time.Now()
+	for len(s.batchKeys) > 0 && now.Sub(s.batches[s.batchKeys[0]].createdAt) > model.IdempotencyKeyTTL {
+		delete(s.batches, s.batchKeys[0])
+		s.batchKeys = s.batchKeys[1:]
+	}
+
+	if batch, ok := s.batches[key]; ok {
+		logger.Log.Debug("Batch is already applied", zap.String("key", key))
+		return batch.results, nil
+	}
+	results, err := s.BatchUpsertMetrics(ctx, metrics)
+	if err != nil {
+		return nil, err
+	}
+	s.batches[key] = &appliedBatch{createdAt: now, results: results}
+	s.batchKeys = append(s.batchKeys, key)
+	return results, nil
+}
+
 func (s *Store) ListGauge(_ context.Context) ([]*model.Gauge, error) {
 	s.mux.RLock()
 	defer s.mux.RUnlock()
 
 	res := make([]*model.Gauge, 0, len(s.gauge))
-	for _, v := range s.gauge {
-		res = append(res, v)
+	for _, key := range sortedKeys(s.gauge) {
+		res = append(res, s.gauge[key])
 	}
 	return res, nil
 }
@@ -167,20 +268,82 @@ func (s *Store) ListCounter(_ context.Context) ([]*model.Counter, error) {
 	defer s.mux.RUnlock()
 
 	res := make([]*model.Counter, 0, len(s.counter))
-	for _, v := range s.counter {
-		res = append(res, v)
+	for _, key := range sortedKeys(s.counter) {
+		res = append(res, s.counter[key])
+	}
+	return res, nil
+}
+
+func (s *Store) ListHistogram(_ context.Context) ([]*model.Histogram, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	res := make([]*model.Histogram, 0, len(s.histogram))
+	for _, key := range sortedKeys(s.histogram) {
+		res = append(res, s.histogram[key])
+	}
+	return res, nil
+}
+
+// QueryMetrics returns metrics satisfying the query filters ordered by the query sort key.
+func (s *Store) QueryMetrics(_ context.Context, q *model.MetricsQuery) ([]*model.MetricsV2, error) {
+	type item struct {
+		cursor *model.MetricsCursor
+		metric *model.MetricsV2
+	}
+	items := make([]item, 0)
+	add := func(m *model.MetricsV2) {
+		c := model.NewMetricsCursor(m)
+		if q.After != nil && q.Compare(c, q.After) <= 0 {
+			return
+		}
+		items = append(items, item{cursor: c, metric: m})
+	}
+
+	s.mux.RLock()
+	for _, g := range s.gauge {
+		if q.Matches(g.Name, model.GaugeType) {
+			v := g.Value
+			add(&model.MetricsV2{ID: g.Name, Labels: g.Labels, MType: model.GaugeType, Value: &v})
+		}
+	}
+	for _, c := range s.counter {
+		if q.Matches(c.Name, model.CounterType) {
+			v := c.Value
+			add(&model.MetricsV2{ID: c.Name, Labels: c.Labels, MType: model.CounterType, Delta: &v})
+		}
+	}
+	for _, h := range s.histogram {
+		if q.Matches(h.Name, model.HistogramType) {
+			add(&model.MetricsV2{ID: h.Name, Labels: h.Labels, MType: model.HistogramType, Histogram: h.Payload()})
+		}
+	}
+	s.mux.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		return q.Compare(items[i].cursor, items[j].cursor) < 0
+	})
+	if q.Limit > 0 && len(items) > q.Limit {
+		items = items[:q.Limit]
+	}
+
+	res := make([]*model.MetricsV2, 0, len(items))
+	for _, it := range items {
+		res = append(res, it.metric)
 	}
 	return res, nil
 }
 
 func (s *Store) saveDump() error {
 	logger.Log.Debug("Dump DB to file", zap.String("path", s.config.FileStoragePath))
-	dump := struct {
-		Gauge   map[string]*model.Gauge   `json:"gauge"`
-		Counter map[string]*model.Counter `json:"counter"`
-	}{
-		Gauge:   s.gauge,
-		Counter: s.counter,
+	dump := dumpData{
+		Gauge:     s.gauge,
+		Counter:   s.counter,
+		Histogram: s.histogram,
+		History:   make(map[string][]model.Sample, len(s.history)),
+	}
+	for key, r := range s.history {
+		dump.History[key] = r.all()
 	}
 
 	data, err := json.MarshalIndent(dump, "", " ")
@@ -200,12 +363,10 @@ func (s *Store) loadDump() error {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 	logger.Log.Info("Load DB dump", zap.String("path", s.config.FileStoragePath))
-	dump := struct {
-		Gauge   map[string]*model.Gauge   `json:"gauge"`
-		Counter map[string]*model.Counter `json:"counter"`
-	}{
-		Gauge:   s.gauge,
-		Counter: s.counter,
+	dump := dumpData{
+		Gauge:     s.gauge,
+		Counter:   s.counter,
+		Histogram: s.histogram,
 	}
 
 	file, err := os.OpenFile(s.config.FileStoragePath, os.O_RDONLY|os.O_CREATE, 0666)
@@ -233,6 +394,18 @@ func (s *Store) loadDump() error {
 
 	s.gauge = dump.Gauge
 	s.counter = dump.Counter
+	if dump.Histogram != nil {
+		s.histogram = dump.Histogram
+	}
+	if s.config.HistorySize > 0 {
+		for key, samples := range dump.History {
+			r := newRing(s.config.HistorySize)
+			for _, sample := range samples {
+				r.push(sample)
+			}
+			s.history[key] = r
+		}
+	}
 	return nil
 }
 
@@ -258,6 +431,57 @@ func (s *Store) dumpPeriodicly(ctx context.Context, wg *sync.WaitGroup) {
 	}
 }
 
+// sortedKeys returns map keys in ascending order, so metrics are listed in the stable order.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func historyKey(mType model.MetricType, key string) string {
+	return mType.String() + ":" + key
+}
+
+// record appends the value to the metric history ring buffer. Should be called under the write lock.
+func (s *Store) record(mType model.MetricType, key string, value float64) {
+	if s.config.HistorySize <= 0 {
+		return
+	}
+	hKey := historyKey(mType, key)
+	r, ok := s.history[hKey]
+	if !ok {
+		r = newRing(s.config.HistorySize)
+		s.history[hKey] = r
+	}
+	r.push(model.Sample{Timestamp: time.Now(), Value: value})
+}
+
+// ListSamples returns the latest values of the metric kept in the ring buffer between from and to.
+// Samples older than HistoryRetention are skipped.
+func (s *Store) ListSamples(_ context.Context, req *model.MetricsV2, from, to time.Time) ([]*model.Sample, error) {
+	if s.config.HistorySize <= 0 {
+		return nil, errors.New("memory store history is disabled")
+	}
+	if s.config.HistoryRetention > 0 {
+		oldest := time.Now().Add(-time.Duration(s.config.HistoryRetention) * time.Second)
+		if from.Before(oldest) {
+			from = oldest
+		}
+	}
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	r, ok := s.history[historyKey(req.MType, req.Key())]
+	if !ok {
+		return []*model.Sample{}, nil
+	}
+	return r.between(from, to), nil
+}
+
 func (s *Store) Ping(_ context.Context) error {
 	return errors.New("memory store not supported ping")
 }
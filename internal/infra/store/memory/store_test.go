@@ -2,6 +2,7 @@ package memory
 
 import (
 	"context"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -152,6 +153,139 @@ func TestMemStorageSetAndGetGauge(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestMemStorageLabels(t *testing.T) {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	repo, err := NewStore(
+		ctx,
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+		},
+	)
+	require.NoError(t, err)
+
+	value01 := 1.0
+	value02 := 2.0
+	batch := []*model.MetricsV2{
+		{ID: "HeapAlloc", MType: model.GaugeType, Value: &value01, Labels: model.Labels{"host": "a"}},
+		{ID: "HeapAlloc", MType: model.GaugeType, Value: &value02, Labels: model.Labels{"host": "b"}},
+	}
+	_, err = repo.BatchUpsertMetrics(ctx, batch)
+	require.NoError(t, err)
+
+	gauges, err := repo.ListGauge(ctx)
+	require.NoError(t, err)
+	assert.Len(t, gauges, 2)
+
+	gauge, err := repo.GetGauge(ctx, &model.MetricsV2{ID: "HeapAlloc", Labels: model.Labels{"host": "b"}})
+	require.NoError(t, err)
+	assert.InEpsilon(t, value02, gauge.Value, 0)
+
+	gauge, err = repo.GetGauge(ctx, &model.MetricsV2{ID: "HeapAlloc"})
+	require.NoError(t, err)
+	assert.Nil(t, gauge)
+}
+
+func TestMemStorageIdempotentBatchUpsert(t *testing.T) {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	repo, err := NewStore(
+		ctx,
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+		},
+	)
+	require.NoError(t, err)
+
+	delta := int64(5)
+	batch := []*model.MetricsV2{{ID: "PollCount", MType: model.CounterType, Delta: &delta}}
+
+	first, err := repo.IdempotentBatchUpsertMetrics(ctx, "batch-1", batch)
+	require.NoError(t, err)
+	retry, err := repo.IdempotentBatchUpsertMetrics(ctx, "batch-1", batch)
+	require.NoError(t, err)
+	assert.Equal(t, first, retry)
+
+	counter, err := repo.GetCounter(ctx, &model.MetricsV2{ID: "PollCount"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), counter.Value)
+
+	_, err = repo.IdempotentBatchUpsertMetrics(ctx, "batch-2", batch)
+	require.NoError(t, err)
+	_, err = repo.IdempotentBatchUpsertMetrics(ctx, "", batch)
+	require.NoError(t, err)
+
+	counter, err = repo.GetCounter(ctx, &model.MetricsV2{ID: "PollCount"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), counter.Value)
+
+	// ключ забывается после IdempotencyKeyTTL
+	repo.batches["batch-1"].createdAt = time.Now().Add(-model.IdempotencyKeyTTL - time.Second)
+	_, err = repo.IdempotentBatchUpsertMetrics(ctx, "batch-1", batch)
+	require.NoError(t, err)
+
+	counter, err = repo.GetCounter(ctx, &model.MetricsV2{ID: "PollCount"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), counter.Value)
+}
+
+func TestMemStorageBatchUpsertHistogram(t *testing.T) {
+	value := 0.3
+	batch := []*model.MetricsV2{
+		{
+			ID:        "histogram_01",
+			MType:     model.HistogramType,
+			Histogram: &model.HistogramValue{Buckets: []float64{0.1, 1}, Counts: []int64{1, 2, 0}, Sum: 1.1},
+		},
+		{
+			ID:    "histogram_01",
+			MType: model.HistogramType,
+			Value: &value,
+		},
+	}
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	repo, err := NewStore(
+		ctx,
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+		},
+	)
+	require.NoError(t, err)
+
+	actual, err := repo.BatchUpsertMetrics(ctx, batch)
+	require.NoError(t, err)
+	require.Len(t, actual, 2)
+	assert.Equal(t, []int64{1, 2, 0}, actual[0].Histogram.Counts)
+	assert.Equal(t, []int64{1, 3, 0}, actual[1].Histogram.Counts)
+	assert.InEpsilon(t, 1.4, actual[1].Histogram.Sum, 0.0001)
+
+	histogram, err := repo.GetHistogram(ctx, &model.MetricsV2{ID: "histogram_01", MType: model.HistogramType})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0.1, 1}, histogram.Buckets)
+	assert.Equal(t, int64(4), histogram.Count())
+
+	mismatched := []*model.MetricsV2{
+		{
+			ID:        "histogram_01",
+			MType:     model.HistogramType,
+			Histogram: &model.HistogramValue{Buckets: []float64{1}, Counts: []int64{1, 0}},
+		},
+	}
+	_, err = repo.BatchUpsertMetrics(ctx, mismatched)
+	require.Error(t, err)
+}
+
 func TestListGauge(t *testing.T) {
 	ctx := context.Background()
 	var wg sync.WaitGroup
@@ -221,6 +355,123 @@ func TestListCounter(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.StorageConfig{
+		StoreIntreval:   1000,
+		FileStoragePath: filepath.Join(t.TempDir(), "storage_dump.json"),
+		Restore:         true,
+		HistorySize:     2,
+	}
+
+	var wg sync.WaitGroup
+	store, err := NewStore(ctx, &wg, cfg)
+	require.NoError(t, err)
+
+	for _, v := range []float64{1, 2, 3} {
+		err = store.SetGauge(ctx, &model.Gauge{Name: "gauge_01", Labels: model.Labels{"host": "a"}, Value: v})
+		require.NoError(t, err)
+	}
+	err = store.SetCounter(ctx, &model.Counter{Name: "gauge_01", Value: 10})
+	require.NoError(t, err)
+
+	req := &model.MetricsV2{ID: "gauge_01", MType: model.GaugeType, Labels: model.Labels{"host": "a"}}
+	now := time.Now()
+	samples, err := store.ListSamples(ctx, req, now.Add(-time.Minute), now)
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+	assert.InEpsilon(t, 2.0, samples[0].Value, 0)
+	assert.InEpsilon(t, 3.0, samples[1].Value, 0)
+
+	// history survives the dump
+	require.NoError(t, store.saveDump())
+	restored, err := NewStore(ctx, &wg, cfg)
+	require.NoError(t, err)
+	restoredSamples, err := restored.ListSamples(ctx, req, now.Add(-time.Minute), now)
+	require.NoError(t, err)
+	assert.Equal(t, len(samples), len(restoredSamples))
+	assert.True(t, samples[1].Timestamp.Equal(restoredSamples[1].Timestamp))
+
+	counterSamples, err := restored.ListSamples(
+		ctx, &model.MetricsV2{ID: "gauge_01", MType: model.CounterType}, now.Add(-time.Minute), now,
+	)
+	require.NoError(t, err)
+	require.Len(t, counterSamples, 1)
+	assert.InEpsilon(t, 10.0, counterSamples[0].Value, 0)
+
+	cfg.HistorySize = 0
+	_, err = store.ListSamples(ctx, req, now.Add(-time.Minute), now)
+	require.Error(t, err)
+}
+
+func TestQueryMetrics(t *testing.T) {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	store, err := NewStore(
+		ctx,
+		&wg,
+		&config.StorageConfig{
+			StoreIntreval:   1000,
+			FileStoragePath: "/tmp/storage_dump.json",
+			Restore:         false,
+		},
+	)
+	require.NoError(t, err)
+
+	heap, stack := 1.5, 2.5
+	var polls int64 = 3
+	batch := []*model.MetricsV2{
+		{ID: "StackInuse", MType: model.GaugeType, Value: &stack},
+		{ID: "HeapAlloc", MType: model.GaugeType, Labels: model.Labels{"host": "b"}, Value: &heap},
+		{ID: "HeapAlloc", MType: model.GaugeType, Labels: model.Labels{"host": "a"}, Value: &heap},
+		{ID: "PollCount", MType: model.CounterType, Delta: &polls},
+		{ID: "HeapAlloc", MType: model.CounterType, Delta: &polls},
+	}
+	_, err = store.BatchUpsertMetrics(ctx, batch)
+	require.NoError(t, err)
+
+	keys := func(metrics []*model.MetricsV2) []string {
+		res := make([]string, 0, len(metrics))
+		for _, m := range metrics {
+			res = append(res, m.MType.String()+":"+m.Key())
+		}
+		return res
+	}
+
+	actual, err := store.QueryMetrics(ctx, &model.MetricsQuery{Sort: model.SortByName})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"counter:HeapAlloc",
+		`gauge:HeapAlloc{host="a"}`,
+		`gauge:HeapAlloc{host="b"}`,
+		"counter:PollCount",
+		"gauge:StackInuse",
+	}, keys(actual))
+
+	q := &model.MetricsQuery{MType: model.GaugeType, Match: "Heap*", Sort: model.SortByName, Limit: 1}
+	actual, err = store.QueryMetrics(ctx, q)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`gauge:HeapAlloc{host="a"}`}, keys(actual))
+
+	q.After = model.NewMetricsCursor(actual[0])
+	actual, err = store.QueryMetrics(ctx, q)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`gauge:HeapAlloc{host="b"}`}, keys(actual))
+
+	q.After = model.NewMetricsCursor(actual[0])
+	actual, err = store.QueryMetrics(ctx, q)
+	require.NoError(t, err)
+	assert.Empty(t, actual)
+
+	actual, err = store.QueryMetrics(ctx, &model.MetricsQuery{Sort: model.SortByType, Desc: true, Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"gauge:StackInuse",
+		`gauge:HeapAlloc{host="b"}`,
+		`gauge:HeapAlloc{host="a"}`,
+	}, keys(actual))
+}
+
 func TestPing(t *testing.T) {
 	ctx := context.Background()
 
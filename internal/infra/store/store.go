@@ -5,6 +5,7 @@ package store
 import (
 	"context"
 	"sync"
+	"time"
 
 	"metrics/internal/core/config"
 	"metrics/internal/core/model"
@@ -15,12 +16,18 @@ import (
 // Store interfave for all public methods.
 type Store interface {
 	BatchUpsertMetrics(ctx context.Context, metrics []*model.MetricsV2) ([]*model.MetricsV2, error)
+	IdempotentBatchUpsertMetrics(ctx context.Context, key string, metrics []*model.MetricsV2) ([]*model.MetricsV2, error)
 	GetGauge(ctx context.Context, req *model.MetricsV2) (*model.Gauge, error)
 	SetGauge(ctx context.Context, gauge *model.Gauge) error
 	ListGauge(ctx context.Context) ([]*model.Gauge, error)
 	GetCounter(ctx context.Context, req *model.MetricsV2) (*model.Counter, error)
 	SetCounter(ctx context.Context, counter *model.Counter) error
 	ListCounter(ctx context.Context) ([]*model.Counter, error)
+	GetHistogram(ctx context.Context, req *model.MetricsV2) (*model.Histogram, error)
+	SetHistogram(ctx context.Context, histogram *model.Histogram) error
+	ListHistogram(ctx context.Context) ([]*model.Histogram, error)
+	ListSamples(ctx context.Context, req *model.MetricsV2, from, to time.Time) ([]*model.Sample, error)
+	QueryMetrics(ctx context.Context, q *model.MetricsQuery) ([]*model.MetricsV2, error)
 	Ping(ctx context.Context) error
 	Close()
 }
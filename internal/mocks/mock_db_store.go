@@ -12,6 +12,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	model "metrics/internal/core/model"
 
@@ -86,6 +87,36 @@ func (mr *MockStoreMockRecorder) GetGauge(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGauge", reflect.TypeOf((*MockStore)(nil).GetGauge), arg0, arg1)
 }
 
+// GetHistogram mocks base method.
+func (m *MockStore) GetHistogram(arg0 context.Context, arg1 *model.MetricsV2) (*model.Histogram, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistogram", arg0, arg1)
+	ret0, _ := ret[0].(*model.Histogram)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistogram indicates an expected call of GetHistogram.
+func (mr *MockStoreMockRecorder) GetHistogram(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistogram", reflect.TypeOf((*MockStore)(nil).GetHistogram), arg0, arg1)
+}
+
+// IdempotentBatchUpsertMetrics mocks base method.
+func (m *MockStore) IdempotentBatchUpsertMetrics(arg0 context.Context, arg1 string, arg2 []*model.MetricsV2) ([]*model.MetricsV2, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IdempotentBatchUpsertMetrics", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*model.MetricsV2)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IdempotentBatchUpsertMetrics indicates an expected call of IdempotentBatchUpsertMetrics.
+func (mr *MockStoreMockRecorder) IdempotentBatchUpsertMetrics(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IdempotentBatchUpsertMetrics", reflect.TypeOf((*MockStore)(nil).IdempotentBatchUpsertMetrics), arg0, arg1, arg2)
+}
+
 // ListCounter mocks base method.
 func (m *MockStore) ListCounter(arg0 context.Context) ([]*model.Counter, error) {
 	m.ctrl.T.Helper()
@@ -116,6 +147,51 @@ func (mr *MockStoreMockRecorder) ListGauge(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGauge", reflect.TypeOf((*MockStore)(nil).ListGauge), arg0)
 }
 
+// ListHistogram mocks base method.
+func (m *MockStore) ListHistogram(arg0 context.Context) ([]*model.Histogram, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListHistogram", arg0)
+	ret0, _ := ret[0].([]*model.Histogram)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListHistogram indicates an expected call of ListHistogram.
+func (mr *MockStoreMockRecorder) ListHistogram(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListHistogram", reflect.TypeOf((*MockStore)(nil).ListHistogram), arg0)
+}
+
+// ListSamples mocks base method.
+func (m *MockStore) ListSamples(arg0 context.Context, arg1 *model.MetricsV2, arg2, arg3 time.Time) ([]*model.Sample, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSamples", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]*model.Sample)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSamples indicates an expected call of ListSamples.
+func (mr *MockStoreMockRecorder) ListSamples(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSamples", reflect.TypeOf((*MockStore)(nil).ListSamples), arg0, arg1, arg2, arg3)
+}
+
+// QueryMetrics mocks base method.
+func (m *MockStore) QueryMetrics(arg0 context.Context, arg1 *model.MetricsQuery) ([]*model.MetricsV2, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryMetrics", arg0, arg1)
+	ret0, _ := ret[0].([]*model.MetricsV2)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryMetrics indicates an expected call of QueryMetrics.
+func (mr *MockStoreMockRecorder) QueryMetrics(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryMetrics", reflect.TypeOf((*MockStore)(nil).QueryMetrics), arg0, arg1)
+}
+
 // SetCounter mocks base method.
 func (m *MockStore) SetCounter(arg0 context.Context, arg1 *model.Counter) error {
 	m.ctrl.T.Helper()
@@ -143,3 +219,17 @@ func (mr *MockStoreMockRecorder) SetGauge(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetGauge", reflect.TypeOf((*MockStore)(nil).SetGauge), arg0, arg1)
 }
+
+// SetHistogram mocks base method.
+func (m *MockStore) SetHistogram(arg0 context.Context, arg1 *model.Histogram) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetHistogram", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetHistogram indicates an expected call of SetHistogram.
+func (mr *MockStoreMockRecorder) SetHistogram(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHistogram", reflect.TypeOf((*MockStore)(nil).SetHistogram), arg0, arg1)
+}
@@ -0,0 +1,30 @@
+package pb
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// SignatureHeader is a metadata key with HMAC-SHA256 signature of the message, like HashSHA256 header of the REST API.
+const SignatureHeader = "hashsha256"
+
+// Sign returns hex encoded HMAC-SHA256 of the message. Deterministic marshaling is used,
+// so the signature could be checked by re-marshaling the received message.
+func Sign(key string, msg proto.Message) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling message to sign: %w", err)
+	}
+	return SignData(key, data), nil
+}
+
+// SignData returns hex encoded HMAC-SHA256 of the data. It is used for MetricsBatch signature.
+func SignData(key string, data []byte) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,159 @@
+// Package selfmetrics collects metrics about the server itself: process, Go runtime and API requests.
+// They are exposed by the Prometheus endpoint under the separate Namespace, so they don't mix with stored metrics.
+package selfmetrics
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"metrics/internal/core/model"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// Namespace is a prefix of all self metrics names.
+const Namespace = "metrics_server"
+
+type Registry struct {
+	mux        *sync.Mutex
+	process    *process.Process
+	counters   map[string]*model.Counter
+	gauges     map[string]*model.Gauge
+	histograms map[string]*model.Histogram
+	startTime  time.Time
+}
+
+func NewRegistry() *Registry {
+	// process metrics are optional, they are skipped if the process could not be inspected
+	proc, _ := process.NewProcess(int32(os.Getpid()))
+	return &Registry{
+		mux:        &sync.Mutex{},
+		process:    proc,
+		counters:   make(map[string]*model.Counter),
+		gauges:     make(map[string]*model.Gauge),
+		histograms: make(map[string]*model.Histogram),
+		startTime:  time.Now(),
+	}
+}
+
+// Add increases the counter.
+func (r *Registry) Add(name string, labels model.Labels, delta int64) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.counter(name, labels).Value += delta
+}
+
+// Set sets the gauge value.
+func (r *Registry) Set(name string, labels model.Labels, value float64) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.gauge(name, labels).Value = value
+}
+
+// Observe adds the value to the histogram. Buckets are used when the histogram is created.
+func (r *Registry) Observe(name string, labels model.Labels, buckets []float64, value float64) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	key := model.MetricKey(name, labels)
+	h, ok := r.histograms[key]
+	if !ok {
+		h = model.NewHistogram(Namespace+"_"+name, labels, buckets)
+		r.histograms[key] = h
+	}
+	h.Observe(value)
+}
+
+// Metrics returns all self metrics. Process and runtime metrics are collected on every call.
+func (r *Registry) Metrics() []*model.MetricResponse {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.collectRuntime()
+	r.collectProcess()
+
+	res := make([]*model.MetricResponse, 0, len(r.counters)+len(r.gauges)+len(r.histograms))
+	for _, g := range r.gauges {
+		res = append(res, &model.MetricResponse{
+			Labels: g.Labels,
+			Name:   g.Name,
+			Type:   g.Type(),
+			Value:  g.StringValue(),
+		})
+	}
+	for _, c := range r.counters {
+		res = append(res, &model.MetricResponse{
+			Labels: c.Labels,
+			Name:   c.Name,
+			Type:   c.Type(),
+			Value:  c.StringValue(),
+		})
+	}
+	if r.process != nil {
+		// CPU time is a float counter, so it could not be kept as model.Counter
+		if times, err := r.process.Times(); err == nil {
+			res = append(res, &model.MetricResponse{
+				Name:  Namespace + "_process_cpu_seconds_total",
+				Type:  model.CounterType,
+				Value: strconv.FormatFloat(times.User+times.System, 'f', -1, 64),
+			})
+		}
+	}
+	for _, h := range r.histograms {
+		res = append(res, &model.MetricResponse{
+			Labels:    h.Labels,
+			Histogram: h.Payload(),
+			Name:      h.Name,
+			Type:      h.Type(),
+			Value:     h.StringValue(),
+		})
+	}
+	return res
+}
+
+func (r *Registry) collectRuntime() {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	r.gauge("go_goroutines", nil).Value = float64(runtime.NumGoroutine())
+	r.gauge("go_memstats_heap_alloc_bytes", nil).Value = float64(mem.HeapAlloc)
+	r.gauge("go_memstats_sys_bytes", nil).Value = float64(mem.Sys)
+	r.counter("go_gc_cycles_total", nil).Value = int64(mem.NumGC)
+	r.gauge("process_start_time_seconds", nil).Value = float64(r.startTime.Unix())
+}
+
+func (r *Registry) collectProcess() {
+	if r.process == nil {
+		return
+	}
+	if mem, err := r.process.MemoryInfo(); err == nil {
+		r.gauge("process_resident_memory_bytes", nil).Value = float64(mem.RSS)
+		r.gauge("process_virtual_memory_bytes", nil).Value = float64(mem.VMS)
+	}
+	if fds, err := r.process.NumFDs(); err == nil {
+		r.gauge("process_open_fds", nil).Value = float64(fds)
+	}
+}
+
+func (r *Registry) counter(name string, labels model.Labels) *model.Counter {
+	key := model.MetricKey(name, labels)
+	c, ok := r.counters[key]
+	if !ok {
+		c = model.NewCounter(Namespace+"_"+name, labels)
+		r.counters[key] = c
+	}
+	return c
+}
+
+func (r *Registry) gauge(name string, labels model.Labels) *model.Gauge {
+	key := model.MetricKey(name, labels)
+	g, ok := r.gauges[key]
+	if !ok {
+		g = model.NewGauge(Namespace+"_"+name, labels)
+		r.gauges[key] = g
+	}
+	return g
+}
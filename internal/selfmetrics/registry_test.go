@@ -0,0 +1,42 @@
+package selfmetrics
+
+import (
+	"testing"
+
+	"metrics/internal/core/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	labels := model.Labels{"path": "/update/"}
+	r.Add("http_requests_total", labels, 1)
+	r.Add("http_requests_total", labels, 2)
+	r.Set("queue_size", nil, 5)
+	r.Observe("http_request_duration_seconds", labels, []float64{0.1, 1}, 0.5)
+
+	metrics := make(map[string]*model.MetricResponse)
+	for _, m := range r.Metrics() {
+		metrics[model.MetricKey(m.Name, m.Labels)] = m
+	}
+
+	requests := metrics[`metrics_server_http_requests_total{path="/update/"}`]
+	require.NotNil(t, requests)
+	assert.Equal(t, model.CounterType, requests.Type)
+	assert.Equal(t, "3", requests.Value)
+
+	require.NotNil(t, metrics["metrics_server_queue_size"])
+	assert.Equal(t, "5", metrics["metrics_server_queue_size"].Value)
+
+	duration := metrics[`metrics_server_http_request_duration_seconds{path="/update/"}`]
+	require.NotNil(t, duration)
+	assert.Equal(t, []int64{0, 1, 0}, duration.Histogram.Counts)
+
+	assert.Contains(t, metrics, "metrics_server_go_goroutines")
+	assert.Contains(t, metrics, "metrics_server_process_start_time_seconds")
+	for name := range metrics {
+		assert.Regexp(t, "^"+Namespace+"_", name)
+	}
+}
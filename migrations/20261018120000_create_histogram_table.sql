@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS histogram(
+   id VARCHAR(255) PRIMARY KEY,
+   buckets JSONB NOT NULL,
+   counts JSONB NOT NULL,
+   sum DOUBLE PRECISION NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS histogram;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE gauge ADD COLUMN IF NOT EXISTS labels TEXT NOT NULL DEFAULT '';
+ALTER TABLE gauge DROP CONSTRAINT IF EXISTS gauge_pkey;
+ALTER TABLE gauge ADD PRIMARY KEY (id, labels);
+
+ALTER TABLE counter ADD COLUMN IF NOT EXISTS labels TEXT NOT NULL DEFAULT '';
+ALTER TABLE counter DROP CONSTRAINT IF EXISTS counter_pkey;
+ALTER TABLE counter ADD PRIMARY KEY (id, labels);
+
+ALTER TABLE histogram ADD COLUMN IF NOT EXISTS labels TEXT NOT NULL DEFAULT '';
+ALTER TABLE histogram DROP CONSTRAINT IF EXISTS histogram_pkey;
+ALTER TABLE histogram ADD PRIMARY KEY (id, labels);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM gauge WHERE labels <> '';
+ALTER TABLE gauge DROP CONSTRAINT IF EXISTS gauge_pkey;
+ALTER TABLE gauge DROP COLUMN IF EXISTS labels;
+ALTER TABLE gauge ADD PRIMARY KEY (id);
+
+DELETE FROM counter WHERE labels <> '';
+ALTER TABLE counter DROP CONSTRAINT IF EXISTS counter_pkey;
+ALTER TABLE counter DROP COLUMN IF EXISTS labels;
+ALTER TABLE counter ADD PRIMARY KEY (id);
+
+DELETE FROM histogram WHERE labels <> '';
+ALTER TABLE histogram DROP CONSTRAINT IF EXISTS histogram_pkey;
+ALTER TABLE histogram DROP COLUMN IF EXISTS labels;
+ALTER TABLE histogram ADD PRIMARY KEY (id);
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS sample(
+   type VARCHAR(16) NOT NULL,
+   id VARCHAR(255) NOT NULL,
+   labels TEXT NOT NULL DEFAULT '',
+   value DOUBLE PRECISION NOT NULL,
+   ts TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS sample_metric_ts_idx ON sample(type, id, labels, ts);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS sample;
+-- +goose StatementEnd
@@ -23,6 +23,18 @@ const docTemplate = `{
                 ],
                 "summary": "List metrics",
                 "operationId": "ListHandler",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Label matchers: host=a, host!=a, host=~a.*, host!~a.*",
+                        "name": "match",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "Metrics list",
@@ -45,35 +57,49 @@ const docTemplate = `{
                 }
             }
         },
-        "/update/": {
-            "post": {
-                "consumes": [
-                    "application/json"
-                ],
+        "/alerts": {
+            "get": {
+                "description": "Pending and firing alerts sorted by rule name and labels",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "V2 API"
+                    "Alerting"
                 ],
-                "summary": "Update metrics",
-                "operationId": "UpdateHandlerV2",
-                "parameters": [
-                    {
-                        "description": "Metric Name",
-                        "name": "req",
-                        "in": "body",
-                        "required": true,
+                "summary": "List active alerts",
+                "operationId": "ListAlertsHandler",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.MetricsV2"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/alert.Alert"
+                            }
                         }
                     }
+                }
+            }
+        },
+        "/api/v1/write": {
+            "post": {
+                "description": "Accepts snappy-compressed protobuf WriteRequest. Samples are stored as gauges and counters,\nseries which could not be stored (histograms, summaries) are listed in the response.",
+                "consumes": [
+                    "application/x-protobuf"
                 ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Prometheus"
+                ],
+                "summary": "Prometheus remote write receiver",
+                "operationId": "RemoteWriteHandler",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.MetricsV2"
+                            "$ref": "#/definitions/model.WriteResponse"
                         }
                     },
                     "400": {
@@ -82,6 +108,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Inernal Server Error",
                         "schema": {
@@ -91,35 +123,57 @@ const docTemplate = `{
                 }
             }
         },
-        "/update/{type}/{name}/": {
+        "/api/v2/metrics": {
             "get": {
-                "description": "Get metric from storage",
+                "description": "Metrics filtered by type and name ordered by the sort key. Use next_cursor to get the next page.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "V1 API"
+                    "V2 API"
                 ],
-                "summary": "Get metric",
-                "operationId": "GetHandler",
+                "summary": "Query metrics",
+                "operationId": "QueryHandler",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Metric name",
-                        "name": "name",
-                        "in": "path",
-                        "required": true
+                        "description": "Metric type: gauge, counter or histogram",
+                        "name": "type",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Metric type",
-                        "name": "type",
-                        "in": "path",
-                        "required": true
+                        "description": "Metric name glob: Heap*, *Alloc, Gauge?",
+                        "name": "match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "name",
+                        "description": "Sort key: name, type, -name or -type for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 1000,
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Page size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Ok",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.MetricsPage"
                         }
                     },
                     "400": {
@@ -128,12 +182,6 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Not found",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
                     "500": {
                         "description": "Inernal Server Error",
                         "schema": {
@@ -143,39 +191,65 @@ const docTemplate = `{
                 }
             }
         },
-        "/update/{type}/{name}/{value}/": {
-            "post": {
+        "/api/v2/query_range": {
+            "get": {
+                "description": "Metric samples between start and end aggregated by steps. Steps without samples are skipped.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "V1 API"
+                    "V2 API"
                 ],
-                "summary": "Update metrics",
-                "operationId": "UpdateHandler",
+                "summary": "Query metric range",
+                "operationId": "QueryRangeHandler",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Metric name",
-                        "name": "name",
-                        "in": "path",
+                        "description": "Metric name with optional labels: HeapAlloc or HeapAlloc{host=\\",
+                        "name": "metric",
+                        "in": "query",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Metric type",
+                        "default": "gauge",
+                        "description": "Metric type: gauge, counter or histogram",
                         "name": "type",
-                        "in": "path",
-                        "required": true
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Metric value ",
-                        "name": "value",
-                        "in": "path",
-                        "required": true
+                        "description": "Range start, RFC3339 or unix seconds. An hour before the end by default",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Range end, RFC3339 or unix seconds. The current time by default",
+                        "name": "end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "1m",
+                        "description": "Step duration: 30s, 5m or seconds",
+                        "name": "step",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "avg",
+                        "description": "Function: avg, min, max, sum, p95, rate or increase",
+                        "name": "fn",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.RangeResponse"
+                        }
                     },
                     "400": {
                         "description": "Bad request",
@@ -192,8 +266,59 @@ const docTemplate = `{
                 }
             }
         },
-        "/updates/": {
+        "/api/v2/write": {
+            "post": {
+                "description": "Accepts InfluxDB line protocol (v1 /write and v2 /api/v2/write). Fields are stored as gauges\nnamed ` + "`" + `measurement_field` + "`" + ` with tags as labels. String fields are skipped. Query parameters are ignored.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Influx"
+                ],
+                "summary": "InfluxDB line protocol receiver",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Inernal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/grafana/": {
+            "get": {
+                "description": "Grafana checks the datasource is available",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Grafana"
+                ],
+                "summary": "Test connection",
+                "operationId": "GrafanaTestHandler",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/grafana/annotations": {
             "post": {
+                "description": "Active alerts of the range. The annotation query selects alerts by the rule name.",
                 "consumes": [
                     "application/json"
                 ],
@@ -201,22 +326,63 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "V2 API"
+                    "Grafana"
                 ],
-                "summary": "Batch update",
-                "operationId": "BatchUpdateHandler",
+                "summary": "Alert annotations",
+                "operationId": "GrafanaAnnotationsHandler",
                 "parameters": [
                     {
-                        "description": "Metrics request",
+                        "description": "Annotations request",
                         "name": "req",
                         "in": "body",
                         "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GrafanaAnnotationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/model.MetricsV2"
+                                "$ref": "#/definitions/handlers.GrafanaAnnotation"
                             }
                         }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/grafana/query": {
+            "post": {
+                "description": "Samples of the targets averaged by the request interval",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Grafana"
+                ],
+                "summary": "Query time series",
+                "operationId": "GrafanaQueryHandler",
+                "parameters": [
+                    {
+                        "description": "Query request",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GrafanaQueryRequest"
+                        }
                     }
                 ],
                 "responses": {
@@ -225,7 +391,7 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/model.MetricsV2"
+                                "$ref": "#/definitions/handlers.GrafanaSeries"
                             }
                         }
                     },
@@ -244,8 +410,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/value/": {
+        "/grafana/search": {
             "post": {
+                "description": "Targets of metrics which names contain the requested string",
                 "consumes": [
                     "application/json"
                 ],
@@ -253,18 +420,18 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "V2 API"
+                    "Grafana"
                 ],
-                "summary": "Get metrics",
-                "operationId": "GetHandlerV2",
+                "summary": "Search metrics",
+                "operationId": "GrafanaSearchHandler",
                 "parameters": [
                     {
-                        "description": "Metric request",
+                        "description": "Search request",
                         "name": "req",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.MetricsV2"
+                            "$ref": "#/definitions/handlers.GrafanaSearchRequest"
                         }
                     }
                 ],
@@ -272,7 +439,10 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.MetricsV2"
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
                         }
                     },
                     "400": {
@@ -289,34 +459,905 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "model.MetricType": {
-            "type": "string",
-            "enum": [
-                "gauge",
-                "counter"
-            ],
-            "x-enum-varnames": [
-                "GaugeType",
-                "CounterType"
-            ]
         },
-        "model.MetricsV2": {
-            "type": "object",
-            "properties": {
-                "delta": {
-                    "type": "integer"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "type": {
-                    "$ref": "#/definitions/model.MetricType"
-                },
-                "value": {
-                    "type": "number"
+        "/grafana/tag-keys": {
+            "post": {
+                "description": "Label names of all metrics for ad hoc filters",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Grafana"
+                ],
+                "summary": "Label names",
+                "operationId": "GrafanaTagKeysHandler",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.GrafanaTag"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Inernal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/grafana/tag-values": {
+            "post": {
+                "description": "Values of the label for ad hoc filters",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Grafana"
+                ],
+                "summary": "Label values",
+                "operationId": "GrafanaTagValuesHandler",
+                "parameters": [
+                    {
+                        "description": "Label name",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GrafanaTagValuesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.GrafanaTag"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Inernal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/history/": {
+            "post": {
+                "description": "Metric values recorded between two timestamps ordered by time",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "V2 API"
+                ],
+                "summary": "Get metric history",
+                "operationId": "HistoryHandler",
+                "parameters": [
+                    {
+                        "description": "History request",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.HistoryRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.HistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Inernal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "All stored metrics and the server own metrics (with metrics_server_ prefix) in the text exposition format",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Prometheus"
+                ],
+                "summary": "Metrics in Prometheus text format",
+                "operationId": "PrometheusMetricsHandler",
+                "responses": {
+                    "200": {
+                        "description": "Metrics",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Inernal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/update/": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "V2 API"
+                ],
+                "summary": "Update metrics",
+                "operationId": "UpdateHandlerV2",
+                "parameters": [
+                    {
+                        "description": "Metric Name",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MetricsV2"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MetricsV2"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Inernal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/update/{type}/{name}/": {
+            "get": {
+                "description": "Get metric from storage",
+                "tags": [
+                    "V1 API"
+                ],
+                "summary": "Get metric",
+                "operationId": "GetHandler",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Metric name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metric type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Label matchers: host=a, host!=a, host=~a.*, host!~a.*",
+                        "name": "match",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Inernal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/update/{type}/{name}/{value}/": {
+            "post": {
+                "tags": [
+                    "V1 API"
+                ],
+                "summary": "Update metrics",
+                "operationId": "UpdateHandler",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Metric name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metric type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metric value. For histogram it is a single observation",
+                        "name": "value",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Inernal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/updates/": {
+            "post": {
+                "description": "The batch with Idempotency-Key header is applied once, a retry returns the result of the first request.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "V2 API"
+                ],
+                "summary": "Batch update",
+                "operationId": "BatchUpdateHandler",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Batch ID",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Metrics request",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.MetricsV2"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.MetricsV2"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Inernal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/metrics": {
+            "post": {
+                "description": "Accepts ExportMetricsServiceRequest encoded as protobuf or JSON, the response has the same encoding.\nGauges, sums and explicit bucket histograms are stored, other data points are counted\nas rejected in the partial success of the response.",
+                "consumes": [
+                    "application/x-protobuf",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/x-protobuf",
+                    "application/json"
+                ],
+                "tags": [
+                    "OpenTelemetry"
+                ],
+                "summary": "OTLP/HTTP metrics receiver",
+                "operationId": "OTLPMetricsHandler",
+                "responses": {
+                    "200": {
+                        "description": "ExportMetricsServiceResponse",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported media type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Inernal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/value/": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "V2 API"
+                ],
+                "summary": "Get metrics",
+                "operationId": "GetHandlerV2",
+                "parameters": [
+                    {
+                        "description": "Metric request",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MetricsV2"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MetricsV2"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Inernal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/write": {
+            "post": {
+                "description": "Accepts InfluxDB line protocol (v1 /write and v2 /api/v2/write). Fields are stored as gauges\nnamed ` + "`" + `measurement_field` + "`" + ` with tags as labels. String fields are skipped. Query parameters are ignored.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Influx"
+                ],
+                "summary": "InfluxDB line protocol receiver",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Inernal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "alert.Alert": {
+            "type": "object",
+            "properties": {
+                "active_at": {
+                    "type": "string"
+                },
+                "annotations": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "fired_at": {
+                    "type": "string"
+                },
+                "labels": {
+                    "$ref": "#/definitions/model.Labels"
+                },
+                "metric": {
+                    "type": "string"
+                },
+                "resolved_at": {
+                    "type": "string"
+                },
+                "rule": {
+                    "type": "string"
+                },
+                "state": {
+                    "$ref": "#/definitions/alert.State"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
+        "alert.State": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "firing",
+                "resolved"
+            ],
+            "x-enum-varnames": [
+                "StatePending",
+                "StateFiring",
+                "StateResolved"
+            ]
+        },
+        "handlers.GrafanaAdhocFilter": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string"
+                },
+                "operator": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.GrafanaAnnotation": {
+            "type": "object",
+            "properties": {
+                "annotation": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "text": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "integer"
+                },
+                "timeEnd": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.GrafanaAnnotationRequest": {
+            "type": "object",
+            "properties": {
+                "annotation": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "range": {
+                    "$ref": "#/definitions/handlers.GrafanaRange"
+                }
+            }
+        },
+        "handlers.GrafanaQueryRequest": {
+            "type": "object",
+            "properties": {
+                "adhocFilters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.GrafanaAdhocFilter"
+                    }
+                },
+                "intervalMs": {
+                    "type": "integer"
+                },
+                "maxDataPoints": {
+                    "type": "integer"
+                },
+                "range": {
+                    "$ref": "#/definitions/handlers.GrafanaRange"
+                },
+                "targets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.GrafanaTarget"
+                    }
+                }
+            }
+        },
+        "handlers.GrafanaRange": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.GrafanaSearchRequest": {
+            "type": "object",
+            "properties": {
+                "target": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.GrafanaSeries": {
+            "type": "object",
+            "properties": {
+                "datapoints": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "type": "number"
+                        }
+                    }
+                },
+                "target": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.GrafanaTag": {
+            "type": "object",
+            "properties": {
+                "text": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.GrafanaTagValuesRequest": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.GrafanaTarget": {
+            "type": "object",
+            "properties": {
+                "payload": {
+                    "type": "object",
+                    "properties": {
+                        "fn": {
+                            "description": "avg by default",
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/model.RangeFunction"
+                                }
+                            ]
+                        }
+                    }
+                },
+                "refId": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.HistogramValue": {
+            "type": "object",
+            "properties": {
+                "buckets": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "counts": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "sum": {
+                    "type": "number"
+                }
+            }
+        },
+        "model.HistoryRequest": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "labels": {
+                    "$ref": "#/definitions/model.Labels"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/model.MetricType"
+                }
+            }
+        },
+        "model.HistoryResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "labels": {
+                    "$ref": "#/definitions/model.Labels"
+                },
+                "samples": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Sample"
+                    }
+                },
+                "type": {
+                    "$ref": "#/definitions/model.MetricType"
+                }
+            }
+        },
+        "model.Labels": {
+            "type": "object",
+            "additionalProperties": {
+                "type": "string"
+            }
+        },
+        "model.MetricType": {
+            "type": "string",
+            "enum": [
+                "gauge",
+                "counter",
+                "histogram"
+            ],
+            "x-enum-varnames": [
+                "GaugeType",
+                "CounterType",
+                "HistogramType"
+            ]
+        },
+        "model.MetricsPage": {
+            "type": "object",
+            "properties": {
+                "metrics": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.MetricsV2"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MetricsV2": {
+            "type": "object",
+            "properties": {
+                "delta": {
+                    "type": "integer"
+                },
+                "histogram": {
+                    "$ref": "#/definitions/model.HistogramValue"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "labels": {
+                    "$ref": "#/definitions/model.Labels"
+                },
+                "type": {
+                    "$ref": "#/definitions/model.MetricType"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
+        "model.RangeFunction": {
+            "type": "string",
+            "enum": [
+                "avg",
+                "min",
+                "max",
+                "sum",
+                "p95",
+                "rate",
+                "increase"
+            ],
+            "x-enum-comments": {
+                "FnIncrease": "counters and histograms only",
+                "FnRate": "per-second increase, counters and histograms only"
+            },
+            "x-enum-varnames": [
+                "FnAvg",
+                "FnMin",
+                "FnMax",
+                "FnSum",
+                "FnP95",
+                "FnRate",
+                "FnIncrease"
+            ]
+        },
+        "model.RangeResponse": {
+            "type": "object",
+            "properties": {
+                "fn": {
+                    "$ref": "#/definitions/model.RangeFunction"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "labels": {
+                    "$ref": "#/definitions/model.Labels"
+                },
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Sample"
+                    }
+                },
+                "step": {
+                    "description": "seconds",
+                    "type": "number"
+                },
+                "type": {
+                    "$ref": "#/definitions/model.MetricType"
+                }
+            }
+        },
+        "model.Sample": {
+            "type": "object",
+            "properties": {
+                "timestamp": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
+        "model.UnsupportedSeries": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
+                },
+                "series": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.WriteResponse": {
+            "type": "object",
+            "properties": {
+                "unsupported": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.UnsupportedSeries"
+                    }
+                },
+                "written": {
+                    "type": "integer"
                 }
             }
         }